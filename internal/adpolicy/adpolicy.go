@@ -0,0 +1,312 @@
+// Package adpolicy reads the password policies of an Active Directory domain
+// from the dumps collected during an engagement: the output of
+// Get-ADDefaultDomainPasswordPolicy and Get-ADFineGrainedPasswordPolicy, as
+// text or ConvertTo-Json, and ldapsearch LDIF of the domain object and of
+// msDS-PasswordSettings objects.
+package adpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/wordlist"
+)
+
+// DefaultName names the default domain policy, which has no name of its own
+const DefaultName = "default"
+
+// complexPasswordFlag is DOMAIN_PASSWORD_COMPLEX of the pwdProperties attribute
+const complexPasswordFlag = 1
+
+// Policy is one password policy object. A zero Precedence belongs to the
+// default domain policy; fine-grained policies with a lower precedence win.
+// History is only reported: Apply cannot turn it into a candidate filter.
+type Policy struct {
+	Name             string
+	Precedence       int
+	MinLength        int
+	Complexity       bool
+	History          int
+	LockoutThreshold int
+	LockoutWindow    time.Duration
+}
+
+// attribute names, lowercased, of the three dump formats
+var (
+	nameKeys       = []string{"name", "cn"}
+	precedenceKeys = []string{"precedence", "msds-passwordsettingsprecedence"}
+	minLengthKeys  = []string{"minpasswordlength", "msds-minimumpasswordlength", "minpwdlength"}
+	complexityKeys = []string{"complexityenabled", "msds-passwordcomplexityenabled"}
+	historyKeys    = []string{"passwordhistorycount", "msds-passwordhistorylength", "pwdhistorylength"}
+	thresholdKeys  = []string{"lockoutthreshold", "msds-lockoutthreshold"}
+	windowKeys     = []string{"lockoutobservationwindow", "msds-lockoutobservationwindow"}
+	propertiesKey  = "pwdproperties"
+)
+
+// Load reads every policy object of a dump file
+func Load(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file %s: %w", path, err)
+	}
+
+	policies, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+
+	return policies, nil
+}
+
+// Parse reads every policy object of a dump, detecting its format. Objects
+// without a password setting, such as ldapsearch result summaries, are left
+// out.
+func Parse(data []byte) ([]Policy, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var records []map[string]string
+	var err error
+
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		records, err = parseJSON(trimmed)
+	case wordlist.IsLDIF(data):
+		records, err = wordlist.ParseLDIF(data)
+	default:
+		records = parseList(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []Policy
+	for _, record := range records {
+		policy, ok, err := newPolicy(record)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			policies = append(policies, policy)
+		}
+	}
+
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("no password policy found")
+	}
+
+	return policies, nil
+}
+
+// Apply tightens base with the rules of the policy: its minimum length, and
+// with complexity three of the four character classes and no username
+func (p Policy) Apply(base config.PolicyConfig) config.PolicyConfig {
+	base.MinLength = max(base.MinLength, p.MinLength)
+	if p.Complexity {
+		base.MinClasses = max(base.MinClasses, 3)
+		base.NoUsername = true
+	}
+
+	return base
+}
+
+// String sums up the policy in one line
+func (p Policy) String() string {
+	rules := []string{fmt.Sprintf("min length %d", p.MinLength)}
+	if p.Complexity {
+		rules = append(rules, "complexity")
+	}
+	rules = append(rules, fmt.Sprintf("history %d", p.History))
+
+	if p.LockoutThreshold > 0 {
+		rules = append(rules, fmt.Sprintf("lockout after %d attempts in %s", p.LockoutThreshold, p.LockoutWindow))
+	} else {
+		rules = append(rules, "no lockout")
+	}
+
+	return fmt.Sprintf("%s: %s", p.Name, strings.Join(rules, ", "))
+}
+
+// FileSuffix turns the policy name into a part of a file name
+func (p Policy) FileSuffix() string {
+	words := strings.FieldsFunc(strings.ToLower(p.Name), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+
+	return strings.Join(words, "-")
+}
+
+func newPolicy(record map[string]string) (Policy, bool, error) {
+	policy := Policy{Name: lookup(record, nameKeys)}
+	found := false
+
+	for _, field := range []struct {
+		keys  []string
+		value *int
+	}{
+		{precedenceKeys, &policy.Precedence},
+		{minLengthKeys, &policy.MinLength},
+		{historyKeys, &policy.History},
+		{thresholdKeys, &policy.LockoutThreshold},
+	} {
+		value := lookup(record, field.keys)
+		if value == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return Policy{}, false, fmt.Errorf("invalid %s '%s'", field.keys[0], value)
+		}
+		*field.value = number
+		found = true
+	}
+
+	if value := lookup(record, complexityKeys); value != "" {
+		policy.Complexity = strings.EqualFold(value, "true")
+		found = true
+	}
+	if value, exists := record[propertiesKey]; exists {
+		properties, err := strconv.Atoi(value)
+		if err != nil {
+			return Policy{}, false, fmt.Errorf("invalid pwdproperties '%s'", value)
+		}
+		policy.Complexity = properties&complexPasswordFlag != 0
+	}
+
+	if value := lookup(record, windowKeys); value != "" {
+		window, err := parseDuration(value)
+		if err != nil {
+			return Policy{}, false, fmt.Errorf("invalid lockout observation window '%s'", value)
+		}
+		policy.LockoutWindow = window
+	}
+
+	// Only fine-grained policies have a precedence; the domain object may carry the domain name
+	if policy.Name == "" || policy.Precedence == 0 {
+		policy.Name = DefaultName
+	}
+
+	return policy, found, nil
+}
+
+func lookup(record map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value, exists := record[key]; exists {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// parseDuration reads a TimeSpan as [-][d.]hh:mm:ss, or a count of 100ns
+// intervals, which LDIF stores negated
+func parseDuration(value string) (time.Duration, error) {
+	if ticks, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(max(ticks, -ticks)) * 100, nil
+	}
+
+	value = strings.TrimPrefix(value, "-")
+	days := 0
+	if dayText, rest, found := strings.Cut(value, "."); found && !strings.Contains(dayText, ":") {
+		var err error
+		if days, err = strconv.Atoi(dayText); err != nil {
+			return 0, err
+		}
+		value = rest
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("expected hh:mm:ss")
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(days)*24*time.Hour + time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)), nil
+}
+
+// parseList reads PowerShell Format-List output: "Key : Value" lines, with
+// objects separated by blank lines
+func parseList(data []byte) []map[string]string {
+	var records []map[string]string
+	record := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if len(record) > 0 {
+				records = append(records, record)
+				record = make(map[string]string)
+			}
+			continue
+		}
+
+		if key, value, found := strings.Cut(line, ":"); found {
+			record[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+
+	if len(record) > 0 {
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// parseJSON reads ConvertTo-Json output of one policy object or of an array
+// of them. TimeSpan values are objects that carry their Ticks.
+func parseJSON(data []byte) ([]map[string]string, error) {
+	var objects []map[string]any
+	if data[0] == '{' {
+		var object map[string]any
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		objects = append(objects, object)
+	} else if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var records []map[string]string
+	for _, object := range objects {
+		record := make(map[string]string, len(object))
+		for key, value := range object {
+			switch value := value.(type) {
+			case string:
+				record[strings.ToLower(key)] = value
+			case bool:
+				record[strings.ToLower(key)] = strconv.FormatBool(value)
+			case float64:
+				record[strings.ToLower(key)] = strconv.FormatInt(int64(value), 10)
+			case map[string]any:
+				if ticks, ok := value["Ticks"].(float64); ok {
+					record[strings.ToLower(key)] = strconv.FormatInt(int64(ticks), 10)
+				}
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
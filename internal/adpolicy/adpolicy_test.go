@@ -0,0 +1,128 @@
+package adpolicy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+const listDump = `
+ComplexityEnabled           : True
+DistinguishedName           : DC=corp,DC=local
+LockoutDuration             : 00:30:00
+LockoutObservationWindow    : 00:30:00
+LockoutThreshold            : 5
+MaxPasswordAge              : 42.00:00:00
+MinPasswordAge              : 1.00:00:00
+MinPasswordLength           : 7
+objectClass                 : {domainDNS}
+PasswordHistoryCount        : 24
+ReversibleEncryptionEnabled : False
+
+AppliesTo                   : {CN=Domain Admins,CN=Users,DC=corp,DC=local}
+ComplexityEnabled           : True
+LockoutObservationWindow    : 1.00:00:00
+LockoutThreshold            : 3
+MinPasswordLength           : 14
+Name                        : Tier0 Admins
+PasswordHistoryCount        : 48
+Precedence                  : 10
+`
+
+const ldifDump = `# extended LDIF
+version: 1
+
+dn: DC=corp,DC=local
+minPwdLength: 7
+pwdProperties: 1
+pwdHistoryLength: 24
+lockoutThreshold: 5
+lockOutObservationWindow: -18000000000
+
+dn: CN=Tier0 Admins,CN=Password Settings Container,CN=System,DC=corp,DC=loc
+ al
+objectClass: top
+objectClass: msDS-PasswordSettings
+cn:: VGllcjAgQWRtaW5z
+msDS-PasswordSettingsPrecedence: 10
+msDS-PasswordComplexityEnabled: TRUE
+msDS-MinimumPasswordLength: 14
+msDS-PasswordHistoryLength: 48
+msDS-LockoutThreshold: 3
+msDS-LockoutObservationWindow: -864000000000
+msDS-PSOAppliesTo: CN=Domain Admins,CN=Users,DC=corp,DC=local
+
+# search result
+search: 2
+result: 0 Success
+`
+
+const jsonDump = `[
+  {
+    "ComplexityEnabled": true,
+    "LockoutObservationWindow": {"Ticks": 18000000000, "TotalMinutes": 30},
+    "LockoutThreshold": 5,
+    "MinPasswordLength": 7,
+    "PasswordHistoryCount": 24
+  },
+  {
+    "ComplexityEnabled": true,
+    "LockoutObservationWindow": "1.00:00:00",
+    "LockoutThreshold": 3,
+    "MinPasswordLength": 14,
+    "Name": "Tier0 Admins",
+    "PasswordHistoryCount": 48,
+    "Precedence": 10
+  }
+]`
+
+func TestParse(t *testing.T) {
+	expected := []Policy{
+		{Name: DefaultName, MinLength: 7, Complexity: true, History: 24, LockoutThreshold: 5, LockoutWindow: 30 * time.Minute},
+		{Name: "Tier0 Admins", Precedence: 10, MinLength: 14, Complexity: true, History: 48, LockoutThreshold: 3, LockoutWindow: 24 * time.Hour},
+	}
+
+	for _, tt := range []struct {
+		name string
+		dump string
+	}{
+		{"powershell list", listDump},
+		{"ldif", ldifDump},
+		{"json", jsonDump},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := Parse([]byte(tt.dump))
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(policies, expected) {
+				t.Errorf("expected %+v, got %+v", expected, policies)
+			}
+		})
+	}
+}
+
+func TestParseWithoutPolicy(t *testing.T) {
+	if _, err := Parse([]byte("search: 2\nresult: 0 Success\n")); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
+
+func TestApply(t *testing.T) {
+	base := config.PolicyConfig{MinLength: 10, Usernames: []string{"jdoe"}}
+
+	got := Policy{Name: DefaultName, MinLength: 7, Complexity: true}.Apply(base)
+	expected := config.PolicyConfig{MinLength: 10, MinClasses: 3, NoUsername: true, Usernames: []string{"jdoe"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestFileSuffix(t *testing.T) {
+	if suffix := (Policy{Name: "Tier0 Admins (PSO)"}).FileSuffix(); suffix != "tier0-admins-pso" {
+		t.Errorf("unexpected suffix %q", suffix)
+	}
+}
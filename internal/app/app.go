@@ -3,9 +3,14 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 
+	"github.com/omarelshopky/craftlist/internal/adpolicy"
 	"github.com/omarelshopky/craftlist/internal/config"
 	"github.com/omarelshopky/craftlist/internal/generator"
+	"github.com/omarelshopky/craftlist/internal/hashcat"
 	"github.com/omarelshopky/craftlist/internal/interfaces"
 	"github.com/omarelshopky/craftlist/internal/ui"
 	"github.com/omarelshopky/craftlist/internal/wordlist"
@@ -21,6 +26,7 @@ const (
 type App struct {
 	flags   *Flags
 	printer interfaces.Printer
+	cmd     *cobra.Command
 }
 
 func New() *App {
@@ -47,6 +53,7 @@ func (a *App) Execute(ctx context.Context) error {
 	}
 
 	rootCmd.SetContext(ctx)
+	a.cmd = rootCmd
 	a.setupFlags(rootCmd)
 	a.setupErrorHandling(rootCmd)
 
@@ -63,6 +70,7 @@ func (a *App) Execute(ctx context.Context) error {
 func (a *App) runCommand(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
+	a.configurePrinter()
 	a.printer.PrintIntro(AppVersion)
 
 	if a.flags.ListPlaceholders {
@@ -72,13 +80,24 @@ func (a *App) runCommand(cmd *cobra.Command, args []string) error {
 	return a.runGeneration(ctx)
 }
 
+// configurePrinter keeps stdout free for passwords when they are streamed
+// there, and silences everything but errors in quiet mode
+func (a *App) configurePrinter() {
+	switch {
+	case a.flags.Quiet:
+		a.printer = ui.NewPrinterTo(io.Discard, os.Stderr)
+	case a.flags.OutputFile == generator.StdoutFilename:
+		a.printer = ui.NewPrinterTo(os.Stderr, os.Stderr)
+	}
+}
+
 func (a *App) handleListPlaceholders() error {
 	cfg, err := a.loadConfiguration()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	a.printer.PrintPlaceholders(cfg.Placeholders)
+	a.printer.PrintPlaceholders(cfg.AllPlaceholders())
 
 	return nil
 }
@@ -89,43 +108,245 @@ func (a *App) runGeneration(ctx context.Context) error {
 		return err
 	}
 
+	if a.flags.ADPolicy == "" {
+		return a.generateList(ctx, cfg)
+	}
+
+	if a.flags.ExportRules != "" || a.flags.ExportMasks != "" {
+		return fmt.Errorf("--ad-policy cannot be combined with an export, which cannot apply the policy")
+	}
+
+	policies, err := adpolicy.Load(a.flags.ADPolicy)
+	if err != nil {
+		return err
+	}
+
+	// Every policy object gets its own list, named after it when there are several
+	suffixes := make(map[string]int)
+	for _, policy := range policies {
+		a.printer.Bold(fmt.Sprintf("\nPassword policy %s", policy))
+		if policy.History > 0 {
+			a.printer.Warning(fmt.Sprintf("Password history of %d is not enforced: a wordlist cannot know the previous passwords of an account", policy.History))
+		}
+
+		listCfg := *cfg
+		listCfg.Generator.Policy = policy.Apply(cfg.Generator.Policy)
+
+		// Unless set by hand, spraying stays one attempt below the lockout threshold of the policy
+		if listCfg.Output.Spray() {
+			if listCfg.Output.Attempts == 0 && policy.LockoutThreshold > 1 {
+				listCfg.Output.Attempts = policy.LockoutThreshold - 1
+			}
+			if listCfg.Output.LockoutWindow == 0 {
+				listCfg.Output.LockoutWindow = policy.LockoutWindow
+			}
+		}
+		if len(policies) > 1 {
+			suffix := policy.FileSuffix()
+			if suffixes[suffix]++; suffixes[suffix] > 1 {
+				suffix = fmt.Sprintf("%s-%d", suffix, suffixes[suffix])
+			}
+			listCfg.Output.Filename = generator.SuffixFilename(cfg.Output.Filename, suffix)
+		}
+
+		if err := a.generateList(ctx, &listCfg); err != nil {
+			return fmt.Errorf("policy %s: %w", policy.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// generateList validates cfg and writes its password list, or only counts
+// or exports it as the flags ask
+func (a *App) generateList(ctx context.Context, cfg *config.Config) error {
 	if err := cfg.Validate(); err != nil {
+		if patternErr, ok := err.(*config.PatternError); ok {
+			a.printer.PrintPatternErrors(patternErr.Details)
+		}
 		return err
 	}
 
+	a.printer.Success("All patterns validated successfully\n")
+
 	gen := generator.New(cfg.Generator, cfg.Placeholders)
-	counter := generator.NewCounter(cfg.Generator, cfg.Placeholders)
 	loader := wordlist.NewLoader()
 
-	if err := a.loadWordLists(gen, loader); err != nil {
+	if err := a.loadWordLists(cfg, gen, loader); err != nil {
 		return fmt.Errorf("failed to load word lists: %w", err)
 	}
 
-	if gen.PrepareVariations() != nil {
+	if a.flags.ExportRules != "" {
+		return a.exportRules(cfg, gen)
+	}
+
+	if a.flags.ExportMasks != "" {
+		return a.exportMasks(ctx, cfg, gen)
+	}
+
+	if err := gen.PrepareVariations(); err != nil {
 		return err
 	}
+	counter := gen.Counter()
+
+	// Checked before counting, so a stream too large to index is never estimated
+	keyspaceSize, err := gen.KeyspaceSize()
+	if err != nil {
+		return err
+	}
+
+	if conflicts := gen.PolicyConflicts(); len(conflicts) > 0 {
+		a.printer.Warning("\nSkipping patterns that cannot pass the password policy:")
+		for _, conflict := range conflicts {
+			a.printer.Warning("  - " + conflict)
+		}
+	}
 
 	count, stats := counter.CountPasswords(gen.GetCustomWords(), gen.GetCommonWords(), gen.GetSSIDs(), gen.GetNumbers())
+	counterStats := stats
+
+	partition, window := cfg.Generator.Partition, cfg.Generator.Window
+	if partition.Total > 0 || window.Skip > 0 || window.Limit > 0 {
+		if stats, err = gen.EstimateWindowCounts(stats); err != nil {
+			return err
+		}
+
+		count = 0
+		for _, patternCount := range stats {
+			count = config.AddCount(count, patternCount)
+		}
+	}
+
+	if top := cfg.Generator.Ranking.Top; top > 0 && top < count {
+		count = top
+	}
 
 	a.printer.PrintTotalPasswordsCount(count)
 
 	if a.flags.CountPasswords {
-		a.printer.PrintCountStats(stats)
+		if cfg.Generator.Policy.Enabled() {
+			candidates := counter.CountCandidates(gen.GetCustomWords(), gen.GetCommonWords(), gen.GetSSIDs(), gen.GetNumbers())
+			a.printer.PrintPassRates(stats, passRates(counterStats, candidates))
+		} else {
+			a.printer.PrintCountStats(stats)
+		}
+
+		if partition.Total > 0 {
+			partCounts, err := a.sumPartCounts(gen, counterStats, partition.Total)
+			if err != nil {
+				return err
+			}
+			a.printer.PrintPartCounts(partCounts, partition.Index)
+		}
+
+		a.printer.Info(fmt.Sprintf("\nThe candidate stream has %d positions (for --skip and --limit)", keyspaceSize))
 
 		return nil
 	}
 
 	a.printer.Info("\nGenerating password combinations...")
 
-	if err := gen.Generate(ctx, cfg.Output.Filename, a.printer); err != nil {
+	if err := gen.Generate(ctx, cfg.Output, a.printer); err != nil {
 		return fmt.Errorf("password generation failed: %w", err)
 	}
 
-	a.printer.PrintOutputFile(cfg.Output.Filename)
+	switch {
+	case cfg.Output.Filename == generator.StdoutFilename:
+	case cfg.Output.Format == config.OutputPerUser:
+		a.printer.PrintOutputFile(generator.SuffixFilename(cfg.Output.Filename, "<user>"))
+	case cfg.Output.Format == config.OutputSchedule:
+		a.printer.PrintOutputFile(cfg.Output.Filename)
+		a.printer.PrintOutputFile(generator.ScheduleFilename(cfg.Output.Filename))
+	case cfg.Output.SplitLines > 0 || cfg.Output.SplitBytes > 0:
+		a.printer.PrintOutputFile(generator.ManifestFilename(cfg.Output.Filename))
+	default:
+		a.printer.PrintOutputFile(cfg.Output.Filename)
+	}
+
+	return nil
+}
+
+// exportRules writes base wordlists and rule files instead of the expanded list
+func (a *App) exportRules(cfg *config.Config, gen *generator.Generator) error {
+	exporter := hashcat.NewRuleExporter(cfg.Generator, cfg.Placeholders)
+	exporter.SetKeywalks(gen.GetKeywalks())
+
+	report, err := exporter.Export(a.flags.ExportRules, gen.GetWordLists())
+	if err != nil {
+		return fmt.Errorf("rule export failed: %w", err)
+	}
+
+	a.printExportReport(report, "\nRun every base wordlist with its rules:")
+
+	return nil
+}
+
+// exportMasks writes hashcat masks and hybrid attack inputs instead of the expanded list
+func (a *App) exportMasks(ctx context.Context, cfg *config.Config, gen *generator.Generator) error {
+	exporter := hashcat.NewMaskExporter(cfg.Generator, cfg.Placeholders)
+	exporter.SetKeywalks(gen.GetKeywalks())
+
+	report, err := exporter.Export(ctx, a.flags.ExportMasks, gen.GetWordLists(), a.printer)
+	if err != nil {
+		return fmt.Errorf("mask export failed: %w", err)
+	}
+
+	a.printExportReport(report, "\nRun every attack:")
 
 	return nil
 }
 
+func (a *App) printExportReport(report *hashcat.Report, commandsTitle string) {
+	a.printer.Info("\nWrote export files:")
+	for _, file := range report.Files {
+		a.printer.Bold("  " + file)
+	}
+
+	a.printer.Info(commandsTitle)
+	for _, command := range report.Commands {
+		a.printer.Bold("  " + command)
+	}
+
+	if len(report.Mismatches) > 0 {
+		a.printer.Warning("\nDifferences from the expanded wordlist:")
+		for _, mismatch := range report.Mismatches {
+			a.printer.Warning("  - " + mismatch)
+		}
+	}
+}
+
+// passRates divides the count of every pattern by its count before the
+// password policy
+func passRates(stats, candidates map[string]int) map[string]float64 {
+	rates := make(map[string]float64, len(stats))
+	for pattern, count := range stats {
+		if candidates[pattern] > 0 {
+			rates[pattern] = float64(count) / float64(candidates[pattern])
+		}
+	}
+
+	return rates
+}
+
+// sumPartCounts estimates the number of passwords in each of total parts
+func (a *App) sumPartCounts(gen *generator.Generator, stats map[string]int, total int) ([]int, error) {
+	parts, err := gen.EstimatePartCounts(stats, total)
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []int
+	for _, partStats := range parts {
+		count := 0
+		for _, patternCount := range partStats {
+			count = config.AddCount(count, patternCount)
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
 func (a *App) loadConfiguration() (*config.Config, error) {
 	return config.Load(a.flags.CfgFile)
 }
@@ -136,20 +357,112 @@ func (a *App) buildConfiguration() (*config.Config, error) {
 		return nil, err
 	}
 
-	a.applyCliOverrides(cfg)
+	if err := a.applyCliOverrides(cfg); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
 
-func (a *App) applyCliOverrides(cfg *config.Config) {
+func (a *App) applyCliOverrides(cfg *config.Config) error {
 	cfg.Output.Filename = a.flags.OutputFile
+	if a.flagChanged("compress") {
+		cfg.Output.Compression = a.flags.Compression
+	}
+	if a.flagChanged("split-lines") {
+		cfg.Output.SplitLines = a.flags.SplitLines
+	}
 	cfg.Generator.MinPasswordLen = a.flags.MinLength
 	cfg.Generator.MaxPasswordLen = a.flags.MaxLength
 	cfg.Generator.MinYear = a.flags.MinYear
 	cfg.Generator.MaxYear = a.flags.MaxYear
+	if a.flagChanged("rank") {
+		cfg.Generator.Ranking.Enabled = a.flags.Rank
+	}
+	cfg.Generator.Roster.Combo = cfg.Generator.Roster.Combo || a.flags.Combo
+
+	if a.flags.Roster != "" {
+		cfg.Generator.Roster.File = a.flags.Roster
+	}
+
+	if a.flagChanged("format") {
+		cfg.Output.Format = a.flags.OutputFormat
+	}
+	if a.flagChanged("delimiter") {
+		cfg.Output.Delimiter = a.flags.Delimiter
+	}
+	if a.flagChanged("escape") {
+		cfg.Output.Escape = a.flags.Escape
+	}
+	if a.flagChanged("attempts") {
+		cfg.Output.Attempts = a.flags.Attempts
+	}
+	if a.flagChanged("windows") {
+		cfg.Output.Windows = a.flags.Windows
+	}
+	if a.flagChanged("lockout-window") {
+		cfg.Output.LockoutWindow = a.flags.LockoutWindow
+	}
+	if a.flagChanged("dedup") {
+		cfg.Generator.Dedup.Mode = a.flags.DedupMode
+	}
+	if a.flagChanged("dedup-dir") {
+		cfg.Generator.Dedup.TempDir = a.flags.DedupDir
+	}
+	if a.flagChanged("dedup-memory") {
+		cfg.Generator.Dedup.MemoryLimit = a.flags.DedupMemory
+	}
+	if a.flagChanged("dedup-fp-rate") {
+		cfg.Generator.Dedup.FalsePositiveRate = a.flags.DedupFPRate
+	}
+	if a.flagChanged("deterministic") {
+		cfg.Generator.Deterministic = a.flags.Deterministic
+	}
+
+	// Spray formats pair every candidate with a roster user, each user's most likely guesses first
+	if cfg.Output.Spray() {
+		cfg.Generator.Roster.Combo = true
+		cfg.Generator.Ranking.Enabled = true
+	}
+
+	if a.flagChanged("top") {
+		cfg.Generator.Ranking.Top = a.flags.Top
+	}
+
+	cfg.Generator.Checkpoint.Enabled = cfg.Generator.Checkpoint.Enabled || a.flags.Checkpoint || a.flags.Resume
+	cfg.Generator.Checkpoint.Resume = a.flags.Resume
+	if a.flags.CheckpointEvery > 0 {
+		cfg.Generator.Checkpoint.Interval = a.flags.CheckpointEvery
+	}
+
+	if a.flags.Part != "" {
+		index, total, err := parsePart(a.flags.Part)
+		if err != nil {
+			return fmt.Errorf("invalid --part: %w", err)
+		}
+		cfg.Generator.Partition = config.PartitionConfig{Index: index, Total: total}
+	}
+
+	cfg.Generator.Window = config.WindowConfig{Skip: a.flags.Skip, Limit: a.flags.Limit}
+
+	if a.flagChanged("split-size") {
+		size, err := parseSize(a.flags.SplitSize)
+		if err != nil {
+			return fmt.Errorf("invalid --split-size: %w", err)
+		}
+		cfg.Output.SplitBytes = size
+	}
+
+	return nil
 }
 
-func (a *App) loadWordLists(gen *generator.Generator, loader *wordlist.Loader) error {
+// flagChanged reports whether the flag was passed on the command line, so
+// that flags left at their defaults keep the values of the config file
+func (a *App) flagChanged(name string) bool {
+	return a.cmd != nil && a.cmd.Flags().Changed(name)
+}
+
+func (a *App) loadWordLists(cfg *config.Config, gen *generator.Generator, loader *wordlist.Loader) error {
 	if a.flags.WordsFile != "" {
 		words, err := loader.LoadFromFile(a.flags.WordsFile)
 		if err != nil {
@@ -169,6 +482,61 @@ func (a *App) loadWordLists(gen *generator.Generator, loader *wordlist.Loader) e
 		a.printer.PrintLoadedWords("SSIDs", len(ssids))
 	}
 
+	for _, placeholder := range cfg.Generator.Placeholders {
+		words := placeholder.Words
+
+		switch {
+		case placeholder.File != "":
+			// Word files are found relative to the config file that declares them
+			path := placeholder.File
+			if !filepath.IsAbs(path) && a.flags.CfgFile != "" {
+				path = filepath.Join(filepath.Dir(a.flags.CfgFile), path)
+			}
+
+			var err error
+			if words, err = loader.LoadFromFile(path); err != nil {
+				return fmt.Errorf("failed to load words of %s: %w", placeholder.Format, err)
+			}
+		case placeholder.Builtin != "":
+			words, _ = wordlist.Builtin(placeholder.Builtin)
+		}
+
+		gen.SetWords(placeholder.Format, words)
+		a.printer.PrintLoadedWords(placeholder.Format, len(words))
+	}
+
+	if roster := cfg.Generator.Roster; roster.File != "" {
+		// A roster named in the config file is found relative to it, like word files
+		path := roster.File
+		if a.flags.Roster == "" && !filepath.IsAbs(path) && a.flags.CfgFile != "" {
+			path = filepath.Join(filepath.Dir(a.flags.CfgFile), path)
+		}
+
+		users, err := loader.LoadRoster(path)
+		if err != nil {
+			return fmt.Errorf("failed to load roster: %w", err)
+		}
+
+		gen.SetUsers(users)
+		a.printer.PrintLoadedWords("roster users", len(users))
+	}
+
+	if keywalk := cfg.Generator.Keywalk; keywalk.File != "" {
+		path := keywalk.File
+		if !filepath.IsAbs(path) && a.flags.CfgFile != "" {
+			path = filepath.Join(filepath.Dir(a.flags.CfgFile), path)
+		}
+
+		layout, err := loader.LoadKeyboard(path)
+		if err != nil {
+			return fmt.Errorf("failed to load keyboard layout: %w", err)
+		}
+
+		walks := generator.Keywalks(layout, keywalk)
+		gen.SetKeywalks(walks)
+		a.printer.PrintLoadedWords("keyboard walks", len(walks))
+	}
+
 	return nil
 }
 
@@ -178,7 +546,11 @@ func (a *App) setupFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&a.flags.WordsFile, "words", "w", "", "path to company names and abbreviations file (one per line)")
 	cmd.Flags().StringVarP(&a.flags.SSIDsFile, "ssids", "s", "", "path to SSIDs file (one per line)")
 
-	cmd.Flags().StringVarP(&a.flags.OutputFile, "output", "o", "passwords.txt", "output file path")
+	cmd.Flags().StringVarP(&a.flags.OutputFile, "output", "o", "passwords.txt", "output file path, or - to stream passwords to stdout")
+	cmd.Flags().StringVar(&a.flags.Compression, "compress", config.CompressionAuto, "output compression: auto (by file extension), none, gzip or zstd")
+	cmd.Flags().Int64Var(&a.flags.SplitLines, "split-lines", 0, "split the output into numbered chunks of at most N lines")
+	cmd.Flags().StringVar(&a.flags.SplitSize, "split-size", "", "split the output into numbered chunks of at most this uncompressed size (e.g. 500M, 2G)")
+	cmd.Flags().BoolVarP(&a.flags.Quiet, "quiet", "q", false, "only print errors")
 
 	cmd.Flags().IntVar(&a.flags.MinLength, "min-length", 8, "minimum password length")
 	cmd.Flags().IntVar(&a.flags.MaxLength, "max-length", 64, "maximum password length")
@@ -186,6 +558,40 @@ func (a *App) setupFlags(cmd *cobra.Command) {
 	cmd.Flags().IntVar(&a.flags.MinYear, "min-year", 1990, "minimum year for combinations")
 	cmd.Flags().IntVar(&a.flags.MaxYear, "max-year", 2025, "maximum year for combinations")
 
+	cmd.Flags().StringVar(&a.flags.DedupMode, "dedup", config.DedupAuto, "deduplication mode: auto, memory, disk, bloom or none")
+	cmd.Flags().StringVar(&a.flags.DedupDir, "dedup-dir", "", "directory for disk deduplication runs (default is the system temp directory)")
+	cmd.Flags().IntVar(&a.flags.DedupMemory, "dedup-memory", 5000000, "passwords held in memory before disk deduplication spills to disk, and the auto mode switch point")
+	cmd.Flags().Float64Var(&a.flags.DedupFPRate, "dedup-fp-rate", 0.001, "false positive rate of bloom deduplication")
+
+	cmd.Flags().BoolVar(&a.flags.Deterministic, "deterministic", false, "write passwords in a stable order (pattern order, then word order) across runs")
+
+	cmd.Flags().BoolVar(&a.flags.Rank, "rank", false, "write the most likely passwords first based on pattern and variation weights")
+	cmd.Flags().IntVar(&a.flags.Top, "top", 0, "stop after writing the first N passwords (0 means no limit)")
+
+	cmd.Flags().StringVar(&a.flags.Part, "part", "", "generate only part i of N disjoint slices of the candidates (e.g. 3/8)")
+
+	cmd.Flags().Uint64Var(&a.flags.Skip, "skip", 0, "skip the first N positions of the candidate stream")
+	cmd.Flags().Uint64Var(&a.flags.Limit, "limit", 0, "stop after N positions of the candidate stream (0 means no limit)")
+
+	cmd.Flags().BoolVar(&a.flags.Checkpoint, "checkpoint", false, "periodically save progress next to the output file so an interrupted run can be resumed")
+	cmd.Flags().BoolVar(&a.flags.Resume, "resume", false, "continue an interrupted run from its saved checkpoint")
+	cmd.Flags().IntVar(&a.flags.CheckpointEvery, "checkpoint-interval", 0, "seconds between checkpoints (default 30)")
+
+	cmd.Flags().StringVar(&a.flags.ExportRules, "export-rules", "", "write base wordlists and hashcat rule files to this directory instead of the expanded list")
+	cmd.Flags().StringVar(&a.flags.ExportMasks, "export-masks", "", "write hashcat masks and hybrid attack wordlists to this directory instead of the expanded list")
+
+	cmd.Flags().StringVar(&a.flags.ADPolicy, "ad-policy", "", "Active Directory password policy dump (Get-AD*PasswordPolicy text or JSON, or LDIF) to filter by, one list per policy object")
+
+	cmd.Flags().StringVar(&a.flags.Roster, "roster", "", "CSV or LDIF roster of users whose names fill <USER>, <FIRST>, <LAST> and <INITIALS>, one user at a time")
+	cmd.Flags().BoolVar(&a.flags.Combo, "combo", false, "write every password as username:password for each roster user")
+
+	cmd.Flags().StringVar(&a.flags.OutputFormat, "format", config.OutputPlain, "output format: plain, or for spraying combo (user:pass lines), per-user (a password file per user) or schedule (combo rounds of --attempts per user)")
+	cmd.Flags().StringVar(&a.flags.Delimiter, "delimiter", ":", "delimiter between username and password in combo and schedule output")
+	cmd.Flags().StringVar(&a.flags.Escape, "escape", config.EscapeNone, "escaping of usernames and passwords that contain the delimiter: none, backslash or csv")
+	cmd.Flags().IntVar(&a.flags.Attempts, "attempts", 0, "spray at most N passwords per user per lockout window (default one below the --ad-policy lockout threshold, 0 means no cap)")
+	cmd.Flags().IntVar(&a.flags.Windows, "windows", 1, "number of lockout windows the spray output covers (0 means as many as the candidates need)")
+	cmd.Flags().DurationVar(&a.flags.LockoutWindow, "lockout-window", 0, "lockout observation window, for the round timing of schedule output (default from --ad-policy)")
+
 	cmd.Flags().BoolVar(&a.flags.ListPlaceholders, "list-placeholders", false, "list all available placeholders and exit")
 	cmd.Flags().BoolVar(&a.flags.CountPasswords, "count-passwords", false, "show the estimated number of passwords to be generated for each pattern")
 
@@ -1,26 +1,125 @@
 package app
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
 
 type Flags struct {
 	CfgFile          string
 	WordsFile        string
 	SSIDsFile        string
 	OutputFile       string
+	Compression      string
+	SplitLines       int64
+	SplitSize        string
 	MinLength        int
 	MaxLength        int
 	MinYear          int
 	MaxYear          int
 	ListPlaceholders bool
 	CountPasswords   bool
+	Quiet            bool
+	DedupMode        string
+	DedupDir         string
+	DedupMemory      int
+	DedupFPRate      float64
+	Deterministic    bool
+	Rank             bool
+	Top              int
+	Checkpoint       bool
+	Resume           bool
+	CheckpointEvery  int
+	Part             string
+	Skip             uint64
+	Limit            uint64
+	ExportRules      string
+	ExportMasks      string
+	ADPolicy         string
+	Roster           string
+	Combo            bool
+	OutputFormat     string
+	Delimiter        string
+	Escape           string
+	Attempts         int
+	Windows          int
+	LockoutWindow    time.Duration
 }
 
 func NewFlags() *Flags {
 	return &Flags{
-		OutputFile: "passwords.txt",
-		MinLength:  8,
-		MaxLength:  64,
-		MinYear:    1990,
-		MaxYear:    time.Now().Year(),
+		OutputFile:   "passwords.txt",
+		Compression:  config.CompressionAuto,
+		MinLength:    8,
+		MaxLength:    64,
+		MinYear:      1990,
+		MaxYear:      time.Now().Year(),
+		DedupMode:    config.DedupAuto,
+		OutputFormat: config.OutputPlain,
+		Delimiter:    ":",
+		Escape:       config.EscapeNone,
+		Windows:      1,
+	}
+}
+
+var sizeUnits = map[string]int64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// parseSize reads a byte size such as 500M or 2G using binary units
+func parseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+
+	unit := ""
+	if len(value) > 0 && (value[len(value)-1] < '0' || value[len(value)-1] > '9') {
+		unit = value[len(value)-1:]
+		value = value[:len(value)-1]
+	}
+
+	multiplier, exists := sizeUnits[unit]
+	if !exists {
+		return 0, fmt.Errorf("unknown size unit '%s'", unit)
 	}
-}
\ No newline at end of file
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", value+unit)
+	}
+
+	if size > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size '%s' is too large", value+unit)
+	}
+
+	return size * multiplier, nil
+}
+
+// parsePart reads a part selector such as 3/8
+func parsePart(value string) (int, int, error) {
+	index, total, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("expected the form i/N, got '%s'", value)
+	}
+
+	partIndex, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid part index '%s'", index)
+	}
+
+	partTotal, err := strconv.Atoi(total)
+	if err != nil || partTotal < 1 {
+		return 0, 0, fmt.Errorf("invalid part count '%s'", total)
+	}
+
+	return partIndex, partTotal, nil
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -22,18 +24,294 @@ type GeneratorConfig struct {
 	Substitutions  map[string][]string `mapstructure:"substitutions" json:"substitutions"`
 	NumberPatterns []string            `mapstructure:"number_patterns" json:"number_patterns"`
 	Patterns       []string            `mapstructure:"patterns" json:"patterns"`
+	Dedup          DedupConfig         `mapstructure:"dedup" json:"dedup"`
+	Deterministic  bool                `mapstructure:"deterministic" json:"deterministic"`
+	Ranking        RankingConfig       `mapstructure:"ranking" json:"ranking"`
+	Variations     VariationProfiles   `mapstructure:"variations" json:"variations"`
+	Strategies     VariationStrategies `mapstructure:"variation_strategies" json:"variation_strategies"`
+	Placeholders   []UserPlaceholder   `mapstructure:"placeholders" json:"placeholders"`
+	Dates          DatesConfig         `mapstructure:"dates" json:"dates"`
+	Keywalk        KeywalkConfig       `mapstructure:"keywalk" json:"keywalk"`
+	Checkpoint     CheckpointConfig    `mapstructure:"checkpoint" json:"checkpoint"`
+	Policy         PolicyConfig        `mapstructure:"policy" json:"policy"`
+	Roster         RosterConfig        `mapstructure:"roster" json:"roster"`
+	Partition      PartitionConfig     `mapstructure:"-" json:"-"`
+	Window         WindowConfig        `mapstructure:"-" json:"-"`
 }
 
+const (
+	DedupAuto   = "auto"
+	DedupNone   = "none"
+	DedupMemory = "memory"
+	DedupDisk   = "disk"
+	DedupBloom  = "bloom"
+)
+
+type DedupConfig struct {
+	Mode              string  `mapstructure:"mode" json:"mode"`
+	MemoryLimit       int     `mapstructure:"memory_limit" json:"memory_limit"`
+	TempDir           string  `mapstructure:"temp_dir" json:"temp_dir"`
+	FalsePositiveRate float64 `mapstructure:"false_positive_rate" json:"false_positive_rate"`
+}
+
+type RankingConfig struct {
+	Enabled          bool               `mapstructure:"enabled" json:"enabled"`
+	Top              int                `mapstructure:"top" json:"top"`
+	PatternWeights   map[string]float64 `mapstructure:"pattern_weights" json:"pattern_weights"`
+	VariationWeights VariationWeights   `mapstructure:"variation_weights" json:"variation_weights"`
+}
+
+type VariationWeights struct {
+	Original float64 `mapstructure:"original" json:"original"`
+	Case     float64 `mapstructure:"case" json:"case"`
+	Leet     float64 `mapstructure:"leet" json:"leet"`
+}
+
+// UserPlaceholder declares a placeholder filled from a word file, an inline
+// list or a built-in list. A nil Variations applies every variation stage.
+type UserPlaceholder struct {
+	Format      string            `mapstructure:"format" json:"format"`
+	Description string            `mapstructure:"description" json:"description,omitempty"`
+	File        string            `mapstructure:"file" json:"file,omitempty"`
+	Words       []string          `mapstructure:"words" json:"words,omitempty"`
+	Builtin     string            `mapstructure:"builtin" json:"builtin,omitempty"`
+	Variations  *VariationProfile `mapstructure:"variations" json:"variations,omitempty"`
+	Strategy    VariationStrategy `mapstructure:"variation_strategy" json:"variation_strategy,omitempty"`
+}
+
+// VariationProfile selects the variation stages applied to a word list:
+// word segmentation (spacing and individual words), case and leet
+type VariationProfile struct {
+	Words bool `mapstructure:"words" json:"words"`
+	Case  bool `mapstructure:"case" json:"case"`
+	Leet  bool `mapstructure:"leet" json:"leet"`
+}
+
+// VariationProfiles selects a VariationProfile per word list
+type VariationProfiles struct {
+	Custom VariationProfile `mapstructure:"custom" json:"custom"`
+	Common VariationProfile `mapstructure:"common" json:"common"`
+	SSID   VariationProfile `mapstructure:"ssid" json:"ssid"`
+}
+
+// JSONVariationProfiles leaves the lists missing from the JSON config untouched
+type JSONVariationProfiles struct {
+	Custom *VariationProfile `json:"custom,omitempty"`
+	Common *VariationProfile `json:"common,omitempty"`
+	SSID   *VariationProfile `json:"ssid,omitempty"`
+}
+
+const (
+	CaseAll         = "all"
+	CaseLower       = "lower"
+	CaseUpper       = "upper"
+	CaseTitle       = "title"
+	CaseCamel       = "camel"
+	CaseToggleFirst = "toggle_first"
+	CaseInverted    = "inverted"
+)
+
+// VariationStrategy caps the case and leet variations of a word list. An
+// empty Case list or "all" keeps every upper/lower combination. A zero
+// MaxSubstitutions or SubstituteLast leaves that dimension unlimited.
+type VariationStrategy struct {
+	Case             []string `mapstructure:"case" json:"case,omitempty"`
+	MaxSubstitutions int      `mapstructure:"max_substitutions" json:"max_substitutions,omitempty"`
+	SubstituteLast   int      `mapstructure:"substitute_last" json:"substitute_last,omitempty"`
+}
+
+// VariationStrategies selects a VariationStrategy per word list
+type VariationStrategies struct {
+	Custom VariationStrategy `mapstructure:"custom" json:"custom"`
+	Common VariationStrategy `mapstructure:"common" json:"common"`
+	SSID   VariationStrategy `mapstructure:"ssid" json:"ssid"`
+}
+
+// DatesConfig sets the languages of the month and season names and the
+// range of the full date placeholders, as YYYY-MM-DD. An empty Start or End
+// falls back to the first or last day of the year range.
+type DatesConfig struct {
+	Locales []string `mapstructure:"locales" json:"locales"`
+	Start   string   `mapstructure:"start" json:"start,omitempty"`
+	End     string   `mapstructure:"end" json:"end,omitempty"`
+}
+
+const dateLayout = "2006-01-02"
+
+// Range returns the first and last day of the full date placeholders
+func (d DatesConfig) Range(minYear, maxYear int) (time.Time, time.Time, error) {
+	start := time.Date(minYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(maxYear, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	var err error
+	if d.Start != "" {
+		if start, err = time.Parse(dateLayout, d.Start); err != nil {
+			return start, end, fmt.Errorf("invalid date range start '%s' (expected YYYY-MM-DD)", d.Start)
+		}
+	}
+	if d.End != "" {
+		if end, err = time.Parse(dateLayout, d.End); err != nil {
+			return start, end, fmt.Errorf("invalid date range end '%s' (expected YYYY-MM-DD)", d.End)
+		}
+	}
+
+	return start, end, nil
+}
+
+const (
+	WalkRight = "right"
+	WalkLeft  = "left"
+	WalkDown  = "down"
+	WalkUp    = "up"
+)
+
+// KeywalkConfig shapes the keyboard walks of <KEYWALK>. A walk is up to
+// MaxSegments straight runs of keys of the same length in one of the
+// Directions, between MinLength and MaxLength keys in total. Layout names a
+// built-in keyboard; File loads one instead. Shift also tries every run
+// typed with shift.
+type KeywalkConfig struct {
+	Layout      string   `mapstructure:"layout" json:"layout"`
+	File        string   `mapstructure:"file" json:"file,omitempty"`
+	MinLength   int      `mapstructure:"min_length" json:"min_length"`
+	MaxLength   int      `mapstructure:"max_length" json:"max_length"`
+	Directions  []string `mapstructure:"directions" json:"directions"`
+	MaxSegments int      `mapstructure:"max_segments" json:"max_segments"`
+	Shift       bool     `mapstructure:"shift" json:"shift"`
+}
+
+// RosterConfig controls per-user generation. File is a CSV or LDIF roster
+// of users, relative to the config file. Every user's fields fill <USER>,
+// <FIRST>, <LAST> and <INITIALS> with that user's values only, varied by
+// Variations and Strategy. Combo writes every candidate as
+// username:password, also for patterns without a roster placeholder.
+type RosterConfig struct {
+	File       string            `mapstructure:"file" json:"file,omitempty"`
+	Variations VariationProfile  `mapstructure:"variations" json:"variations"`
+	Strategy   VariationStrategy `mapstructure:"variation_strategy" json:"variation_strategy,omitempty"`
+	Combo      bool              `mapstructure:"combo" json:"combo"`
+}
+
+// JSONRosterConfig leaves the roster settings missing from the JSON config untouched
+type JSONRosterConfig struct {
+	File       string             `json:"file,omitempty"`
+	Variations *VariationProfile  `json:"variations,omitempty"`
+	Strategy   *VariationStrategy `json:"variation_strategy,omitempty"`
+	Combo      bool               `json:"combo,omitempty"`
+}
+
+const (
+	CompressionAuto = "auto"
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// CheckpointConfig controls periodic saving of progress so an interrupted
+// run can be resumed. Interval is in seconds.
+type CheckpointConfig struct {
+	Enabled  bool `mapstructure:"enabled" json:"enabled"`
+	Interval int  `mapstructure:"interval" json:"interval"`
+	Resume   bool `mapstructure:"-" json:"-"`
+}
+
+// PartitionConfig selects part Index (1-based) of Total equal slices of the
+// candidate stream. A zero Total generates the whole stream.
+type PartitionConfig struct {
+	Index int
+	Total int
+}
+
+// WindowConfig skips the first Skip positions of the candidate stream and
+// stops after Limit more. A zero Limit runs to the end of the stream.
+type WindowConfig struct {
+	Skip  uint64
+	Limit uint64
+}
+
+// OutputConfig describes where and how candidates are written. The spray
+// formats write roster combos: combo writes username, Delimiter and
+// password lines, per-user writes one password file per user, and schedule
+// writes a single combo list in rounds. Every user gets at most Attempts
+// candidates per lockout window (0 means no cap) over Windows windows (0
+// means as many as the candidates need).
 type OutputConfig struct {
-	Filename string `mapstructure:"filename" json:"filename"`
+	Filename      string        `mapstructure:"filename" json:"filename"`
+	Compression   string        `mapstructure:"compression" json:"compression"`
+	SplitLines    int64         `mapstructure:"split_lines" json:"split_lines"`
+	SplitBytes    int64         `mapstructure:"split_bytes" json:"split_bytes"`
+	Format        string        `mapstructure:"format" json:"format"`
+	Delimiter     string        `mapstructure:"delimiter" json:"delimiter"`
+	Escape        string        `mapstructure:"escape" json:"escape"`
+	Attempts      int           `mapstructure:"attempts" json:"attempts"`
+	Windows       int           `mapstructure:"windows" json:"windows"`
+	LockoutWindow time.Duration `mapstructure:"lockout_window" json:"lockout_window"`
+}
+
+// Spray reports whether the output format writes roster combos
+func (o OutputConfig) Spray() bool {
+	return o.Format != OutputPlain && o.Format != ""
+}
+
+// Compressed reports whether the output is compressed, either by name or, in
+// auto mode, by the file extension
+func (o OutputConfig) Compressed() bool {
+	switch o.Compression {
+	case CompressionNone:
+		return false
+	case CompressionAuto, "":
+		return strings.HasSuffix(o.Filename, ".gz") || strings.HasSuffix(o.Filename, ".zst")
+	}
+
+	return true
+}
+
+const (
+	OutputPlain    = "plain"
+	OutputCombo    = "combo"
+	OutputPerUser  = "per-user"
+	OutputSchedule = "schedule"
+)
+
+const (
+	EscapeNone      = "none"
+	EscapeBackslash = "backslash"
+	EscapeCSV       = "csv"
+)
+
+// JSONOutputConfig holds the output settings of the JSON config. The split
+// size is a plain byte count and the lockout window is a duration such as
+// "30m"; a missing Windows keeps the default of one window.
+type JSONOutputConfig struct {
+	Compression   string `json:"compression,omitempty"`
+	SplitLines    int64  `json:"split_lines,omitempty"`
+	SplitBytes    int64  `json:"split_bytes,omitempty"`
+	Format        string `json:"format,omitempty"`
+	Delimiter     string `json:"delimiter,omitempty"`
+	Escape        string `json:"escape,omitempty"`
+	Attempts      int    `json:"attempts,omitempty"`
+	Windows       *int   `json:"windows,omitempty"`
+	LockoutWindow string `json:"lockout_window,omitempty"`
 }
 
 type JSONConfig struct {
-	CommonWords    []string            `json:"common_words,omitempty"`
-	Separators     []string            `json:"separators,omitempty"`
-	NumberPatterns []string            `json:"number_patterns,omitempty"`
-	Substitutions  map[string][]string `json:"substitutions,omitempty"`
-	Patterns       []string            `json:"patterns,omitempty"`
+	CommonWords    []string               `json:"common_words,omitempty"`
+	Separators     []string               `json:"separators,omitempty"`
+	NumberPatterns []string               `json:"number_patterns,omitempty"`
+	Substitutions  map[string][]string    `json:"substitutions,omitempty"`
+	Patterns       []string               `json:"patterns,omitempty"`
+	Dedup          *DedupConfig           `json:"dedup,omitempty"`
+	Deterministic  *bool                  `json:"deterministic,omitempty"`
+	Checkpoint     *CheckpointConfig      `json:"checkpoint,omitempty"`
+	Ranking        *RankingConfig         `json:"ranking,omitempty"`
+	Variations     *JSONVariationProfiles `json:"variations,omitempty"`
+	Strategies     *VariationStrategies   `json:"variation_strategies,omitempty"`
+	Placeholders   []UserPlaceholder      `json:"placeholders,omitempty"`
+	Dates          *DatesConfig           `json:"dates,omitempty"`
+	Keywalk        *KeywalkConfig         `json:"keywalk,omitempty"`
+	Policy         *PolicyConfig          `json:"policy,omitempty"`
+	Roster         *JSONRosterConfig      `json:"roster,omitempty"`
+	Output         *JSONOutputConfig      `json:"output,omitempty"`
 }
 
 func Load(jsonConfigPath string) (*Config, error) {
@@ -63,11 +341,10 @@ func (c *Config) loadFromJSON(filePath string) error {
 		return fmt.Errorf("failed to parse JSON config: %w", err)
 	}
 
-	c.applyJSONConfig(&jsonConfig)
-	return nil
+	return c.applyJSONConfig(&jsonConfig)
 }
 
-func (c *Config) applyJSONConfig(jsonConfig *JSONConfig) {
+func (c *Config) applyJSONConfig(jsonConfig *JSONConfig) error {
 	if len(jsonConfig.CommonWords) > 0 {
 		c.Generator.CommonWords = jsonConfig.CommonWords
 	}
@@ -83,4 +360,177 @@ func (c *Config) applyJSONConfig(jsonConfig *JSONConfig) {
 	if len(jsonConfig.Patterns) > 0 {
 		c.Generator.Patterns = jsonConfig.Patterns
 	}
+	if jsonConfig.Dedup != nil {
+		c.applyDedupConfig(jsonConfig.Dedup)
+	}
+	if jsonConfig.Deterministic != nil {
+		c.Generator.Deterministic = *jsonConfig.Deterministic
+	}
+	if jsonConfig.Checkpoint != nil {
+		c.applyCheckpointConfig(jsonConfig.Checkpoint)
+	}
+	if jsonConfig.Ranking != nil {
+		c.applyRankingConfig(jsonConfig.Ranking)
+	}
+	if jsonConfig.Variations != nil {
+		c.applyVariationProfiles(jsonConfig.Variations)
+	}
+	if jsonConfig.Strategies != nil {
+		c.Generator.Strategies = *jsonConfig.Strategies
+	}
+	if len(jsonConfig.Placeholders) > 0 {
+		c.Generator.Placeholders = jsonConfig.Placeholders
+	}
+	if jsonConfig.Dates != nil {
+		c.applyDatesConfig(jsonConfig.Dates)
+	}
+	if jsonConfig.Keywalk != nil {
+		c.applyKeywalkConfig(jsonConfig.Keywalk)
+	}
+	if jsonConfig.Policy != nil {
+		c.Generator.Policy = *jsonConfig.Policy
+	}
+	if jsonConfig.Roster != nil {
+		c.applyRosterConfig(jsonConfig.Roster)
+	}
+	if jsonConfig.Output != nil {
+		return c.applyOutputConfig(jsonConfig.Output)
+	}
+
+	return nil
+}
+
+func (c *Config) applyOutputConfig(output *JSONOutputConfig) error {
+	if output.Compression != "" {
+		c.Output.Compression = output.Compression
+	}
+	if output.SplitLines > 0 {
+		c.Output.SplitLines = output.SplitLines
+	}
+	if output.SplitBytes > 0 {
+		c.Output.SplitBytes = output.SplitBytes
+	}
+	if output.Format != "" {
+		c.Output.Format = output.Format
+	}
+	if output.Delimiter != "" {
+		c.Output.Delimiter = output.Delimiter
+	}
+	if output.Escape != "" {
+		c.Output.Escape = output.Escape
+	}
+	if output.Attempts > 0 {
+		c.Output.Attempts = output.Attempts
+	}
+	if output.Windows != nil {
+		c.Output.Windows = *output.Windows
+	}
+	if output.LockoutWindow != "" {
+		window, err := time.ParseDuration(output.LockoutWindow)
+		if err != nil {
+			return fmt.Errorf("invalid output lockout_window '%s'", output.LockoutWindow)
+		}
+		c.Output.LockoutWindow = window
+	}
+
+	return nil
+}
+
+func (c *Config) applyCheckpointConfig(checkpoint *CheckpointConfig) {
+	if checkpoint.Enabled {
+		c.Generator.Checkpoint.Enabled = true
+	}
+	if checkpoint.Interval > 0 {
+		c.Generator.Checkpoint.Interval = checkpoint.Interval
+	}
+}
+
+func (c *Config) applyDedupConfig(dedup *DedupConfig) {
+	if dedup.Mode != "" {
+		c.Generator.Dedup.Mode = dedup.Mode
+	}
+	if dedup.MemoryLimit > 0 {
+		c.Generator.Dedup.MemoryLimit = dedup.MemoryLimit
+	}
+	if dedup.TempDir != "" {
+		c.Generator.Dedup.TempDir = dedup.TempDir
+	}
+	if dedup.FalsePositiveRate > 0 {
+		c.Generator.Dedup.FalsePositiveRate = dedup.FalsePositiveRate
+	}
+}
+
+func (c *Config) applyRosterConfig(roster *JSONRosterConfig) {
+	c.Generator.Roster.File = roster.File
+	c.Generator.Roster.Combo = roster.Combo
+	if roster.Variations != nil {
+		c.Generator.Roster.Variations = *roster.Variations
+	}
+	if roster.Strategy != nil {
+		c.Generator.Roster.Strategy = *roster.Strategy
+	}
+}
+
+func (c *Config) applyDatesConfig(dates *DatesConfig) {
+	if len(dates.Locales) > 0 {
+		c.Generator.Dates.Locales = dates.Locales
+	}
+	c.Generator.Dates.Start = dates.Start
+	c.Generator.Dates.End = dates.End
+}
+
+func (c *Config) applyKeywalkConfig(keywalk *KeywalkConfig) {
+	walk := &c.Generator.Keywalk
+
+	if keywalk.Layout != "" {
+		walk.Layout = keywalk.Layout
+	}
+	walk.File = keywalk.File
+	if keywalk.MinLength > 0 {
+		walk.MinLength = keywalk.MinLength
+	}
+	if keywalk.MaxLength > 0 {
+		walk.MaxLength = keywalk.MaxLength
+	}
+	if len(keywalk.Directions) > 0 {
+		walk.Directions = keywalk.Directions
+	}
+	if keywalk.MaxSegments > 0 {
+		walk.MaxSegments = keywalk.MaxSegments
+	}
+	walk.Shift = keywalk.Shift
+}
+
+func (c *Config) applyRankingConfig(ranking *RankingConfig) {
+	c.Generator.Ranking.Enabled = c.Generator.Ranking.Enabled || ranking.Enabled
+
+	if ranking.Top > 0 {
+		c.Generator.Ranking.Top = ranking.Top
+	}
+	if len(ranking.PatternWeights) > 0 {
+		c.Generator.Ranking.PatternWeights = ranking.PatternWeights
+	}
+
+	weights := &c.Generator.Ranking.VariationWeights
+	if ranking.VariationWeights.Original > 0 {
+		weights.Original = ranking.VariationWeights.Original
+	}
+	if ranking.VariationWeights.Case > 0 {
+		weights.Case = ranking.VariationWeights.Case
+	}
+	if ranking.VariationWeights.Leet > 0 {
+		weights.Leet = ranking.VariationWeights.Leet
+	}
+}
+
+func (c *Config) applyVariationProfiles(profiles *JSONVariationProfiles) {
+	if profiles.Custom != nil {
+		c.Generator.Variations.Custom = *profiles.Custom
+	}
+	if profiles.Common != nil {
+		c.Generator.Variations.Common = *profiles.Common
+	}
+	if profiles.SSID != nil {
+		c.Generator.Variations.SSID = *profiles.SSID
+	}
 }
\ No newline at end of file
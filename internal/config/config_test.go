@@ -1,10 +1,12 @@
 package config
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -51,6 +53,145 @@ func TestLoad(t *testing.T) {
 		}
 	})
 
+	t.Run("variation strategies", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "config.json")
+		jsonData := `{
+			"variation_strategies": {
+				"custom": {"case": ["lower", "title"], "max_substitutions": 2, "substitute_last": 4}
+			}
+		}`
+		if err := os.WriteFile(tmpFile, []byte(jsonData), 0644); err != nil {
+			t.Fatalf("Failed to create temp JSON file: %v", err)
+		}
+
+		cfg, err := Load(tmpFile)
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		expected := VariationStrategy{Case: []string{CaseLower, CaseTitle}, MaxSubstitutions: 2, SubstituteLast: 4}
+		if !reflect.DeepEqual(cfg.Generator.Strategies.Custom, expected) {
+			t.Errorf("expected custom strategy %+v, got %+v", expected, cfg.Generator.Strategies.Custom)
+		}
+
+		if !reflect.DeepEqual(cfg.Generator.Strategies.Common, VariationStrategy{}) {
+			t.Errorf("expected the common strategy to stay unlimited, got %+v", cfg.Generator.Strategies.Common)
+		}
+
+		cfg.Generator.Strategies.SSID.Case = []string{"sponge"}
+		if err := cfg.Validate(); err == nil {
+			t.Error("expected an error for an unknown case strategy, got nil")
+		}
+	})
+
+	t.Run("variation profiles", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "config.json")
+		jsonData := `{"variations": {"ssid": {"case": true}}}`
+		if err := os.WriteFile(tmpFile, []byte(jsonData), 0644); err != nil {
+			t.Fatalf("Failed to create temp JSON file: %v", err)
+		}
+
+		cfg, err := Load(tmpFile)
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		if expected := (VariationProfile{Case: true}); cfg.Generator.Variations.SSID != expected {
+			t.Errorf("expected SSID profile %+v, got %+v", expected, cfg.Generator.Variations.SSID)
+		}
+
+		if expected := NewDefaultVariationProfiles().Custom; cfg.Generator.Variations.Custom != expected {
+			t.Errorf("expected the custom profile to keep every stage, got %+v", cfg.Generator.Variations.Custom)
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(tmpFile, []byte(`{"deterministic": true}`), 0644); err != nil {
+			t.Fatalf("Failed to create temp JSON file: %v", err)
+		}
+
+		cfg, err := Load(tmpFile)
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		if !cfg.Generator.Deterministic {
+			t.Error("expected deterministic output from the config file")
+		}
+	})
+
+	t.Run("dedup", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "config.json")
+		jsonData := `{"dedup": {"mode": "bloom", "memory_limit": 1000, "false_positive_rate": 0.01}}`
+		if err := os.WriteFile(tmpFile, []byte(jsonData), 0644); err != nil {
+			t.Fatalf("Failed to create temp JSON file: %v", err)
+		}
+
+		cfg, err := Load(tmpFile)
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		expected := NewDefaultDedupConfig()
+		expected.Mode = DedupBloom
+		expected.MemoryLimit = 1000
+		expected.FalsePositiveRate = 0.01
+		if cfg.Generator.Dedup != expected {
+			t.Errorf("expected dedup settings %+v, got %+v", expected, cfg.Generator.Dedup)
+		}
+	})
+
+	t.Run("checkpoint", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "config.json")
+		jsonData := `{"checkpoint": {"enabled": true, "interval": 5}}`
+		if err := os.WriteFile(tmpFile, []byte(jsonData), 0644); err != nil {
+			t.Fatalf("Failed to create temp JSON file: %v", err)
+		}
+
+		cfg, err := Load(tmpFile)
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		expected := CheckpointConfig{Enabled: true, Interval: 5}
+		if cfg.Generator.Checkpoint != expected {
+			t.Errorf("expected checkpoint settings %+v, got %+v", expected, cfg.Generator.Checkpoint)
+		}
+	})
+
+	t.Run("output", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "config.json")
+		jsonData := `{"output": {"compression": "zstd", "split_lines": 1000, "format": "schedule", "escape": "csv", "attempts": 4, "windows": 0, "lockout_window": "30m"}}`
+		if err := os.WriteFile(tmpFile, []byte(jsonData), 0644); err != nil {
+			t.Fatalf("Failed to create temp JSON file: %v", err)
+		}
+
+		cfg, err := Load(tmpFile)
+		if err != nil {
+			t.Fatalf("Load() returned error: %v", err)
+		}
+
+		expected := NewDefaultOutputConfig()
+		expected.Compression = CompressionZstd
+		expected.SplitLines = 1000
+		expected.Format = OutputSchedule
+		expected.Escape = EscapeCSV
+		expected.Attempts = 4
+		expected.Windows = 0
+		expected.LockoutWindow = 30 * time.Minute
+		if cfg.Output != expected {
+			t.Errorf("expected output settings %+v, got %+v", expected, cfg.Output)
+		}
+
+		if err := os.WriteFile(tmpFile, []byte(`{"output": {"lockout_window": "half an hour"}}`), 0644); err != nil {
+			t.Fatalf("Failed to create temp JSON file: %v", err)
+		}
+		if _, err := Load(tmpFile); err == nil {
+			t.Error("expected an error for an invalid lockout window, got nil")
+		}
+	})
+
 	t.Run("non existent JSON file", func(t *testing.T) {
 		_, err := Load("non_existent.json")
 		if err == nil {
@@ -69,4 +210,515 @@ func TestLoad(t *testing.T) {
 			t.Error("expected error for invalid JSON, got nil")
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestValidateUserPlaceholders(t *testing.T) {
+	tests := []struct {
+		name        string
+		placeholder UserPlaceholder
+		valid       bool
+	}{
+		{"inline words", UserPlaceholder{Format: "<CITY>", Words: []string{"paris"}}, true},
+		{"builtin list", UserPlaceholder{Format: "<MONTH>", Builtin: "months"}, true},
+		{"no source", UserPlaceholder{Format: "<CITY>"}, false},
+		{"two sources", UserPlaceholder{Format: "<CITY>", File: "cities.txt", Words: []string{"paris"}}, false},
+		{"unknown builtin", UserPlaceholder{Format: "<CITY>", Builtin: "cities"}, false},
+		{"clashes with a built-in placeholder", UserPlaceholder{Format: "<YEAR>", Words: []string{"1"}}, false},
+		{"malformed format", UserPlaceholder{Format: "CITY", Words: []string{"paris"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Placeholders = []UserPlaceholder{tt.placeholder}
+			cfg.Generator.Patterns = append(cfg.Generator.Patterns, "<CUSTOM>"+tt.placeholder.Format)
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateNumberedPlaceholders(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		valid   bool
+	}{
+		{"independent repeats", "<CUSTOM><SEP><CUSTOM>", true},
+		{"numbered value repeated", "<CUSTOM:1><SEP><CUSTOM=1>", true},
+		{"short year repeats a numbered year", "<YEAR:1><CUSTOM><SHORTYEAR=1>", true},
+		{"user placeholder numbered", "<CITY:2><CITY=2>", true},
+		{"back-reference without a numbered value", "<CUSTOM><CUSTOM=1>", false},
+		{"back-reference to another placeholder", "<COMMON:1><CUSTOM=1>", false},
+		{"numbered unknown placeholder", "<CUSTOM><FOO:1>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Placeholders = []UserPlaceholder{{Format: "<CITY>", Words: []string{"paris"}}}
+			cfg.Generator.Patterns = []string{tt.pattern}
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidatePlaceholderModifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		valid   bool
+	}{
+		{"case modifier", "<CUSTOM|upper>", true},
+		{"chained modifiers", "<CUSTOM|title|first3><SEP><YEAR|rev>", true},
+		{"numbered and modified", "<CUSTOM:1><SEP><CUSTOM=1|reverse>", true},
+		{"filter on a word list", "<SSID|noleet><CITY|nocase>", true},
+		{"unknown modifier", "<CUSTOM|shout>", false},
+		{"truncation to nothing", "<CUSTOM|first0>", false},
+		{"filter on a year", "<CUSTOM><YEAR|noleet>", false},
+		{"modified unknown placeholder", "<CUSTOM><FOO|upper>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Placeholders = []UserPlaceholder{{Format: "<CITY>", Words: []string{"paris"}}}
+			cfg.Generator.Patterns = []string{tt.pattern}
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateDates(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates DatesConfig
+		valid bool
+	}{
+		{"defaults", NewDefaultDatesConfig(), true},
+		{"several locales and a range", DatesConfig{Locales: []string{"en", "de"}, Start: "2024-03-01", End: "2025-02-28"}, true},
+		{"unknown locale", DatesConfig{Locales: []string{"xx"}}, false},
+		{"no locale", DatesConfig{}, false},
+		{"malformed start", DatesConfig{Locales: []string{"en"}, Start: "01/03/2024"}, false},
+		{"start after end", DatesConfig{Locales: []string{"en"}, Start: "2025-01-01", End: "2024-01-01"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Dates = tt.dates
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateKeywalk(t *testing.T) {
+	tests := []struct {
+		name    string
+		keywalk KeywalkConfig
+		valid   bool
+	}{
+		{"defaults", NewDefaultKeywalkConfig(), true},
+		{"layout file", KeywalkConfig{File: "dvorak.txt", MinLength: 4, MaxLength: 6, Directions: []string{WalkRight}, MaxSegments: 1}, true},
+		{"unknown layout", KeywalkConfig{Layout: "dvorak", MinLength: 4, MaxLength: 6, Directions: []string{WalkRight}, MaxSegments: 1}, false},
+		{"single key walks", KeywalkConfig{Layout: "qwerty", MinLength: 1, MaxLength: 6, Directions: []string{WalkRight}, MaxSegments: 1}, false},
+		{"min length above max", KeywalkConfig{Layout: "qwerty", MinLength: 8, MaxLength: 6, Directions: []string{WalkRight}, MaxSegments: 1}, false},
+		{"unknown direction", KeywalkConfig{Layout: "qwerty", MinLength: 4, MaxLength: 6, Directions: []string{"diagonal"}, MaxSegments: 1}, false},
+		{"no direction", KeywalkConfig{Layout: "qwerty", MinLength: 4, MaxLength: 6, MaxSegments: 1}, false},
+		{"no segment", KeywalkConfig{Layout: "qwerty", MinLength: 4, MaxLength: 6, Directions: []string{WalkRight}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Keywalk = tt.keywalk
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy PolicyConfig
+		valid  bool
+	}{
+		{"no policy", PolicyConfig{}, true},
+		{"preset", PolicyConfig{Preset: PolicyADDefault}, true},
+		{"preset with extra rules", PolicyConfig{Preset: PolicyNIST, Require: []string{ClassSpecial}, MinClasses: 2}, true},
+		{"unknown preset", PolicyConfig{Preset: "fips"}, false},
+		{"unknown class", PolicyConfig{Require: []string{"emoji"}}, false},
+		{"too many classes", PolicyConfig{MinClasses: 5}, false},
+		{"min length above max", PolicyConfig{MinLength: 100}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Policy = tt.policy
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateRoster(t *testing.T) {
+	tests := []struct {
+		name   string
+		roster func(*RosterConfig)
+		valid  bool
+	}{
+		{"defaults", func(*RosterConfig) {}, true},
+		{"combo with file", func(r *RosterConfig) { r.File = "users.csv"; r.Combo = true }, true},
+		{"combo without file", func(r *RosterConfig) { r.Combo = true }, false},
+		{"unknown case strategy", func(r *RosterConfig) { r.Strategy.Case = []string{"snake"} }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			tt.roster(&cfg.Generator.Roster)
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateSpray(t *testing.T) {
+	tests := []struct {
+		name   string
+		output func(*OutputConfig)
+		roster bool
+		valid  bool
+	}{
+		{"plain", func(*OutputConfig) {}, false, true},
+		{"combo", func(o *OutputConfig) { o.Format = OutputCombo; o.Attempts = 4 }, true, true},
+		{"combo to stdout", func(o *OutputConfig) { o.Format = OutputCombo; o.Filename = "-" }, true, true},
+		{"combo without roster", func(o *OutputConfig) { o.Format = OutputCombo }, false, false},
+		{"unknown format", func(o *OutputConfig) { o.Format = "kerbrute" }, true, false},
+		{"unknown escape", func(o *OutputConfig) { o.Format = OutputCombo; o.Escape = "url" }, true, false},
+		{"empty delimiter", func(o *OutputConfig) { o.Format = OutputCombo; o.Delimiter = "" }, true, false},
+		{"per-user without delimiter", func(o *OutputConfig) { o.Format = OutputPerUser; o.Delimiter = "" }, true, true},
+		{"per-user to stdout", func(o *OutputConfig) { o.Format = OutputPerUser; o.Filename = "-" }, true, false},
+		{"schedule without attempts", func(o *OutputConfig) { o.Format = OutputSchedule }, true, false},
+		{"negative attempts", func(o *OutputConfig) { o.Attempts = -1 }, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			tt.output(&cfg.Output)
+			if tt.roster {
+				cfg.Generator.Roster.File = "users.csv"
+			}
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateRanking(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranking func(*RankingConfig)
+		valid   bool
+	}{
+		{"defaults", func(*RankingConfig) {}, true},
+		{"top cutoff", func(r *RankingConfig) { r.Enabled = true; r.Top = 1000 }, true},
+		{"negative top cutoff", func(r *RankingConfig) { r.Top = -1 }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			tt.ranking(&cfg.Generator.Ranking)
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateCheckpoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		output func(*OutputConfig)
+		valid  bool
+	}{
+		{"plain file", func(*OutputConfig) {}, true},
+		{"stdout", func(o *OutputConfig) { o.Filename = "-" }, false},
+		{"split", func(o *OutputConfig) { o.SplitLines = 1000 }, false},
+		{"compressed by extension", func(o *OutputConfig) { o.Filename = "passwords.txt.gz" }, false},
+		{"compressed by name", func(o *OutputConfig) { o.Compression = CompressionZstd }, false},
+		{"compression turned off", func(o *OutputConfig) { o.Filename = "passwords.gz"; o.Compression = CompressionNone }, true},
+		{"spray", func(o *OutputConfig) { o.Format = OutputCombo }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Checkpoint.Enabled = true
+			cfg.Generator.Roster.File = "users.csv"
+			tt.output(&cfg.Output)
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestResolvePolicy(t *testing.T) {
+	resolved := PolicyConfig{Preset: PolicyPCI, MinLength: 10, MinClasses: 3, Require: []string{ClassDigit, ClassSpecial}}.Resolve()
+
+	if resolved.MinLength != 12 {
+		t.Errorf("expected the stricter preset min length 12, got %d", resolved.MinLength)
+	}
+	if resolved.MinClasses != 3 {
+		t.Errorf("expected min classes 3, got %d", resolved.MinClasses)
+	}
+	if !reflect.DeepEqual(resolved.Require, []string{ClassDigit, ClassLetter, ClassSpecial}) {
+		t.Errorf("expected the preset and extra classes, got %v", resolved.Require)
+	}
+}
+
+func TestValidateNumberPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		valid   bool
+	}{
+		{"digits", "19dd", true},
+		{"escaped d", "\\dd", true},
+		{"padded range", "[00-31]", true},
+		{"stepped range list", "[0-98/2,100]", true},
+		{"repetition", "d{1,3}", true},
+		{"named generator", "@sequential{4,6}", true},
+		{"named generator with a suffix", "@repeated!", true},
+		{"unclosed range", "[0-31", false},
+		{"descending range", "[31-0]", false},
+		{"range of letters", "[a-z]", false},
+		{"step without range", "[5/2]", false},
+		{"decreasing repetition", "d{3,1}", false},
+		{"repetition of nothing", "{2}", false},
+		{"repeated repetition", "d{2}{3}", false},
+		{"unknown generator", "@fibonacci", false},
+		{"unmatched brace", "12}", false},
+		{"unfinished escape", "12\\", false},
+		{"seven digits", "d{7}", true},
+		{"too many repetitions", "d{1,12}", false},
+		{"too wide a range", "[0-99999999999]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.NumberPatterns = []string{tt.pattern}
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestNumberPatternSize(t *testing.T) {
+	tests := []struct {
+		pattern string
+		size    int
+	}{
+		{"19dd", 100},
+		{"d{1,3}", 1110},
+		{"[01-12]d{1,2}", 12 * 110},
+		{"@repeated{2,3}", 20},
+		{"d{16}d{16}", math.MaxInt},
+	}
+
+	for _, tt := range tests {
+		parsed, err := ParseNumberPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("ParseNumberPattern(%q) returned error: %v", tt.pattern, err)
+		}
+
+		if size := parsed.Size(); size != tt.size {
+			t.Errorf("%s: expected %d numbers, got %d", tt.pattern, tt.size, size)
+		}
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	cfg.Generator.NumberPatterns = []string{"d{7}", "1d{6}", "2d{6}", "d{7}"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an error for number patterns that together pass the limit, got nil")
+	}
+}
+
+func TestMaskSize(t *testing.T) {
+	tests := []struct {
+		mask     string
+		expected uint64
+		fits     bool
+	}{
+		{"?d?d", 100, true},
+		{"?d{1,3}", 1110, true},
+		{"[ab]{1,2}?u", 6 * 26, true},
+		{"?a{9}", 630249409724609375, true},
+		{"?a{16}", 0, false},
+		{"?h{16}", 0, false},
+		{"?h{15}", 1 << 60, true},
+	}
+
+	for _, tt := range tests {
+		mask, err := ParseMask(tt.mask)
+		if err != nil {
+			t.Fatalf("ParseMask(%q) returned error: %v", tt.mask, err)
+		}
+
+		size, fits := mask.Size()
+		if fits != tt.fits || (fits && size != tt.expected) {
+			t.Errorf("%s: expected size %d (fits %v), got %d (fits %v)", tt.mask, tt.expected, tt.fits, size, fits)
+		}
+	}
+}
+
+func TestValidateInlineMasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		valid   bool
+	}{
+		{"built-in charsets", "<CUSTOM><MASK:?d?d?s>", true},
+		{"custom charset with repetition", "<CUSTOM><MASK:[!@#$]{1,2}>", true},
+		{"charset range and escape", "<MASK:[a-c\\]?d]>", true},
+		{"literal question mark", "<MASK:??>", true},
+		{"mask without mask", "<CUSTOM><MASK>", false},
+		{"unknown charset", "<MASK:?x>", false},
+		{"unclosed charset", "<MASK:[!@>", false},
+		{"empty charset", "<MASK:[]>", false},
+		{"backwards range", "<MASK:[z-a]>", false},
+		{"repetition of nothing", "<MASK:{2}>", false},
+		{"repetition past the limit", "<MASK:?d{1,17}>", false},
+		{"largest indexable mask", "<CUSTOM><MASK:?a{9}>", true},
+		{"mask past 2^64 candidates", "<CUSTOM><MASK:?a{16}>", false},
+		{"masks past 2^64 candidates together", "<MASK:?a{8}><SEP><MASK:?a{8}>", false},
+		{"repeated mask past 2^64 candidates", "<MASK:?a{8}><MASK:?a{8}>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load("")
+			if err != nil {
+				t.Fatalf("Load() returned error: %v", err)
+			}
+			cfg.Generator.Patterns = []string{tt.pattern}
+
+			err = cfg.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
@@ -13,12 +13,78 @@ func NewDefaultGeneratorConfig() GeneratorConfig {
 		Substitutions:  getDefaultSubstitutions(),
 		NumberPatterns: getDefaultNumberPatterns(),
 		Patterns:       getDefaultPatterns(),
+		Dedup:          NewDefaultDedupConfig(),
+		Ranking:        NewDefaultRankingConfig(),
+		Variations:     NewDefaultVariationProfiles(),
+		Dates:          NewDefaultDatesConfig(),
+		Keywalk:        NewDefaultKeywalkConfig(),
+		Checkpoint:     NewDefaultCheckpointConfig(),
+		Roster:         NewDefaultRosterConfig(),
+	}
+}
+
+func NewDefaultRankingConfig() RankingConfig {
+	return RankingConfig{
+		PatternWeights: map[string]float64{},
+		VariationWeights: VariationWeights{
+			Original: 1.0,
+			Case:     0.5,
+			Leet:     0.25,
+		},
+	}
+}
+
+func NewDefaultVariationProfiles() VariationProfiles {
+	all := VariationProfile{Words: true, Case: true, Leet: true}
+
+	return VariationProfiles{Custom: all, Common: all, SSID: all}
+}
+
+func NewDefaultDatesConfig() DatesConfig {
+	return DatesConfig{
+		Locales: []string{"en"},
+	}
+}
+
+func NewDefaultKeywalkConfig() KeywalkConfig {
+	return KeywalkConfig{
+		Layout:      "qwerty",
+		MinLength:   4,
+		MaxLength:   8,
+		Directions:  []string{WalkRight, WalkLeft, WalkDown, WalkUp},
+		MaxSegments: 2,
+	}
+}
+
+func NewDefaultRosterConfig() RosterConfig {
+	return RosterConfig{
+		Variations: VariationProfile{Case: true, Leet: true},
+		Strategy:   VariationStrategy{Case: []string{CaseLower, CaseTitle, CaseUpper}},
+	}
+}
+
+func NewDefaultCheckpointConfig() CheckpointConfig {
+	return CheckpointConfig{
+		Interval: 30,
+	}
+}
+
+func NewDefaultDedupConfig() DedupConfig {
+	return DedupConfig{
+		Mode:              DedupAuto,
+		MemoryLimit:       5000000,
+		FalsePositiveRate: 0.001,
 	}
 }
 
 func NewDefaultOutputConfig() OutputConfig {
 	return OutputConfig{
-		Filename: "passwords.txt",
+		Filename:    "passwords.txt",
+		Compression: CompressionAuto,
+		Format:      OutputPlain,
+		Delimiter:   ":",
+		Escape:      EscapeNone,
+		Windows:     1,
 	}
 }
 
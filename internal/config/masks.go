@@ -0,0 +1,273 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+)
+
+const maxMaskRepeat = 16
+
+// maskCharsets are the built-in hashcat charsets a mask can use after ?
+var maskCharsets = map[byte]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+	'h': "0123456789abcdef",
+	'H': "0123456789ABCDEF",
+}
+
+func init() {
+	maskCharsets['a'] = maskCharsets['l'] + maskCharsets['u'] + maskCharsets['d'] + maskCharsets['s']
+}
+
+// MaskPosition is one position of a mask, repeated between MinRepeat and
+// MaxRepeat times. Charset holds its characters; Builtin is the hashcat
+// charset it came from (d for ?d), or 0 for a literal or a [...] charset.
+type MaskPosition struct {
+	Charset   []rune
+	Builtin   byte
+	MinRepeat int
+	MaxRepeat int
+}
+
+// Mask is a parsed inline mask such as ?u?l?l?d{1,2}
+type Mask struct {
+	Positions []MaskPosition
+}
+
+// ParseMask reads a hashcat-style mask:
+//
+//	?l ?u ?d ?s ?a ?h ?H   the hashcat charsets, and ?? for a literal ?
+//	[!@#$]                 one of the listed characters; a-z adds a range,
+//	                       ?d a built-in charset and \x the character x
+//	{n}, {n,m}             repeats the previous position n to m times
+//
+// Any other character stands for itself.
+func ParseMask(text string) (Mask, error) {
+	var mask Mask
+
+	repeatable := false
+	runes := []rune(text)
+	for idx := 0; idx < len(runes); {
+		char := runes[idx]
+		if char != '{' {
+			repeatable = true
+		}
+
+		switch char {
+		case '?':
+			if idx+1 == len(runes) {
+				return Mask{}, fmt.Errorf("mask '%s' ends with an unfinished ?", text)
+			}
+			position, err := builtinPosition(runes[idx+1])
+			if err != nil {
+				return Mask{}, fmt.Errorf("mask '%s': %w", text, err)
+			}
+			mask.Positions = append(mask.Positions, position)
+			idx += 2
+		case '[':
+			charset, consumed, err := parseMaskCharset(runes[idx+1:])
+			if err != nil {
+				return Mask{}, fmt.Errorf("mask '%s': %w", text, err)
+			}
+			mask.Positions = append(mask.Positions, MaskPosition{Charset: charset, MinRepeat: 1, MaxRepeat: 1})
+			idx += consumed + 1
+		case '{':
+			if !repeatable {
+				return Mask{}, fmt.Errorf("mask '%s' repeats nothing", text)
+			}
+
+			closing := slices.Index(runes[idx:], '}')
+			if closing < 0 {
+				return Mask{}, fmt.Errorf("mask '%s' has an unclosed repetition", text)
+			}
+
+			last := &mask.Positions[len(mask.Positions)-1]
+			var err error
+			if last.MinRepeat, last.MaxRepeat, err = parseRepeat(string(runes[idx+1:idx+closing]), maxMaskRepeat); err != nil {
+				return Mask{}, fmt.Errorf("mask '%s': %w", text, err)
+			}
+			repeatable = false
+			idx += closing + 1
+		case ']', '}':
+			return Mask{}, fmt.Errorf("mask '%s' has an unmatched '%c'", text, char)
+		default:
+			mask.Positions = append(mask.Positions, MaskPosition{Charset: []rune{char}, MinRepeat: 1, MaxRepeat: 1})
+			idx++
+		}
+	}
+
+	if len(mask.Positions) == 0 {
+		return Mask{}, fmt.Errorf("mask is empty")
+	}
+
+	return mask, nil
+}
+
+// MaskText returns the mask of a <MASK:...> occurrence of the mask
+// placeholder format. The mask runs up to the closing >, so it takes no
+// labels or modifiers.
+func MaskText(text, format string) (string, bool) {
+	prefix := strings.TrimSuffix(format, ">") + ":"
+	if format == "" || !strings.HasPrefix(text, prefix) || !strings.HasSuffix(text, ">") {
+		return "", false
+	}
+
+	return text[len(prefix) : len(text)-1], true
+}
+
+// Size returns the number of candidates of the mask, and false when that
+// does not fit in a uint64
+func (m Mask) Size() (uint64, bool) {
+	size := uint64(1)
+
+	for _, position := range m.Positions {
+		// Every repeat count adds charset^repeat candidates of the position
+		var choices, power uint64 = 0, 1
+		for repeat := 0; repeat <= position.MaxRepeat; repeat++ {
+			if repeat >= position.MinRepeat {
+				var carry uint64
+				if choices, carry = bits.Add64(choices, power, 0); carry != 0 {
+					return 0, false
+				}
+			}
+
+			hi, lo := bits.Mul64(power, uint64(len(position.Charset)))
+			if hi != 0 && repeat < position.MaxRepeat {
+				return 0, false
+			}
+			power = lo
+		}
+
+		hi, lo := bits.Mul64(size, choices)
+		if hi != 0 {
+			return 0, false
+		}
+		size = lo
+	}
+
+	return size, true
+}
+
+// Lengths returns how many candidates of each length in characters the mask
+// produces, the way password lengths are measured. Counts saturate at
+// math.MaxInt instead of wrapping.
+func (m Mask) Lengths() map[int]int {
+	lengths := map[int]int{0: 1}
+
+	for _, position := range m.Positions {
+		single := map[int]int{1: len(position.Charset)}
+
+		repeated := lengths
+		next := make(map[int]int)
+		for repeat := 1; repeat <= position.MaxRepeat; repeat++ {
+			repeated = convolveLengths(repeated, single)
+			if repeat >= position.MinRepeat {
+				for length, count := range repeated {
+					next[length] = AddCount(next[length], count)
+				}
+			}
+		}
+		lengths = next
+	}
+
+	return lengths
+}
+
+func convolveLengths(a, b map[int]int) map[int]int {
+	result := make(map[int]int)
+	for lengthA, countA := range a {
+		for lengthB, countB := range b {
+			result[lengthA+lengthB] = AddCount(result[lengthA+lengthB], MulCount(countA, countB))
+		}
+	}
+
+	return result
+}
+
+// AddCount adds two non-negative candidate counts, saturating at math.MaxInt
+func AddCount(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+
+	return a + b
+}
+
+// MulCount multiplies two non-negative candidate counts, saturating at
+// math.MaxInt
+func MulCount(a, b int) int {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi != 0 || lo > math.MaxInt {
+		return math.MaxInt
+	}
+
+	return int(lo)
+}
+
+func builtinPosition(name rune) (MaskPosition, error) {
+	if name == '?' {
+		return MaskPosition{Charset: []rune{'?'}, MinRepeat: 1, MaxRepeat: 1}, nil
+	}
+
+	if name < 128 {
+		if charset, exists := maskCharsets[byte(name)]; exists {
+			return MaskPosition{Charset: []rune(charset), Builtin: byte(name), MinRepeat: 1, MaxRepeat: 1}, nil
+		}
+	}
+
+	return MaskPosition{}, fmt.Errorf("unknown charset ?%c (expected one of: ?l, ?u, ?d, ?s, ?a, ?h, ?H, ??)", name)
+}
+
+// parseMaskCharset reads a [...] charset up to its closing ], which is not
+// part of runes, and returns its characters without duplicates and the
+// number of runes it used including the ]
+func parseMaskCharset(runes []rune) ([]rune, int, error) {
+	var charset []rune
+	seen := make(map[rune]bool)
+	add := func(chars ...rune) {
+		for _, char := range chars {
+			if !seen[char] {
+				seen[char] = true
+				charset = append(charset, char)
+			}
+		}
+	}
+
+	for idx := 0; idx < len(runes); idx++ {
+		switch char := runes[idx]; {
+		case char == ']':
+			if len(charset) == 0 {
+				return nil, 0, fmt.Errorf("charset [] is empty")
+			}
+			return charset, idx + 1, nil
+		case char == '\\' && idx+1 < len(runes):
+			idx++
+			add(runes[idx])
+		case char == '?' && idx+1 < len(runes):
+			idx++
+			position, err := builtinPosition(runes[idx])
+			if err != nil {
+				return nil, 0, err
+			}
+			add(position.Charset...)
+		case idx+2 < len(runes) && runes[idx+1] == '-' && runes[idx+2] != ']':
+			last := runes[idx+2]
+			if last < char {
+				return nil, 0, fmt.Errorf("charset range %c-%c goes backwards", char, last)
+			}
+			for current := char; current <= last; current++ {
+				add(current)
+			}
+			idx += 2
+		default:
+			add(char)
+		}
+	}
+
+	return nil, 0, fmt.Errorf("charset is not closed with ]")
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ModifierUpper   = "upper"
+	ModifierLower   = "lower"
+	ModifierTitle   = "title"
+	ModifierReverse = "reverse"
+	ModifierRev     = "rev"
+	ModifierFirst   = "first"
+	ModifierLast    = "last"
+	ModifierNoLeet  = "noleet"
+	ModifierNoCase  = "nocase"
+)
+
+// Modifier transforms the value of one placeholder occurrence, such as the
+// upper in <CUSTOM|upper>. Length is the N of firstN and lastN.
+type Modifier struct {
+	Name   string
+	Length int
+}
+
+// Filter reports whether the modifier drops word variations instead of
+// transforming the value
+func (m Modifier) Filter() bool {
+	return m.Name == ModifierNoLeet || m.Name == ModifierNoCase
+}
+
+var truncateModifierRegex = regexp.MustCompile(`^(first|last)([0-9]+)$`)
+
+// ParseModifier reads a single modifier such as upper, first3 or noleet
+func ParseModifier(text string) (Modifier, error) {
+	switch text {
+	case ModifierUpper, ModifierLower, ModifierTitle, ModifierReverse, ModifierNoLeet, ModifierNoCase:
+		return Modifier{Name: text}, nil
+	case ModifierRev:
+		return Modifier{Name: ModifierReverse}, nil
+	}
+
+	if parts := truncateModifierRegex.FindStringSubmatch(text); parts != nil {
+		length, err := strconv.Atoi(parts[2])
+		if err != nil || length < 1 {
+			return Modifier{}, fmt.Errorf("modifier '%s' must keep at least one character", text)
+		}
+		return Modifier{Name: parts[1], Length: length}, nil
+	}
+
+	return Modifier{}, fmt.Errorf("unknown modifier '%s' (expected one of: %s)", text, strings.Join([]string{
+		ModifierUpper, ModifierLower, ModifierTitle, ModifierReverse, ModifierRev,
+		ModifierFirst + "N", ModifierLast + "N", ModifierNoLeet, ModifierNoCase,
+	}, ", "))
+}
+
+// PlaceholderRef is a placeholder occurrence in a pattern split into its
+// parts: <CUSTOM:1|upper> has the Format <CUSTOM>, the Marker :, the Label 1
+// and the Modifiers [upper]
+type PlaceholderRef struct {
+	Format    string
+	Marker    string
+	Label     string
+	Modifiers []string
+}
+
+var numberedPlaceholderRegex = regexp.MustCompile(`^([^:=|]+)([:=])([0-9]+)$`)
+
+// ParsePlaceholderRef splits text, which must span a whole <...> occurrence.
+// Modifiers are returned as written and checked with ParseModifier.
+func ParsePlaceholderRef(text string) (PlaceholderRef, bool) {
+	if len(text) < 3 || text[0] != '<' || text[len(text)-1] != '>' {
+		return PlaceholderRef{}, false
+	}
+
+	parts := strings.Split(text[1:len(text)-1], "|")
+	ref := PlaceholderRef{Format: "<" + parts[0] + ">", Modifiers: parts[1:]}
+
+	if numbered := numberedPlaceholderRegex.FindStringSubmatch(parts[0]); numbered != nil {
+		ref.Format = "<" + numbered[1] + ">"
+		ref.Marker = numbered[2]
+		ref.Label = numbered[3]
+	}
+
+	return ref, true
+}
+
+// ParseModifiers parses the modifiers of a placeholder reference, which
+// validation has already checked
+func (r PlaceholderRef) ParseModifiers() []Modifier {
+	var modifiers []Modifier
+	for _, text := range r.Modifiers {
+		if modifier, err := ParseModifier(text); err == nil {
+			modifiers = append(modifiers, modifier)
+		}
+	}
+
+	return modifiers
+}
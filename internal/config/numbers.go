@@ -0,0 +1,295 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	NumberSequential  = "sequential"
+	NumberRepeated    = "repeated"
+	NumberPalindromic = "palindromic"
+
+	maxNumberRepeat    = 16
+	maxGeneratedLength = 10
+
+	// MaxNumberValues caps how many numbers the number patterns expand to
+	// together, as the expansion is held in memory
+	MaxNumberValues = 10000000
+)
+
+// Default lengths of a named generator written without {n,m}
+const (
+	defaultGeneratedMin = 3
+	defaultGeneratedMax = 6
+)
+
+// NumberAtom is one piece of a number pattern. Every repetition picks one of
+// Values, and the atom is repeated between MinRepeat and MaxRepeat times.
+// Digit marks a d, which mask exports write as ?d.
+type NumberAtom struct {
+	Values    []string
+	MinRepeat int
+	MaxRepeat int
+	Digit     bool
+}
+
+// NumberPattern is a parsed number pattern such as 19dd, [01-12]d{1,2} or
+// @sequential{4}
+type NumberPattern struct {
+	Atoms []NumberAtom
+}
+
+var digitValues = []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+// ParseNumberPattern reads a number pattern:
+//
+//	d             any digit
+//	[0-31]        a number from a range, zero-padded when both bounds have
+//	              the same width ([00-31]); [0-98/2] steps by 2 and [1,5-7]
+//	              lists several numbers and ranges
+//	@sequential   a named generator: sequential (1234, 4321), repeated
+//	              (1111) or palindromic (1221), 3 to 6 digits long unless
+//	              followed by {n} or {n,m}
+//	{n}, {n,m}    repeats the previous piece n to m times
+//	\x            the character x as it is, so \d is a literal d
+//
+// Any other character stands for itself.
+func ParseNumberPattern(text string) (NumberPattern, error) {
+	var pattern NumberPattern
+
+	// repeatable is set while the last piece may still take a repetition
+	repeatable := false
+	for idx := 0; idx < len(text); {
+		if text[idx] != '{' {
+			repeatable = true
+		}
+
+		switch text[idx] {
+		case 'd':
+			pattern.Atoms = append(pattern.Atoms, NumberAtom{Values: digitValues, MinRepeat: 1, MaxRepeat: 1, Digit: true})
+			idx++
+		case '\\':
+			if idx+1 == len(text) {
+				return NumberPattern{}, fmt.Errorf("'%s' ends with an unfinished escape", text)
+			}
+			pattern.Atoms = append(pattern.Atoms, literalAtom(text[idx+1:idx+2]))
+			idx += 2
+		case '[':
+			end := strings.IndexByte(text[idx:], ']')
+			if end < 0 {
+				return NumberPattern{}, fmt.Errorf("'%s' has an unclosed range", text)
+			}
+			values, err := parseNumberRange(text[idx+1 : idx+end])
+			if err != nil {
+				return NumberPattern{}, fmt.Errorf("'%s': %w", text, err)
+			}
+			pattern.Atoms = append(pattern.Atoms, NumberAtom{Values: values, MinRepeat: 1, MaxRepeat: 1})
+			idx += end + 1
+		case '@':
+			end := idx + 1
+			for end < len(text) && text[end] >= 'a' && text[end] <= 'z' {
+				end++
+			}
+			name := text[idx+1 : end]
+
+			minLength, maxLength := defaultGeneratedMin, defaultGeneratedMax
+			if end < len(text) && text[end] == '{' {
+				closing := strings.IndexByte(text[end:], '}')
+				if closing < 0 {
+					return NumberPattern{}, fmt.Errorf("'%s' has an unclosed repetition", text)
+				}
+
+				var err error
+				if minLength, maxLength, err = parseRepeat(text[end+1:end+closing], maxGeneratedLength); err != nil {
+					return NumberPattern{}, fmt.Errorf("'%s': %w", text, err)
+				}
+				end += closing + 1
+			}
+
+			values, err := generateNumbers(name, minLength, maxLength)
+			if err != nil {
+				return NumberPattern{}, fmt.Errorf("'%s': %w", text, err)
+			}
+			pattern.Atoms = append(pattern.Atoms, NumberAtom{Values: values, MinRepeat: 1, MaxRepeat: 1})
+			repeatable = end == idx+1+len(name)
+			idx = end
+		case '{':
+			if !repeatable {
+				return NumberPattern{}, fmt.Errorf("'%s' repeats nothing", text)
+			}
+			last := &pattern.Atoms[len(pattern.Atoms)-1]
+
+			closing := strings.IndexByte(text[idx:], '}')
+			if closing < 0 {
+				return NumberPattern{}, fmt.Errorf("'%s' has an unclosed repetition", text)
+			}
+
+			var err error
+			if last.MinRepeat, last.MaxRepeat, err = parseRepeat(text[idx+1:idx+closing], maxNumberRepeat); err != nil {
+				return NumberPattern{}, fmt.Errorf("'%s': %w", text, err)
+			}
+			repeatable = false
+			idx += closing + 1
+		case ']', '}':
+			return NumberPattern{}, fmt.Errorf("'%s' has an unmatched '%c' (escape it as \\%c)", text, text[idx], text[idx])
+		default:
+			pattern.Atoms = append(pattern.Atoms, literalAtom(text[idx:idx+1]))
+			idx++
+		}
+	}
+
+	return pattern, nil
+}
+
+// Size returns how many numbers the pattern expands to, saturating at
+// math.MaxInt instead of wrapping
+func (p NumberPattern) Size() int {
+	size := 1
+
+	for _, atom := range p.Atoms {
+		// Every repeat count adds values^repeat numbers of the atom
+		choices, power := 0, 1
+		for repeat := 1; repeat <= atom.MaxRepeat; repeat++ {
+			power = MulCount(power, len(atom.Values))
+			if repeat >= atom.MinRepeat {
+				choices = AddCount(choices, power)
+			}
+		}
+		size = MulCount(size, choices)
+	}
+
+	return size
+}
+
+func literalAtom(text string) NumberAtom {
+	return NumberAtom{Values: []string{text}, MinRepeat: 1, MaxRepeat: 1}
+}
+
+// parseRepeat reads the n or n,m between the braces of a repetition
+func parseRepeat(text string, limit int) (int, int, error) {
+	first, second, isRange := strings.Cut(text, ",")
+	if !isRange {
+		second = first
+	}
+
+	minimum, errMin := strconv.Atoi(first)
+	maximum, errMax := strconv.Atoi(second)
+	if errMin != nil || errMax != nil {
+		return 0, 0, fmt.Errorf("repetition {%s} must be {n} or {n,m}", text)
+	}
+
+	if minimum < 1 || minimum > maximum || maximum > limit {
+		return 0, 0, fmt.Errorf("repetition {%s} must be between 1 and %d and increasing", text, limit)
+	}
+
+	return minimum, maximum, nil
+}
+
+// parseNumberRange reads the comma-separated numbers and ranges between
+// the brackets of a range
+func parseNumberRange(text string) ([]string, error) {
+	var values []string
+
+	for _, item := range strings.Split(text, ",") {
+		bounds, stepText, stepped := strings.Cut(item, "/")
+		low, high, isRange := strings.Cut(bounds, "-")
+		if !isRange {
+			high = low
+		}
+
+		if !isDigits(low) || !isDigits(high) {
+			return nil, fmt.Errorf("range item '%s' must be a number or two numbers joined by '-'", item)
+		}
+
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 || !isRange {
+				return nil, fmt.Errorf("range item '%s' must step a range by at least 1", item)
+			}
+		}
+
+		first, errFirst := strconv.Atoi(low)
+		last, errLast := strconv.Atoi(high)
+		if errFirst != nil || errLast != nil || first > last {
+			return nil, fmt.Errorf("range item '%s' must go from a smaller to a larger number", item)
+		}
+
+		if (last-first)/step >= MaxNumberValues-len(values) {
+			return nil, fmt.Errorf("range item '%s' has more than %d numbers", item, MaxNumberValues)
+		}
+
+		width := 0
+		if len(low) == len(high) {
+			width = len(low)
+		}
+		for number := first; number <= last; number += step {
+			values = append(values, fmt.Sprintf("%0*d", width, number))
+		}
+	}
+
+	return values, nil
+}
+
+// generateNumbers lists the values of a named generator from the shortest
+// to the longest
+func generateNumbers(name string, minLength, maxLength int) ([]string, error) {
+	var values []string
+
+	for length := minLength; length <= maxLength; length++ {
+		switch name {
+		case NumberSequential:
+			// Runs along the digit row, where 0 follows 9 as well as
+			// coming before 1, then the same runs backwards
+			const row = "01234567890"
+			var backwards []string
+			for start := 0; start+length <= len(row); start++ {
+				run := row[start : start+length]
+				values = append(values, run)
+				backwards = append(backwards, reverseDigits(run))
+			}
+			values = append(values, backwards...)
+		case NumberRepeated:
+			for _, digit := range digitValues {
+				values = append(values, strings.Repeat(digit, length))
+			}
+		case NumberPalindromic:
+			half := (length + 1) / 2
+			for number := 0; number < int(math.Pow10(half)); number++ {
+				front := fmt.Sprintf("%0*d", half, number)
+				values = append(values, front+reverseDigits(front[:length/2]))
+			}
+		default:
+			return nil, fmt.Errorf("unknown number generator '@%s' (expected one of: %s)", name,
+				strings.Join([]string{NumberSequential, NumberRepeated, NumberPalindromic}, ", "))
+		}
+	}
+
+	return values, nil
+}
+
+func reverseDigits(digits string) string {
+	reversed := []byte(digits)
+	for left, right := 0, len(reversed)-1; left < right; left, right = left+1, right-1 {
+		reversed[left], reversed[right] = reversed[right], reversed[left]
+	}
+
+	return string(reversed)
+}
+
+func isDigits(text string) bool {
+	if text == "" {
+		return false
+	}
+
+	for _, char := range text {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,11 @@
 package config
 
-import "github.com/omarelshopky/craftlist/internal/interfaces"
+import (
+	"reflect"
+	"strings"
+
+	"github.com/omarelshopky/craftlist/internal/interfaces"
+)
 
 type PlaceholdersConfig = interfaces.PlaceholdersConfig
 type Placeholder = interfaces.Placeholder
@@ -35,5 +40,147 @@ func NewDefaultPlaceholdersConfig() PlaceholdersConfig {
 			Format:      "<NUM>",
 			Description: "Inserts numbers based on the list defined in your config file",
 		},
+		Month: Placeholder{
+			Format:      "<MONTHNUM>",
+			Description: "Inserts two-digit month numbers (e.g., 03)",
+		},
+		MonthName: Placeholder{
+			Format:      "<MONTHNAME>",
+			Description: "Inserts month names in the date locales of your config file (e.g., March)",
+		},
+		MonthAbbr: Placeholder{
+			Format:      "<MONTHABBR>",
+			Description: "Inserts abbreviated month names in the date locales of your config file (e.g., Mar)",
+		},
+		Season: Placeholder{
+			Format:      "<SEASON>",
+			Description: "Inserts season names in the date locales of your config file (e.g., Summer)",
+		},
+		Quarter: Placeholder{
+			Format:      "<QUARTER>",
+			Description: "Inserts quarters (e.g., Q3)",
+		},
+		Day: Placeholder{
+			Format:      "<DAY>",
+			Description: "Inserts two-digit days of the month (e.g., 07)",
+		},
+		DayMonth: Placeholder{
+			Format:      "<DDMM>",
+			Description: "Inserts day and month of every date in the date range (e.g., 2412)",
+		},
+		MonthYear: Placeholder{
+			Format:      "<MMYY>",
+			Description: "Inserts month and two-digit year of every date in the date range (e.g., 0324)",
+		},
+		FullDate: Placeholder{
+			Format:      "<DDMMYYYY>",
+			Description: "Inserts every date in the date range (e.g., 24122024)",
+		},
+		Keywalk: Placeholder{
+			Format:      "<KEYWALK>",
+			Description: "Inserts keyboard walks on the layout defined in your config file (e.g., qwerty, 1qaz2wsx)",
+		},
+		Mask: Placeholder{
+			Format:      "<MASK>",
+			Description: "Inserts every candidate of the hashcat-style mask written after it (e.g., <MASK:?d?d?s>)",
+		},
+		User: Placeholder{
+			Format:      "<USER>",
+			Description: "Inserts the username of each user of the roster, one user at a time",
+		},
+		First: Placeholder{
+			Format:      "<FIRST>",
+			Description: "Inserts the first name of each user of the roster, one user at a time",
+		},
+		Last: Placeholder{
+			Format:      "<LAST>",
+			Description: "Inserts the last name of each user of the roster, one user at a time",
+		},
+		Initials: Placeholder{
+			Format:      "<INITIALS>",
+			Description: "Inserts the initials of each user of the roster, one user at a time (e.g., jd)",
+		},
 	}
+}
+
+// WordList is a placeholder filled from a list of words, with the variation
+// settings applied to that list. The words of an Entity list come from one
+// roster user at a time.
+type WordList struct {
+	Name     string
+	Format   string
+	Profile  VariationProfile
+	Strategy VariationStrategy
+	Entity   bool
+}
+
+// BuiltinWordLists is the number of built-in word lists (custom, common and
+// SSID) WordLists starts with; the user-defined placeholders follow them
+const BuiltinWordLists = 3
+
+// WordLists returns the built-in word lists followed by the user-defined
+// placeholders and the roster fields, in the order their words vary in the
+// candidate stream
+func WordLists(cfg GeneratorConfig, placeholders PlaceholdersConfig) []WordList {
+	lists := []WordList{
+		{Name: "custom", Format: placeholders.CustomWord.Format, Profile: cfg.Variations.Custom, Strategy: cfg.Strategies.Custom},
+		{Name: "common", Format: placeholders.CommonWord.Format, Profile: cfg.Variations.Common, Strategy: cfg.Strategies.Common},
+		{Name: "ssid", Format: placeholders.SSID.Format, Profile: cfg.Variations.SSID, Strategy: cfg.Strategies.SSID},
+	}
+
+	for _, placeholder := range cfg.Placeholders {
+		profile := VariationProfile{Words: true, Case: true, Leet: true}
+		if placeholder.Variations != nil {
+			profile = *placeholder.Variations
+		}
+
+		lists = append(lists, WordList{
+			Name:     strings.ToLower(strings.Trim(placeholder.Format, "<>")),
+			Format:   placeholder.Format,
+			Profile:  profile,
+			Strategy: placeholder.Strategy,
+		})
+	}
+
+	for _, format := range EntityFormats(placeholders) {
+		lists = append(lists, WordList{
+			Name:     strings.ToLower(strings.Trim(format, "<>")),
+			Format:   format,
+			Profile:  cfg.Roster.Variations,
+			Strategy: cfg.Roster.Strategy,
+			Entity:   true,
+		})
+	}
+
+	return lists
+}
+
+// EntityFormats returns the formats of the roster placeholders, in the
+// order of the fields of a roster user: username, first name, last name
+// and initials
+func EntityFormats(placeholders PlaceholdersConfig) []string {
+	return []string{placeholders.User.Format, placeholders.First.Format, placeholders.Last.Format, placeholders.Initials.Format}
+}
+
+// AllPlaceholders returns the built-in placeholders followed by the
+// user-defined ones
+func (c *Config) AllPlaceholders() []Placeholder {
+	var all []Placeholder
+
+	values := reflect.ValueOf(c.Placeholders)
+	for idx := 0; idx < values.NumField(); idx++ {
+		if placeholder, ok := values.Field(idx).Interface().(Placeholder); ok {
+			all = append(all, placeholder)
+		}
+	}
+
+	for _, placeholder := range c.Generator.Placeholders {
+		description := placeholder.Description
+		if description == "" {
+			description = "Inserts variations of the words defined for this placeholder in your config file"
+		}
+		all = append(all, Placeholder{Format: placeholder.Format, Description: description})
+	}
+
+	return all
 }
\ No newline at end of file
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"slices"
+	"unicode"
+)
+
+const (
+	PolicyADDefault = "ad-default"
+	PolicyPCI       = "pci"
+	PolicyNIST      = "nist"
+)
+
+const (
+	ClassLower   = "lower"
+	ClassUpper   = "upper"
+	ClassDigit   = "digit"
+	ClassSpecial = "special"
+	ClassLetter  = "letter"
+)
+
+// Character class bits of CharClasses. ClassLetter is met by either letter bit.
+const (
+	CharLower uint8 = 1 << iota
+	CharUpper
+	CharDigit
+	CharSpecial
+)
+
+// PolicyConfig describes the password policy of the target, so candidates
+// it would reject are never written. Preset starts from a known policy and
+// the other fields tighten it: MinLength raises the length floor,
+// MinClasses asks for that many of the lower, upper, digit and special
+// classes, Require names classes every candidate must contain, and
+// NoUsername rejects candidates containing one of Usernames.
+type PolicyConfig struct {
+	Preset     string   `mapstructure:"preset" json:"preset,omitempty"`
+	MinLength  int      `mapstructure:"min_length" json:"min_length,omitempty"`
+	MinClasses int      `mapstructure:"min_classes" json:"min_classes,omitempty"`
+	Require    []string `mapstructure:"require" json:"require,omitempty"`
+	NoUsername bool     `mapstructure:"no_username" json:"no_username,omitempty"`
+	Usernames  []string `mapstructure:"usernames" json:"usernames,omitempty"`
+}
+
+// policyPresets are the rules the presets stand for: the Active Directory
+// default complexity rules, PCI DSS 4.0 and NIST SP 800-63B
+var policyPresets = map[string]PolicyConfig{
+	PolicyADDefault: {MinLength: 7, MinClasses: 3, NoUsername: true},
+	PolicyPCI:       {MinLength: 12, Require: []string{ClassDigit, ClassLetter}},
+	PolicyNIST:      {MinLength: 8, NoUsername: true},
+}
+
+// PolicyPresetNames lists the policy presets
+func PolicyPresetNames() []string {
+	return []string{PolicyADDefault, PolicyPCI, PolicyNIST}
+}
+
+// Resolve merges the preset with the fields set next to it, keeping the
+// stricter value of each rule
+func (p PolicyConfig) Resolve() PolicyConfig {
+	resolved := policyPresets[p.Preset]
+	resolved.Preset = p.Preset
+	resolved.MinLength = max(resolved.MinLength, p.MinLength)
+	resolved.MinClasses = max(resolved.MinClasses, p.MinClasses)
+	resolved.NoUsername = resolved.NoUsername || p.NoUsername
+	resolved.Usernames = p.Usernames
+
+	resolved.Require = slices.Clone(resolved.Require)
+	for _, class := range p.Require {
+		if !slices.Contains(resolved.Require, class) {
+			resolved.Require = append(resolved.Require, class)
+		}
+	}
+
+	return resolved
+}
+
+// Enabled reports whether the policy has any rule
+func (p PolicyConfig) Enabled() bool {
+	return p.Preset != "" || p.MinLength > 0 || p.MinClasses > 0 || len(p.Require) > 0 || p.NoUsername
+}
+
+// CharClasses returns the class bits of the characters of text. Anything
+// that is not a cased letter or a digit counts as special.
+func CharClasses(text string) uint8 {
+	var classes uint8
+	for _, char := range text {
+		classes |= CharClass(char)
+	}
+
+	return classes
+}
+
+// CharClass returns the class bit of char
+func CharClass(char rune) uint8 {
+	switch {
+	case unicode.IsLower(char):
+		return CharLower
+	case unicode.IsUpper(char):
+		return CharUpper
+	case unicode.IsDigit(char):
+		return CharDigit
+	default:
+		return CharSpecial
+	}
+}
+
+// ClassBits returns the class bits a required class name stands for
+func ClassBits(class string) uint8 {
+	switch class {
+	case ClassLower:
+		return CharLower
+	case ClassUpper:
+		return CharUpper
+	case ClassDigit:
+		return CharDigit
+	case ClassSpecial:
+		return CharSpecial
+	case ClassLetter:
+		return CharLower | CharUpper
+	}
+
+	return 0
+}
@@ -2,11 +2,14 @@ package config
 
 import (
 	"fmt"
+	"math/bits"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/omarelshopky/craftlist/internal/ui"
+	"github.com/omarelshopky/craftlist/internal/wordlist"
 )
 
 func (c *Config) Validate() error {
@@ -28,6 +31,54 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("output filename cannot be empty")
 	}
 
+	if err := c.validateOutput(); err != nil {
+		return err
+	}
+
+	if err := c.validateDedup(); err != nil {
+		return err
+	}
+
+	if err := c.validateCheckpoint(); err != nil {
+		return err
+	}
+
+	if partition := c.Generator.Partition; partition.Total > 0 && (partition.Index < 1 || partition.Index > partition.Total) {
+		return fmt.Errorf("part %d/%d is out of range", partition.Index, partition.Total)
+	}
+
+	if err := c.validateRanking(); err != nil {
+		return err
+	}
+
+	if err := c.validateStrategies(); err != nil {
+		return err
+	}
+
+	if err := c.validateUserPlaceholders(); err != nil {
+		return err
+	}
+
+	if err := c.validateRoster(); err != nil {
+		return err
+	}
+
+	if err := c.validateDates(); err != nil {
+		return err
+	}
+
+	if err := c.validateKeywalk(); err != nil {
+		return err
+	}
+
+	if err := c.validatePolicy(); err != nil {
+		return err
+	}
+
+	if err := c.validateNumberPatterns(); err != nil {
+		return err
+	}
+
 	if err := c.validatePatterns(); err != nil {
 		return err
 	}
@@ -35,6 +86,365 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func (c *Config) validateRanking() error {
+	ranking := c.Generator.Ranking
+
+	if ranking.Top < 0 {
+		return fmt.Errorf("top cutoff cannot be negative")
+	}
+
+	if ranking.Top > 0 && c.Generator.Dedup.Mode == DedupDisk {
+		return fmt.Errorf("top cutoff cannot be combined with disk deduplication")
+	}
+
+	weights := ranking.VariationWeights
+	if weights.Original <= 0 || weights.Case <= 0 || weights.Leet <= 0 {
+		return fmt.Errorf("variation weights must be greater than 0")
+	}
+
+	configured := make(map[string]bool)
+	for _, pattern := range c.Generator.Patterns {
+		configured[pattern] = true
+	}
+
+	for pattern, weight := range ranking.PatternWeights {
+		if !configured[pattern] {
+			return fmt.Errorf("ranking weight defined for unknown pattern '%s'", pattern)
+		}
+		if weight <= 0 {
+			return fmt.Errorf("ranking weight for pattern '%s' must be greater than 0", pattern)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateStrategies() error {
+	strategies := map[string]VariationStrategy{
+		"custom": c.Generator.Strategies.Custom,
+		"common": c.Generator.Strategies.Common,
+		"ssid":   c.Generator.Strategies.SSID,
+	}
+
+	for _, list := range []string{"custom", "common", "ssid"} {
+		if err := validateStrategy(strategies[list], list+" words"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateStrategy(strategy VariationStrategy, owner string) error {
+	known := []string{CaseAll, CaseLower, CaseUpper, CaseTitle, CaseCamel, CaseToggleFirst, CaseInverted}
+
+	for _, style := range strategy.Case {
+		valid := false
+		for _, candidate := range known {
+			valid = valid || style == candidate
+		}
+		if !valid {
+			return fmt.Errorf("unknown case strategy '%s' for %s (expected one of: %s)",
+				style, owner, strings.Join(known, ", "))
+		}
+	}
+
+	if strategy.MaxSubstitutions < 0 || strategy.SubstituteLast < 0 {
+		return fmt.Errorf("substitution limits for %s cannot be negative", owner)
+	}
+
+	return nil
+}
+
+var userPlaceholderFormat = regexp.MustCompile(`^<[A-Za-z0-9_]+>$`)
+
+func (c *Config) validateUserPlaceholders() error {
+	taken := make(map[string]bool)
+
+	values := reflect.ValueOf(c.Placeholders)
+	for idx := 0; idx < values.NumField(); idx++ {
+		if placeholder, ok := values.Field(idx).Interface().(Placeholder); ok {
+			taken[placeholder.Format] = true
+		}
+	}
+
+	for _, placeholder := range c.Generator.Placeholders {
+		if !userPlaceholderFormat.MatchString(placeholder.Format) {
+			return fmt.Errorf("placeholder '%s' must look like <NAME> using letters, digits and underscores", placeholder.Format)
+		}
+
+		if taken[placeholder.Format] {
+			return fmt.Errorf("placeholder '%s' is defined more than once", placeholder.Format)
+		}
+		taken[placeholder.Format] = true
+
+		sources := 0
+		for _, set := range []bool{placeholder.File != "", len(placeholder.Words) > 0, placeholder.Builtin != ""} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("placeholder '%s' needs exactly one of file, words or builtin", placeholder.Format)
+		}
+
+		if _, exists := wordlist.Builtin(placeholder.Builtin); placeholder.Builtin != "" && !exists {
+			return fmt.Errorf("unknown builtin list '%s' for placeholder '%s' (expected one of: %s)",
+				placeholder.Builtin, placeholder.Format, strings.Join(wordlist.BuiltinNames(), ", "))
+		}
+
+		if err := validateStrategy(placeholder.Strategy, placeholder.Format); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateDates() error {
+	dates := c.Generator.Dates
+
+	if len(dates.Locales) == 0 {
+		return fmt.Errorf("at least one date locale is needed")
+	}
+
+	for _, locale := range dates.Locales {
+		if !slices.Contains(wordlist.Locales(), locale) {
+			return fmt.Errorf("unknown date locale '%s' (expected one of: %s)", locale, strings.Join(wordlist.Locales(), ", "))
+		}
+	}
+
+	start, end, err := dates.Range(c.Generator.MinYear, c.Generator.MaxYear)
+	if err != nil {
+		return err
+	}
+
+	if start.After(end) {
+		return fmt.Errorf("date range start (%s) cannot be after its end (%s)",
+			start.Format(dateLayout), end.Format(dateLayout))
+	}
+
+	return nil
+}
+
+func (c *Config) validateKeywalk() error {
+	keywalk := c.Generator.Keywalk
+
+	if _, exists := wordlist.Keyboard(keywalk.Layout); keywalk.File == "" && !exists {
+		return fmt.Errorf("unknown keyboard layout '%s' (expected one of: %s, or a layout file)",
+			keywalk.Layout, strings.Join(wordlist.KeyboardNames(), ", "))
+	}
+
+	if keywalk.MinLength < 2 {
+		return fmt.Errorf("keyboard walks must be at least 2 keys long")
+	}
+
+	if keywalk.MinLength > keywalk.MaxLength {
+		return fmt.Errorf("min keyboard walk length (%d) cannot be greater than max keyboard walk length (%d)",
+			keywalk.MinLength, keywalk.MaxLength)
+	}
+
+	if keywalk.MaxSegments < 1 {
+		return fmt.Errorf("keyboard walks need at least 1 segment")
+	}
+
+	known := []string{WalkRight, WalkLeft, WalkDown, WalkUp}
+	if len(keywalk.Directions) == 0 {
+		return fmt.Errorf("keyboard walks need at least one direction")
+	}
+	for _, direction := range keywalk.Directions {
+		if !slices.Contains(known, direction) {
+			return fmt.Errorf("unknown keyboard walk direction '%s' (expected one of: %s)", direction, strings.Join(known, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateRoster() error {
+	roster := c.Generator.Roster
+
+	if roster.Combo && roster.File == "" {
+		return fmt.Errorf("roster combo output needs a roster file")
+	}
+
+	return validateStrategy(roster.Strategy, "roster fields")
+}
+
+func (c *Config) validatePolicy() error {
+	policy := c.Generator.Policy
+
+	if _, exists := policyPresets[policy.Preset]; policy.Preset != "" && !exists {
+		return fmt.Errorf("unknown policy preset '%s' (expected one of: %s)",
+			policy.Preset, strings.Join(PolicyPresetNames(), ", "))
+	}
+
+	if policy.MinLength < 0 {
+		return fmt.Errorf("policy min length cannot be negative")
+	}
+
+	if policy.MinClasses < 0 || policy.MinClasses > 4 {
+		return fmt.Errorf("policy min classes must be between 0 and 4")
+	}
+
+	known := []string{ClassLower, ClassUpper, ClassDigit, ClassSpecial, ClassLetter}
+	for _, class := range policy.Require {
+		if !slices.Contains(known, class) {
+			return fmt.Errorf("unknown policy character class '%s' (expected one of: %s)", class, strings.Join(known, ", "))
+		}
+	}
+
+	if minLength := policy.Resolve().MinLength; minLength > c.Generator.MaxPasswordLen {
+		return fmt.Errorf("policy min length (%d) cannot be greater than max password length (%d)",
+			minLength, c.Generator.MaxPasswordLen)
+	}
+
+	return nil
+}
+
+func (c *Config) validateNumberPatterns() error {
+	total := 0
+	for _, pattern := range c.Generator.NumberPatterns {
+		parsed, err := ParseNumberPattern(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid number pattern %w", err)
+		}
+
+		if total = AddCount(total, parsed.Size()); total > MaxNumberValues {
+			return fmt.Errorf("number pattern '%s' takes the number patterns past %d numbers", pattern, MaxNumberValues)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateOutput() error {
+	output := c.Output
+
+	switch output.Compression {
+	case CompressionAuto, CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return fmt.Errorf("unknown compression '%s' (expected one of: %s)", output.Compression,
+			strings.Join([]string{CompressionAuto, CompressionNone, CompressionGzip, CompressionZstd}, ", "))
+	}
+
+	if output.SplitLines < 0 || output.SplitBytes < 0 {
+		return fmt.Errorf("split limits cannot be negative")
+	}
+
+	if output.Filename == "-" && (output.SplitLines > 0 || output.SplitBytes > 0) {
+		return fmt.Errorf("output cannot be split when streaming to stdout")
+	}
+
+	return c.validateSpray()
+}
+
+func (c *Config) validateSpray() error {
+	output := c.Output
+
+	formats := []string{OutputPlain, OutputCombo, OutputPerUser, OutputSchedule}
+	if !slices.Contains(formats, output.Format) {
+		return fmt.Errorf("unknown output format '%s' (expected one of: %s)", output.Format, strings.Join(formats, ", "))
+	}
+
+	escapes := []string{EscapeNone, EscapeBackslash, EscapeCSV}
+	if !slices.Contains(escapes, output.Escape) {
+		return fmt.Errorf("unknown escape mode '%s' (expected one of: %s)", output.Escape, strings.Join(escapes, ", "))
+	}
+
+	if output.Attempts < 0 || output.Windows < 0 || output.LockoutWindow < 0 {
+		return fmt.Errorf("attempts, windows and lockout window cannot be negative")
+	}
+
+	if !output.Spray() {
+		return nil
+	}
+
+	if c.Generator.Roster.File == "" {
+		return fmt.Errorf("output format %s needs a roster of users", output.Format)
+	}
+
+	if output.Delimiter == "" && output.Format != OutputPerUser {
+		return fmt.Errorf("output format %s needs a delimiter", output.Format)
+	}
+
+	if output.Format == OutputSchedule && output.Attempts == 0 {
+		return fmt.Errorf("output format %s needs a number of attempts per lockout window", output.Format)
+	}
+
+	if output.Format != OutputCombo && (output.Filename == "-" || output.SplitLines > 0 || output.SplitBytes > 0) {
+		return fmt.Errorf("output format %s needs a single output file", output.Format)
+	}
+
+	// Disk deduplication only writes once generation finishes, after the per-user caps and rounds are decided
+	if c.Generator.Dedup.Mode == DedupDisk {
+		return fmt.Errorf("output format %s cannot be combined with disk deduplication", output.Format)
+	}
+
+	return nil
+}
+
+func (c *Config) validateCheckpoint() error {
+	checkpoint := c.Generator.Checkpoint
+	if !checkpoint.Enabled && !checkpoint.Resume {
+		return nil
+	}
+
+	if checkpoint.Interval < 1 {
+		return fmt.Errorf("checkpoint interval must be at least 1 second")
+	}
+
+	if c.Output.Filename == "-" || c.Output.SplitLines > 0 || c.Output.SplitBytes > 0 {
+		return fmt.Errorf("checkpoints need a single output file")
+	}
+
+	// Checkpoints point into the output by byte offset, which only holds for plain text
+	if c.Output.Compressed() {
+		return fmt.Errorf("checkpoints need an uncompressed output file")
+	}
+
+	if c.Output.Spray() {
+		return fmt.Errorf("checkpoints cannot be combined with output format %s", c.Output.Format)
+	}
+
+	if c.Generator.Dedup.Mode == DedupDisk {
+		return fmt.Errorf("checkpoints cannot be combined with disk deduplication")
+	}
+
+	return nil
+}
+
+func (c *Config) validateDedup() error {
+	dedup := c.Generator.Dedup
+
+	switch dedup.Mode {
+	case DedupAuto, DedupNone, DedupMemory, DedupDisk, DedupBloom:
+	default:
+		return fmt.Errorf("unknown dedup mode '%s' (expected one of: %s)", dedup.Mode,
+			strings.Join([]string{DedupAuto, DedupNone, DedupMemory, DedupDisk, DedupBloom}, ", "))
+	}
+
+	if dedup.MemoryLimit < 1 {
+		return fmt.Errorf("dedup memory limit must be at least 1 password")
+	}
+
+	if dedup.FalsePositiveRate <= 0 || dedup.FalsePositiveRate >= 1 {
+		return fmt.Errorf("dedup false positive rate must be between 0 and 1, got %g", dedup.FalsePositiveRate)
+	}
+
+	return nil
+}
+
+// PatternError lists every pattern that failed validation, with the
+// offending placeholders highlighted
+type PatternError struct {
+	Details []string
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("%d pattern(s) contain unknown or invalid placeholders", len(e.Details))
+}
+
 func (c *Config) validatePatterns() error {
 	if len(c.Generator.Patterns) == 0 {
 		return fmt.Errorf("no patterns defined in configuration")
@@ -56,35 +466,106 @@ func (c *Config) validatePatterns() error {
 				colors.Reset)
 			validationErrors = append(validationErrors, errorMsg)
 		}
-	}
 
-	if hasErrors {
-		fmt.Printf("%sPattern validation failed:%s\n", colors.Red, colors.Reset)
-		for _, err := range validationErrors {
-			fmt.Printf("  %s\n", err)
+		if invalid, reasons := c.findInvalidModifiers(pattern, knownPlaceholders); len(invalid) > 0 {
+			hasErrors = true
+			errorMsg := fmt.Sprintf("Pattern %d: %s has invalid modifiers: %s%s%s",
+				idx+1,
+				c.highlightPattern(pattern, invalid, colors),
+				colors.Red,
+				strings.Join(reasons, ", "),
+				colors.Reset)
+			validationErrors = append(validationErrors, errorMsg)
+		}
+
+		if invalid, reasons := c.findInvalidMasks(pattern); len(invalid) > 0 {
+			hasErrors = true
+			errorMsg := fmt.Sprintf("Pattern %d: %s has invalid masks: %s%s%s",
+				idx+1,
+				c.highlightPattern(pattern, invalid, colors),
+				colors.Red,
+				strings.Join(reasons, ", "),
+				colors.Reset)
+			validationErrors = append(validationErrors, errorMsg)
 		}
 
-		return fmt.Errorf("%d pattern(s) contain unknown placeholders", len(validationErrors))
+		if undefined := c.findUndefinedReferences(pattern, knownPlaceholders); len(undefined) > 0 {
+			hasErrors = true
+			errorMsg := fmt.Sprintf("Pattern %d: %s refers to values it never numbers: %s%s%s",
+				idx+1,
+				c.highlightPattern(pattern, undefined, colors),
+				colors.Red,
+				strings.Join(undefined, ", "),
+				colors.Reset)
+			validationErrors = append(validationErrors, errorMsg)
+		}
 	}
 
-	fmt.Printf("%sAll patterns validated successfully%s\n\n", colors.Green, colors.Reset)
+	if hasErrors {
+		return &PatternError{Details: validationErrors}
+	}
 
 	return nil
 }
 
-func (c *Config) getKnownPlaceholders() map[string]bool {
-	known := make(map[string]bool)
+// findInvalidMasks lists the inline masks of a pattern that do not parse,
+// or whose candidates do not fit in a 64-bit keyspace, with the reason for
+// each. The mask placeholder on its own has no mask.
+func (c *Config) findInvalidMasks(pattern string) ([]string, []string) {
+	format := c.Placeholders.Mask.Format
+	if format == "" {
+		return nil, nil
+	}
 
-	values := reflect.ValueOf(c.Placeholders)
-	for idx := 0; idx < values.NumField(); idx++ {
-		field := values.Field(idx)
-		if field.CanInterface() {
-			if placeholder, ok := field.Interface().(Placeholder); ok {
-				known[placeholder.Format] = true
+	placeholderRegex := regexp.MustCompile(`<[^>]+>`)
+	matches := placeholderRegex.FindAllString(pattern, -1)
+
+	var invalid, reasons []string
+	seen := make(map[string]bool)
+	size, overflow := uint64(1), false
+
+	for _, match := range matches {
+		if seen[match] {
+			continue
+		}
+
+		var err error
+		if text, isMask := MaskText(match, format); isMask {
+			var mask Mask
+			if mask, err = ParseMask(text); err == nil && !overflow {
+				// Every occurrence of a mask multiplies the keyspace of the pattern
+				maskSize, fits := mask.Size()
+				for range strings.Count(pattern, match) {
+					var hi uint64
+					hi, size = bits.Mul64(size, maskSize)
+					fits = fits && hi == 0
+				}
+				if !fits {
+					overflow = true
+					err = fmt.Errorf("mask '%s' takes the pattern past 2^64 candidates", text)
+				}
 			}
+		} else if ref, ok := ParsePlaceholderRef(match); ok && ref.Format == format {
+			err = fmt.Errorf("%s needs a mask, such as %s", format, strings.TrimSuffix(format, ">")+":?d?d>")
+		}
+
+		if err != nil {
+			invalid = append(invalid, match)
+			reasons = append(reasons, err.Error())
+			seen[match] = true
 		}
 	}
 
+	return invalid, reasons
+}
+
+func (c *Config) getKnownPlaceholders() map[string]bool {
+	known := make(map[string]bool)
+
+	for _, placeholder := range c.AllPlaceholders() {
+		known[placeholder.Format] = true
+	}
+
 	return known
 }
 
@@ -96,6 +577,13 @@ func (c *Config) findUnknownPlaceholders(pattern string, knownPlaceholders map[s
 	seen := make(map[string]bool)
 
 	for _, match := range matches {
+		if _, isMask := MaskText(match, c.Placeholders.Mask.Format); isMask {
+			continue
+		}
+		if ref, ok := ParsePlaceholderRef(match); ok && knownPlaceholders[ref.Format] {
+			continue
+		}
+
 		if !knownPlaceholders[match] && !seen[match] {
 			unknown = append(unknown, match)
 			seen[match] = true
@@ -105,14 +593,94 @@ func (c *Config) findUnknownPlaceholders(pattern string, knownPlaceholders map[s
 	return unknown
 }
 
+// findInvalidModifiers lists the known placeholders of a pattern whose
+// modifiers do not parse, with the reason for each. Filtering modifiers
+// only apply to word lists, which are the only values with variations.
+func (c *Config) findInvalidModifiers(pattern string, knownPlaceholders map[string]bool) ([]string, []string) {
+	placeholderRegex := regexp.MustCompile(`<[^>]+>`)
+	matches := placeholderRegex.FindAllString(pattern, -1)
+
+	wordLists := make(map[string]bool)
+	for _, list := range WordLists(c.Generator, c.Placeholders) {
+		wordLists[list.Format] = true
+	}
+
+	var invalid, reasons []string
+	seen := make(map[string]bool)
+
+	for _, match := range matches {
+		ref, ok := ParsePlaceholderRef(match)
+		if _, isMask := MaskText(match, c.Placeholders.Mask.Format); isMask || !ok || !knownPlaceholders[ref.Format] || seen[match] {
+			continue
+		}
+
+		for _, text := range ref.Modifiers {
+			modifier, err := ParseModifier(text)
+			if err == nil && modifier.Filter() && !wordLists[ref.Format] {
+				err = fmt.Errorf("modifier '%s' only applies to word placeholders", text)
+			}
+
+			if err != nil {
+				invalid = append(invalid, match)
+				reasons = append(reasons, err.Error())
+				seen[match] = true
+				break
+			}
+		}
+	}
+
+	return invalid, reasons
+}
+
+// findUndefinedReferences lists the <X=n> back-references of a pattern that
+// have no <X:n> numbering the value they repeat. Full and short years share
+// their numbers.
+func (c *Config) findUndefinedReferences(pattern string, knownPlaceholders map[string]bool) []string {
+	placeholderRegex := regexp.MustCompile(`<[^>]+>`)
+	matches := placeholderRegex.FindAllString(pattern, -1)
+
+	owner := func(base string) string {
+		if base == c.Placeholders.ShortYear.Format {
+			return c.Placeholders.Year.Format
+		}
+		return base
+	}
+
+	matches = slices.DeleteFunc(matches, func(match string) bool {
+		_, isMask := MaskText(match, c.Placeholders.Mask.Format)
+		return isMask
+	})
+
+	defined := make(map[string]bool)
+	for _, match := range matches {
+		if ref, ok := ParsePlaceholderRef(match); ok && ref.Marker == ":" {
+			defined[owner(ref.Format)+ref.Label] = true
+		}
+	}
+
+	var undefined []string
+	seen := make(map[string]bool)
+
+	for _, match := range matches {
+		ref, ok := ParsePlaceholderRef(match)
+		if !ok || ref.Marker != "=" || !knownPlaceholders[ref.Format] || defined[owner(ref.Format)+ref.Label] {
+			continue
+		}
+
+		if !seen[match] {
+			undefined = append(undefined, match)
+			seen[match] = true
+		}
+	}
+
+	return undefined
+}
+
 func (c *Config) highlightPattern(pattern string, unknownPlaceholders []string, colors ui.Colors) string {
 	highlighted := pattern
 
 	for _, unknown := range unknownPlaceholders {
-		// Escape special regex characters in the placeholder
-		escapedUnknown := regexp.QuoteMeta(unknown)
-		re := regexp.MustCompile(escapedUnknown)
-		highlighted = re.ReplaceAllString(highlighted, colors.Red+unknown+colors.Reset)
+		highlighted = strings.ReplaceAll(highlighted, unknown, colors.Red+unknown+colors.Reset)
 	}
 
 	return highlighted
@@ -0,0 +1,120 @@
+package generator
+
+import "context"
+
+const jobBatchSize = 512
+
+// Batches record the stream position just past their last job, so the
+// writer knows how far the stream has been written once a batch is out
+type jobBatch struct {
+	seq  int
+	end  uint64
+	jobs []PasswordJob
+}
+
+type resultBatch struct {
+	seq       int
+	end       uint64
+	passwords []string
+}
+
+// jobBatcher groups jobs into numbered batches. When ordering is required it
+// also limits how many batches may be in flight, so the writer never has to
+// buffer more than a fixed window of out-of-order results.
+type jobBatcher struct {
+	ctx      context.Context
+	out      chan<- jobBatch
+	window   chan struct{}
+	jobs     []PasswordJob
+	seq      int
+	position uint64
+	sent     uint64
+}
+
+func newJobBatcher(ctx context.Context, out chan<- jobBatch, window chan struct{}, start uint64) *jobBatcher {
+	return &jobBatcher{
+		ctx:      ctx,
+		out:      out,
+		window:   window,
+		jobs:     make([]PasswordJob, 0, jobBatchSize),
+		position: start,
+		sent:     start,
+	}
+}
+
+// add queues a job and reports whether generation should continue
+func (jb *jobBatcher) add(job PasswordJob) bool {
+	jb.jobs = append(jb.jobs, job)
+	jb.position++
+	if len(jb.jobs) < jobBatchSize {
+		return jb.ctx.Err() == nil
+	}
+
+	return jb.flush()
+}
+
+// skip moves past stream positions that get no job
+func (jb *jobBatcher) skip(count uint64) {
+	jb.position += count
+}
+
+// flush sends the pending jobs as one batch and reports whether generation
+// should continue. Skipped positions are sent as a batch without jobs, so
+// the writer still learns how far the stream got.
+func (jb *jobBatcher) flush() bool {
+	if len(jb.jobs) == 0 && jb.position == jb.sent {
+		return jb.ctx.Err() == nil
+	}
+
+	if jb.window != nil {
+		select {
+		case jb.window <- struct{}{}:
+		case <-jb.ctx.Done():
+			return false
+		}
+	}
+
+	select {
+	case jb.out <- jobBatch{seq: jb.seq, end: jb.position, jobs: jb.jobs}:
+	case <-jb.ctx.Done():
+		return false
+	}
+
+	jb.seq++
+	jb.sent = jb.position
+	jb.jobs = make([]PasswordJob, 0, jobBatchSize)
+
+	return true
+}
+
+// resultReorderer releases result batches strictly in sequence order
+type resultReorderer struct {
+	pending map[int]resultBatch
+	next    int
+	window  chan struct{}
+}
+
+func newResultReorderer(window chan struct{}) *resultReorderer {
+	return &resultReorderer{
+		pending: make(map[int]resultBatch),
+		window:  window,
+	}
+}
+
+// push stores a batch and calls emit for every batch that is now in order
+func (rr *resultReorderer) push(batch resultBatch, emit func(resultBatch)) {
+	rr.pending[batch.seq] = batch
+
+	for {
+		batch, ok := rr.pending[rr.next]
+		if !ok {
+			return
+		}
+
+		delete(rr.pending, rr.next)
+		rr.next++
+		<-rr.window
+
+		emit(batch)
+	}
+}
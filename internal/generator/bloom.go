@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+const (
+	minBloomCapacity = 1024
+	maxBloomBits     = 8 << 30 // 1 GiB; larger lists get a higher false positive rate instead
+)
+
+// BloomFilter is a fixed-size probabilistic set. It never reports a member as
+// missing, but may report a missing value as present with the configured
+// false positive rate once the expected capacity is reached.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+func NewBloomFilter(capacity int, falsePositiveRate float64) *BloomFilter {
+	if capacity < minBloomCapacity {
+		capacity = minBloomCapacity
+	}
+
+	n := float64(capacity)
+	size := uint64(math.Min(maxBloomBits, math.Ceil(-n*math.Log(falsePositiveRate)/(math.Ln2*math.Ln2))))
+	hashes := uint64(math.Max(1, math.Round(float64(size)/n*math.Ln2)))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// Add inserts value and reports whether it was (probably) already present.
+func (bf *BloomFilter) Add(value string) bool {
+	h1, h2 := bf.hash(value)
+	present := true
+
+	for i := uint64(0); i < bf.hashes; i++ {
+		bit := (h1 + i*h2) % bf.size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+
+		if bf.bits[word]&mask == 0 {
+			present = false
+			bf.bits[word] |= mask
+		}
+	}
+
+	return present
+}
+
+// SizeBytes returns the memory used by the filter's bit array.
+func (bf *BloomFilter) SizeBytes() int {
+	return len(bf.bits) * 8
+}
+
+func (bf *BloomFilter) hash(value string) (uint64, uint64) {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(value))
+	sum := hasher.Sum64()
+
+	// Kirsch-Mitzenmacher double hashing; force an odd step so every probe differs
+	return sum & 0xffffffff, (sum >> 32) | 1
+}
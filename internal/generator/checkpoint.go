@@ -0,0 +1,193 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// Checkpoint records how far an ordered run got: the next stream position,
+// where it falls inside its pattern and how many output bytes cover it.
+// Resuming truncates the output to Bytes and continues from Position.
+type Checkpoint struct {
+	ConfigHash string `json:"config_hash"`
+	Segment    int    `json:"segment"`
+	Pattern    string `json:"pattern"`
+	Offset     uint64 `json:"offset"`
+	Position   uint64 `json:"position"`
+	Bytes      int64  `json:"bytes"`
+	Unique     int    `json:"unique"`
+	Duplicates int    `json:"duplicates"`
+}
+
+// CheckpointFilename returns where the checkpoint of an output file is kept
+func CheckpointFilename(filename string) string {
+	return filename + ".state"
+}
+
+func loadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no checkpoint found at %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+
+	return &checkpoint, nil
+}
+
+// save replaces the checkpoint atomically so an interruption while saving
+// never leaves a half written state file behind
+func (c *Checkpoint) save(path string) error {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false) // keep pattern placeholders readable
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// configHash fingerprints everything that decides the order and content of
+// the candidate stream, so a checkpoint is never applied to a different run
+func (g *Generator) configHash() string {
+	fingerprint := struct {
+		Patterns         []string
+		Separators       []string
+		MinYear          int
+		MaxYear          int
+		MinPasswordLen   int
+		MaxPasswordLen   int
+		Partition        config.PartitionConfig
+		Window           config.WindowConfig
+		Ranking          bool
+		PatternWeights   map[string]float64
+		VariationWeights config.VariationWeights
+		Placeholders     config.PlaceholdersConfig
+		Dates            config.DatesConfig
+		CustomWords      []string
+		CommonWords      []string
+		SSIDs            []string
+		UserWords        [][]string `json:",omitempty"`
+		Numbers          []string
+		Keywalks         []string
+		Policy           *config.PolicyConfig `json:",omitempty"`
+		Entities         []Entity             `json:",omitempty"`
+		Combo            bool                 `json:",omitempty"`
+	}{
+		Patterns:         g.config.Patterns,
+		Separators:       g.config.Separators,
+		MinYear:          g.config.MinYear,
+		MaxYear:          g.config.MaxYear,
+		MinPasswordLen:   g.config.MinPasswordLen,
+		MaxPasswordLen:   g.config.MaxPasswordLen,
+		Partition:        g.config.Partition,
+		Window:           g.config.Window,
+		Ranking:          g.config.Ranking.Enabled,
+		PatternWeights:   g.config.Ranking.PatternWeights,
+		VariationWeights: g.config.Ranking.VariationWeights,
+		Placeholders:     g.placeholders,
+		Dates:            g.config.Dates,
+		CustomWords:      g.GetCustomWords(),
+		CommonWords:      g.GetCommonWords(),
+		SSIDs:            g.GetSSIDs(),
+		UserWords:        g.userWords(),
+		Numbers:          g.numbers,
+		Keywalks:         g.keywalks,
+		Entities:         g.entities,
+		Combo:            g.combo(),
+	}
+	if g.policy != nil {
+		resolved := g.config.Policy.Resolve()
+		fingerprint.Policy = &resolved
+	}
+
+	data, _ := json.Marshal(fingerprint)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// userWords returns the words of the user-defined placeholders, leaving out
+// the roster lists, which the entities cover
+func (g *Generator) userWords() [][]string {
+	var words [][]string
+	for idx := config.BuiltinWordLists; idx < len(g.lists); idx++ {
+		if !g.lists[idx].Entity {
+			words = append(words, g.words[idx])
+		}
+	}
+
+	return words
+}
+
+// loadResumeCheckpoint reads the checkpoint of output and makes sure it was
+// written by a run with the same configuration and word lists
+func (g *Generator) loadResumeCheckpoint(output config.OutputConfig) (*Checkpoint, error) {
+	checkpoint, err := loadCheckpoint(CheckpointFilename(output.Filename))
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint.ConfigHash != g.configHash() {
+		return nil, fmt.Errorf("checkpoint %s was written with a different configuration or word lists",
+			CheckpointFilename(output.Filename))
+	}
+
+	return checkpoint, nil
+}
+
+// seedDedup feeds the passwords already in the output to the deduplicator,
+// so the resumed run does not write them again
+func seedDedup(dedup Deduplicator, filename string, size int64) error {
+	seeder, ok := dedup.(dedupSeeder)
+	if !ok {
+		return fmt.Errorf("this deduplication mode cannot resume a run")
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read existing output: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(io.LimitReader(file, size))
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read existing output: %w", err)
+		}
+
+		seeder.seed(strings.TrimSuffix(line, "\n"))
+	}
+}
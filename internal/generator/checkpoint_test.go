@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/ui"
+)
+
+func TestGenerateResume(t *testing.T) {
+	patterns := []string{"<CUSTOM><SEP><NUM>", "<COMMON><SEP><CUSTOM>"}
+
+	full := newTestGenerator(t, patterns)
+	full.config.Dedup.Mode = config.DedupMemory
+	full.config.Deterministic = true
+	expected := generateToFile(t, full)
+
+	g := newTestGenerator(t, patterns)
+	g.config.Dedup.Mode = config.DedupMemory
+	g.config.Checkpoint = config.CheckpointConfig{Enabled: true, Interval: 30, Resume: true}
+
+	// Rebuild what an interrupted run had written up to a position in the
+	// middle of the first pattern, followed by a partially written tail
+	ks := buildTestKeyspace(t, g)
+	position := ks.spaces[0].size / 2
+
+	var prefix strings.Builder
+	seen := make(map[string]bool)
+	for idx := uint64(0); idx < position; idx++ {
+		password := g.patterns.ProcessPattern(ks.spaces[0].job(idx))
+		if !seen[password] {
+			seen[password] = true
+			prefix.WriteString(password + "\n")
+		}
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "passwords.txt")
+	if err := os.WriteFile(outputFile, []byte(prefix.String()+"partial-li"), 0644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+
+	checkpoint := Checkpoint{ConfigHash: g.configHash(), Position: position, Bytes: int64(prefix.Len())}
+	if err := checkpoint.save(CheckpointFilename(outputFile)); err != nil {
+		t.Fatalf("save() returned error: %v", err)
+	}
+
+	if err := g.Generate(context.Background(), config.OutputConfig{Filename: outputFile}, ui.NewPrinter()); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	if string(data) != expected {
+		t.Errorf("resumed output differs from an uninterrupted run")
+	}
+
+	if _, err := os.Stat(CheckpointFilename(outputFile)); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoint to be removed after a finished run")
+	}
+}
+
+func TestGenerateResumeRejectsChangedConfig(t *testing.T) {
+	g := newTestGenerator(t, []string{"<CUSTOM><SEP><NUM>"})
+	g.config.Checkpoint = config.CheckpointConfig{Enabled: true, Interval: 30, Resume: true}
+
+	outputFile := filepath.Join(t.TempDir(), "passwords.txt")
+	if err := os.WriteFile(outputFile, nil, 0644); err != nil {
+		t.Fatalf("failed to write output: %v", err)
+	}
+
+	checkpoint := Checkpoint{ConfigHash: "stale"}
+	if err := checkpoint.save(CheckpointFilename(outputFile)); err != nil {
+		t.Fatalf("save() returned error: %v", err)
+	}
+
+	err := g.Generate(context.Background(), config.OutputConfig{Filename: outputFile}, ui.NewPrinter())
+	if err == nil || !strings.Contains(err.Error(), "different configuration") {
+		t.Errorf("expected a configuration mismatch error, got %v", err)
+	}
+}
@@ -1,8 +1,8 @@
 package generator
 
 import (
-	"strings"
 	"sort"
+	"unicode/utf8"
 
 	"github.com/omarelshopky/craftlist/internal/config"
 )
@@ -10,40 +10,64 @@ import (
 type Counter struct {
 	config       config.GeneratorConfig
 	placeholders config.PlaceholdersConfig
+	lists        []config.WordList
+	userWords    map[string][]string
+	kinds        map[string][]VariationKind
+	dates        [][]string
+	keywalks     []string
+	policy       *passwordPolicy
+	entities     []Entity
 }
 
 func NewCounter(cfg config.GeneratorConfig, placeholders config.PlaceholdersConfig) *Counter {
 	return &Counter{
 		config:       cfg,
 		placeholders: placeholders,
+		lists:        config.WordLists(cfg, placeholders),
+		userWords:    make(map[string][]string),
+		kinds:        make(map[string][]VariationKind),
+		dates:        dateValues(cfg),
+		keywalks:     KeywalkValues(cfg),
+		policy:       newPasswordPolicy(cfg.Policy),
 	}
 }
 
+// SetWords sets the words of a user-defined placeholder
+func (c *Counter) SetWords(format string, words []string) {
+	c.userWords[format] = words
+}
+
+// SetKeywalks sets the keyboard walks, when they come from a layout file
+func (c *Counter) SetKeywalks(walks []string) {
+	c.keywalks = walks
+}
+
+// SetKinds sets the variation kind of every word of the word list filling
+// placeholder format, which the noleet and nocase modifiers filter on
+func (c *Counter) SetKinds(format string, kinds []VariationKind) {
+	c.kinds[format] = kinds
+}
+
+// SetEntities sets the prepared roster fields of every user, which the
+// patterns generated per user are counted with
+func (c *Counter) SetEntities(entities []Entity) {
+	c.entities = entities
+}
+
 type DistributionInfo struct {
 	MinLength     int
 	MaxLength     int
 	LengthDist map[int]int // length -> count
 	TotalCount int
+	classDist  map[classProfile]int // only with a password policy
 }
 
-type PatternComponent struct {
-	Type   ComponentType // Type of component
-	Length int           // For base components, this is the fixed length
+// classProfile is the length and the character class bits of a value
+type classProfile struct {
+	length  int
+	classes uint8
 }
 
-type ComponentType string
-
-const (
-	ComponentBase      ComponentType = "base"
-	ComponentCustom    ComponentType = "custom"
-	ComponentCommon    ComponentType = "common"
-	ComponentSSID      ComponentType = "ssid"
-	ComponentNumber    ComponentType = "number"
-	ComponentYear      ComponentType = "year"
-	ComponentShortYear ComponentType = "shortyear"
-	ComponentSeparator ComponentType = "separator"
-)
-
 const (
 	defaultMaxLength = 1000000 // Default max length when no upper limit is specified
 	lengthBuffer     = 50      // Buffer for optimization in combination counting
@@ -51,46 +75,128 @@ const (
 	shortYearLength  = 2       // Short year length
 )
 
-// CountPasswords calculates the total number of possible passwords and per-pattern statistics
+// CountPasswords calculates the total number of possible passwords and per-pattern statistics.
+// Counts saturate at math.MaxInt instead of wrapping.
 func (c *Counter) CountPasswords(customWords, commonWords, ssids, numbers []string) (int, map[string]int) {
 	stats := make(map[string]int)
 	total := 0
 
+	var entityCounters []*Counter
+	var entityStats []*wordListStats
+
 	// Pre-compute statistics for all word lists
 	wordListStats := c.buildWordListStats(customWords, commonWords, ssids, numbers)
-	
+
 	// Calculate statistics for each pattern
 	for _, pattern := range c.config.Patterns {
-		count := c.calculatePatternCount(pattern, wordListStats)
-		total += count
-		stats[pattern] = count
+		if !c.perEntity(compilePattern(pattern, c.lists, c.placeholders)) {
+			count := c.calculatePatternCount(pattern, wordListStats)
+			total = config.AddCount(total, count)
+			stats[pattern] = count
+			continue
+		}
+
+		// Patterns generated per roster user sum the count of every user
+		if entityCounters == nil {
+			entityCounters, entityStats = c.entityStats(wordListStats)
+		}
+		stats[pattern] = 0
+		for idx, counter := range entityCounters {
+			count := counter.calculatePatternCount(pattern, entityStats[idx])
+			total = config.AddCount(total, count)
+			stats[pattern] = config.AddCount(stats[pattern], count)
+		}
 	}
 
 	return total, stats
 }
 
+// perEntity reports whether the pattern is generated once per roster user,
+// like Generator.perEntity
+func (c *Counter) perEntity(compiled compiledPattern) bool {
+	return len(c.entities) > 0 && (c.config.Roster.Combo || compiled.usesEntity(c.lists))
+}
+
+// entityStats returns a Counter and word list statistics per roster user,
+// with the roster lists and the policy bound to that user. Distributions
+// that do not come from a word list are shared.
+func (c *Counter) entityStats(base *wordListStats) ([]*Counter, []*wordListStats) {
+	counters := make([]*Counter, len(c.entities))
+	stats := make([]*wordListStats, len(c.entities))
+
+	for idx, entity := range c.entities {
+		bound := *c
+		bound.policy = c.policy.withNames(entity.Names)
+		bound.kinds = make(map[string][]VariationKind, len(c.kinds))
+		for format, kinds := range c.kinds {
+			bound.kinds[format] = kinds
+		}
+
+		entityStats := *base
+		entityStats.words = append([][]string{}, base.words...)
+		for listIdx, list := range c.lists {
+			if list.Entity {
+				entityStats.words[listIdx] = entity.Words[list.Format]
+				bound.kinds[list.Format] = entity.Kinds[list.Format]
+			}
+		}
+
+		entityStats.lists = nil
+		for _, words := range entityStats.words {
+			entityStats.lists = append(entityStats.lists, bound.buildDistributionInfo(words))
+		}
+
+		counters[idx], stats[idx] = &bound, &entityStats
+	}
+
+	return counters, stats
+}
+
+// CountCandidates returns the per-pattern counts CountPasswords would give
+// without the password policy, which its pass rates are measured against
+func (c *Counter) CountCandidates(customWords, commonWords, ssids, numbers []string) map[string]int {
+	unfiltered := *c
+	unfiltered.policy = nil
+
+	_, stats := unfiltered.CountPasswords(customWords, commonWords, ssids, numbers)
+
+	return stats
+}
+
+// wordListStats holds the distribution of every list of values, and the
+// values themselves for the placeholders whose modifiers change them
 type wordListStats struct {
-	custom    *DistributionInfo
-	common    *DistributionInfo
-	ssid      *DistributionInfo
+	lists     []*DistributionInfo // in config.WordLists order
 	number    *DistributionInfo
-	year      *DistributionInfo
-	shortYear *DistributionInfo
 	separator *DistributionInfo
+	dates     []*DistributionInfo // in dateFormats order
+	keywalk   *DistributionInfo
+	yearCount int
+	words     [][]string
+	numbers   []string
 }
 
 func (c *Counter) buildWordListStats(customWords, commonWords, ssids, numbers []string) *wordListStats {
-	yearCount := c.config.MaxYear - c.config.MinYear + 1
-	
-	return &wordListStats{
-		custom:    c.buildDistributionInfo(customWords),
-		common:    c.buildDistributionInfo(commonWords),
-		ssid:      c.buildDistributionInfo(ssids),
+	stats := &wordListStats{
 		number:    c.buildDistributionInfo(numbers),
 		separator: c.buildDistributionInfo(c.config.Separators),
-		year:      c.createFixedDistributionInfo(yearLength, yearCount),
-		shortYear: c.createFixedDistributionInfo(shortYearLength, yearCount),
+		keywalk:   c.buildDistributionInfo(c.keywalks),
+		yearCount: c.config.MaxYear - c.config.MinYear + 1,
+		words:     [][]string{customWords, commonWords, ssids},
+		numbers:   numbers,
+	}
+
+	for _, list := range c.lists[config.BuiltinWordLists:] {
+		stats.words = append(stats.words, c.userWords[list.Format])
+	}
+	for _, words := range stats.words {
+		stats.lists = append(stats.lists, c.buildDistributionInfo(words))
 	}
+	for _, values := range c.dates {
+		stats.dates = append(stats.dates, c.buildDistributionInfo(values))
+	}
+
+	return stats
 }
 
 func (c *Counter) buildDistributionInfo(words []string) *DistributionInfo {
@@ -99,15 +205,22 @@ func (c *Counter) buildDistributionInfo(words []string) *DistributionInfo {
 	}
 	
 	stats := &DistributionInfo{
-		MinLength:    len(words[0]),
-		MaxLength:    len(words[0]),
+		MinLength:    utf8.RuneCountInString(words[0]),
+		MaxLength:    utf8.RuneCountInString(words[0]),
 		LengthDist: make(map[int]int),
 		TotalCount:   len(words),
 	}
 	
+	if c.policy != nil {
+		stats.classDist = make(map[classProfile]int)
+	}
+
 	for _, word := range words {
-		length := len(word)
+		length := utf8.RuneCountInString(word)
 		stats.LengthDist[length]++
+		if c.policy != nil {
+			stats.classDist[classProfile{length, c.policy.valueClasses(word)}]++
+		}
 		
 		if length < stats.MinLength {
 			stats.MinLength = length
@@ -120,134 +233,221 @@ func (c *Counter) buildDistributionInfo(words []string) *DistributionInfo {
 	return stats
 }
 
-func (c *Counter) createFixedDistributionInfo(length, count int) *DistributionInfo {
-	return &DistributionInfo{
+func (c *Counter) createFixedDistributionInfo(length, count int, classes uint8) *DistributionInfo {
+	info := &DistributionInfo{
 		MinLength:    length,
 		MaxLength:    length,
 		LengthDist: map[int]int{length: count},
 		TotalCount:   count,
 	}
+	if c.policy != nil {
+		info.classDist = map[classProfile]int{{length, classes}: count}
+	}
+
+	return info
 }
 
 // calculatePatternCount calculates the number of valid passwords for a given pattern
 func (c *Counter) calculatePatternCount(pattern string, wordStats *wordListStats) int {
-	components := c.parsePattern(pattern)
-	componentInfos := c.buildComponentInfos(components, wordStats)
-	
+	compiled := compilePattern(pattern, c.lists, c.placeholders)
+	componentInfos := c.buildComponentInfos(compiled, wordStats)
+
+	if c.policy != nil {
+		// Patterns that can never pass the policy are skipped like in the generator
+		if len(c.policy.unmetRules(distributionRanges(componentInfos))) > 0 {
+			return 0
+		}
+
+		return c.countPolicyCombinations(componentInfos, max(c.config.MinPasswordLen, c.policy.minLength), c.config.MaxPasswordLen)
+	}
+
 	return c.countValidCombinations(componentInfos, c.config.MinPasswordLen, c.config.MaxPasswordLen)
 }
 
-// buildComponentInfos converts pattern components to DistributionInfo structs
-func (c *Counter) buildComponentInfos(components []PatternComponent, wordStats *wordListStats) []*DistributionInfo {
+// buildComponentInfos converts the literal text and every independent value
+// of a pattern to DistributionInfo structs. A value repeated k times adds k
+// times its length, and a year adds four or two digits per occurrence.
+func (c *Counter) buildComponentInfos(compiled compiledPattern, wordStats *wordListStats) []*DistributionInfo {
 	var componentInfos []*DistributionInfo
-	
-	for _, comp := range components {
+
+	if compiled.literalLength > 0 {
+		var classes uint8
+		if c.policy != nil {
+			classes = c.policy.valueClasses(compiled.literal)
+		}
+		componentInfos = append(componentInfos, c.createFixedDistributionInfo(compiled.literalLength, 1, classes))
+	}
+
+	for idx, dim := range compiled.dims {
+		if compiled.modified(idx) {
+			componentInfos = append(componentInfos, c.modifiedDistributionInfo(compiled, idx, wordStats))
+			continue
+		}
+
 		var compInfo *DistributionInfo
-		
-		switch comp.Type {
-		case ComponentBase:
-			compInfo = c.createFixedDistributionInfo(comp.Length, 1)
-		case ComponentCustom:
-			compInfo = wordStats.custom
-		case ComponentCommon:
-			compInfo = wordStats.common
-		case ComponentSSID:
-			compInfo = wordStats.ssid
-		case ComponentNumber:
+
+		switch dim.kind {
+		case slotWord:
+			compInfo = wordStats.lists[dim.list]
+		case slotDate:
+			compInfo = wordStats.dates[dim.list]
+		case slotKeywalk:
+			compInfo = wordStats.keywalk
+		case slotNumber:
 			compInfo = wordStats.number
-		case ComponentYear:
-			compInfo = wordStats.year
-		case ComponentShortYear:
-			compInfo = wordStats.shortYear
-		case ComponentSeparator:
+		case slotSeparator:
 			compInfo = wordStats.separator
-		default:
+		case slotMask:
+			compInfo = c.maskDistributionInfo(dim.mask)
+		case slotYear:
+			length := 0
+			for _, slot := range dim.slots {
+				if compiled.slots[slot].kind == slotShortYear {
+					length += shortYearLength
+				} else {
+					length += yearLength
+				}
+			}
+			componentInfos = append(componentInfos, c.createFixedDistributionInfo(length, wordStats.yearCount, config.CharDigit))
 			continue
 		}
-		
-		if compInfo != nil {
-			componentInfos = append(componentInfos, compInfo)
-		}
+
+		componentInfos = append(componentInfos, c.repeatDistributionInfo(compInfo, len(dim.slots)))
 	}
-	
+
 	return componentInfos
 }
 
-func (c *Counter) parsePattern(pattern string) []PatternComponent {
-	var components []PatternComponent
-	remaining := pattern
-	
-	placeholderMap := c.buildPlaceholderMap()
-	
-	for len(remaining) > 0 {
-		if comp, consumed := c.tryMatchPlaceholder(remaining, placeholderMap); consumed > 0 {
-			components = append(components, comp)
-			remaining = remaining[consumed:]
-		} else if baseLength := c.countBaseCharacters(remaining, placeholderMap); baseLength > 0 {
-			components = append(components, PatternComponent{
-				Type:   ComponentBase,
-				Length: baseLength,
-			})
-			remaining = remaining[baseLength:]
-		} else {
-			// Skip unrecognized character
-			remaining = remaining[1:]
+// maskDistributionInfo is the distribution of an inline mask, computed from
+// its charsets without listing its candidates
+func (c *Counter) maskDistributionInfo(mask config.Mask) *DistributionInfo {
+	info := &DistributionInfo{LengthDist: mask.Lengths()}
+
+	first := true
+	for length, count := range info.LengthDist {
+		info.TotalCount = config.AddCount(info.TotalCount, count)
+		if first || length < info.MinLength {
+			info.MinLength = length
 		}
+		if first || length > info.MaxLength {
+			info.MaxLength = length
+		}
+		first = false
 	}
-	
-	return components
+
+	if c.policy != nil {
+		info.classDist = maskClassDistribution(mask)
+	}
+
+	return info
 }
 
-func (c *Counter) buildPlaceholderMap() map[ComponentType]string {
-	return map[ComponentType]string{
-		ComponentCustom:    c.placeholders.CustomWord.Format,
-		ComponentCommon:    c.placeholders.CommonWord.Format,
-		ComponentSSID:      c.placeholders.SSID.Format,
-		ComponentNumber:    c.placeholders.Number.Format,
-		ComponentYear:      c.placeholders.Year.Format,
-		ComponentShortYear: c.placeholders.ShortYear.Format,
-		ComponentSeparator: c.placeholders.Separator.Format,
+// maskClassDistribution counts the candidates of a mask by length and
+// character classes, position by position like config.Mask.Lengths
+func maskClassDistribution(mask config.Mask) map[classProfile]int {
+	profiles := map[classProfile]int{{}: 1}
+
+	for _, position := range mask.Positions {
+		single := make(map[classProfile]int)
+		for _, char := range position.Charset {
+			single[classProfile{1, config.CharClass(char)}]++
+		}
+
+		repeated := profiles
+		next := make(map[classProfile]int)
+		for repeat := 1; repeat <= position.MaxRepeat; repeat++ {
+			repeated = combineClassDistributions(repeated, single, -1)
+			if repeat >= position.MinRepeat {
+				for profile, count := range repeated {
+					next[profile] = config.AddCount(next[profile], count)
+				}
+			}
+		}
+		profiles = next
 	}
+
+	return profiles
 }
 
-func (c *Counter) tryMatchPlaceholder(text string, placeholderMap map[ComponentType]string) (PatternComponent, int) {
-	// Create deterministic order by sorting component types
-	var types []ComponentType
-	for compType := range placeholderMap {
-		types = append(types, compType)
+// modifiedDistributionInfo is the distribution of a value whose slots carry
+// modifiers, measured on the text every slot actually writes
+func (c *Counter) modifiedDistributionInfo(compiled compiledPattern, dim int, wordStats *wordListStats) *DistributionInfo {
+	var values []string
+
+	switch kind := compiled.dims[dim].kind; kind {
+	case slotWord:
+		list := compiled.dims[dim].list
+		values, _ = compiled.filterWords(dim, wordStats.words[list], c.kinds[c.lists[list].Format])
+	case slotDate:
+		values = c.dates[compiled.dims[dim].list]
+	case slotKeywalk:
+		values = c.keywalks
+	case slotNumber:
+		values = wordStats.numbers
+	case slotSeparator:
+		values = c.config.Separators
+	default:
+		values = yearRange(c.config.MinYear, c.config.MaxYear)
 	}
-	sort.Slice(types, func(i, j int) bool {
-		return string(types[i]) < string(types[j])
-	})
-	
-	// Try matching in deterministic order
-	for _, compType := range types {
-		placeholder := placeholderMap[compType]
-		if strings.HasPrefix(text, placeholder) {
-			return PatternComponent{Type: compType, Length: 0}, len(placeholder)
+
+	slots := compiled.dims[dim].slots
+	values, prepared := compiled.dimValues(dim, values)
+	if prepared {
+		return c.repeatDistributionInfo(c.buildDistributionInfo(values), len(slots))
+	}
+
+	info := &DistributionInfo{LengthDist: make(map[int]int), TotalCount: len(values)}
+	if c.policy != nil {
+		info.classDist = make(map[classProfile]int)
+	}
+
+	for idx, value := range values {
+		length := 0
+		var classes uint8
+		for _, slot := range slots {
+			written := compiled.slots[slot].write(value)
+			length += utf8.RuneCountInString(written)
+			if c.policy != nil {
+				classes |= c.policy.valueClasses(written)
+			}
+		}
+
+		info.LengthDist[length]++
+		if c.policy != nil {
+			info.classDist[classProfile{length, classes}]++
+		}
+		if idx == 0 || length < info.MinLength {
+			info.MinLength = length
 		}
+		info.MaxLength = max(info.MaxLength, length)
 	}
 
-	return PatternComponent{}, 0
+	return info
 }
 
-func (c *Counter) countBaseCharacters(text string, placeholderMap map[ComponentType]string) int {
-	// Create sorted list of placeholders for deterministic behavior
-	var placeholders []string
-	for _, placeholder := range placeholderMap {
-		placeholders = append(placeholders, placeholder)
+// repeatDistributionInfo is the distribution of a value written times times
+func (c *Counter) repeatDistributionInfo(info *DistributionInfo, times int) *DistributionInfo {
+	if times == 1 {
+		return info
 	}
-	sort.Strings(placeholders)
-	
-	for i := range text {
-		for _, placeholder := range placeholders {
-			if strings.HasPrefix(text[i:], placeholder) {
-				return i
-			}
+
+	repeated := &DistributionInfo{
+		MinLength:  info.MinLength * times,
+		MaxLength:  info.MaxLength * times,
+		LengthDist: make(map[int]int, len(info.LengthDist)),
+		TotalCount: info.TotalCount,
+	}
+	for length, count := range info.LengthDist {
+		repeated.LengthDist[length*times] = count
+	}
+	if info.classDist != nil {
+		repeated.classDist = make(map[classProfile]int, len(info.classDist))
+		for profile, count := range info.classDist {
+			repeated.classDist[classProfile{profile.length * times, profile.classes}] = count
 		}
 	}
 
-	return len(text)
+	return repeated
 }
 
 func (c *Counter) combineDistributions(dist1 map[int]int, dist2 map[int]int, maxLen int) map[int]int {
@@ -271,7 +471,7 @@ func (c *Counter) combineDistributions(dist1 map[int]int, dist2 map[int]int, max
 			count2 := dist2[len2]
 			totalLen := len1 + len2
 			if totalLen <= maxLen+lengthBuffer {
-				nextDist[totalLen] += count1 * count2
+				nextDist[totalLen] = config.AddCount(nextDist[totalLen], config.MulCount(count1, count2))
 			}
 		}
 	}
@@ -310,9 +510,69 @@ func (c *Counter) countValidLengths(distribution map[int]int, minLen, maxLen int
 	
 	for length, count := range distribution {
 		if length >= minLen && length <= maxLen {
-			validCount += count
+			validCount = config.AddCount(validCount, count)
 		}
 	}
 
 	return validCount
-}
\ No newline at end of file
+}
+// countPolicyCombinations counts the combinations with lengths in the
+// specified range that the password policy accepts. It convolves the
+// length and class distributions of the components, so the classes of a
+// candidate are exact and a username is only noticed inside one value.
+func (c *Counter) countPolicyCombinations(components []*DistributionInfo, minLen, maxLen int) int {
+	if len(components) == 0 {
+		return 0
+	}
+
+	if maxLen == 0 {
+		maxLen = defaultMaxLength
+	}
+
+	current := components[0].classDist
+	for i := 1; i < len(components); i++ {
+		current = combineClassDistributions(current, components[i].classDist, maxLen)
+	}
+
+	validCount := 0
+	for profile, count := range current {
+		if profile.length >= minLen && profile.length <= maxLen && c.policy.allowsClasses(profile.classes) {
+			validCount = config.AddCount(validCount, count)
+		}
+	}
+
+	return validCount
+}
+
+// distributionRanges sums up the class distribution of every component
+func distributionRanges(components []*DistributionInfo) []classRange {
+	ranges := make([]classRange, len(components))
+	for idx, info := range components {
+		ranges[idx].username = len(info.classDist) > 0
+		for profile := range info.classDist {
+			ranges[idx].classes |= profile.classes
+			ranges[idx].username = ranges[idx].username && profile.classes&usernameClass != 0
+		}
+	}
+
+	return ranges
+}
+
+// combineClassDistributions convolves two class distributions, dropping
+// lengths above maxLen unless it is negative
+func combineClassDistributions(dist1, dist2 map[classProfile]int, maxLen int) map[classProfile]int {
+	next := make(map[classProfile]int)
+
+	for profile1, count1 := range dist1 {
+		for profile2, count2 := range dist2 {
+			length := profile1.length + profile2.length
+			if maxLen >= 0 && length > maxLen {
+				continue
+			}
+			profile := classProfile{length, profile1.classes | profile2.classes}
+			next[profile] = config.AddCount(next[profile], config.MulCount(count1, count2))
+		}
+	}
+
+	return next
+}
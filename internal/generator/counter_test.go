@@ -91,3 +91,58 @@ func TestCountPasswords(t *testing.T) {
 		})
 	}
 }
+
+
+func TestCountPasswordsHonoursVariationProfiles(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinPasswordLen = 1
+	cfg.CommonWords = []string{"pass"}
+	cfg.Substitutions = map[string][]string{"a": {"4"}, "s": {"5"}}
+	cfg.Patterns = []string{"<CUSTOM>", "<COMMON>", "<SSID>"}
+	cfg.Variations = config.VariationProfiles{
+		Custom: config.VariationProfile{Words: true},
+		Common: config.VariationProfile{Leet: true},
+		SSID:   config.VariationProfile{Case: true},
+	}
+	placeholders := config.NewDefaultPlaceholdersConfig()
+
+	gen := New(cfg, placeholders)
+	gen.SetCustomWords([]string{"evil corp"})
+	gen.SetSSIDs([]string{"ab"})
+	if err := gen.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	_, stats := NewCounter(cfg, placeholders).CountPasswords(gen.GetCustomWords(), gen.GetCommonWords(), gen.GetSSIDs(), gen.GetNumbers())
+
+	// evil corp keeps only its 6 segmentations, pass only its 2*2*2
+	// substitutions and ab only its 4 case combinations
+	expected := map[string]int{"<CUSTOM>": 6, "<COMMON>": 8, "<SSID>": 4}
+	for pattern, count := range expected {
+		if stats[pattern] != count {
+			t.Errorf("expected %d passwords for %s, got %d", count, pattern, stats[pattern])
+		}
+	}
+}
+
+func TestCountPasswordsHonoursNumberPatterns(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinPasswordLen = 3
+	cfg.MaxPasswordLen = 4
+	cfg.NumberPatterns = []string{"d{1,4}", "[00-31]", "@repeated{2,5}", "\\d"}
+	cfg.Patterns = []string{"<NUM>"}
+	placeholders := config.NewDefaultPlaceholdersConfig()
+
+	gen := New(cfg, placeholders)
+	if err := gen.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	total, _ := NewCounter(cfg, placeholders).CountPasswords(gen.GetCustomWords(), gen.GetCommonWords(), gen.GetSSIDs(), gen.GetNumbers())
+
+	// 1000 + 10000 numbers from d{1,4} and 10 + 10 repeated digits of 3 and
+	// 4 characters; the two-digit days and the literal d are too short
+	if expected := 11020; total != expected {
+		t.Errorf("expected %d passwords, got %d", expected, total)
+	}
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/wordlist"
+)
+
+// Indexes of the date placeholders in dateFormats and dateValues
+const (
+	dateMonth = iota
+	dateMonthName
+	dateMonthAbbr
+	dateSeason
+	dateQuarter
+	dateDay
+	dateDayMonth
+	dateMonthYear
+	dateFullDate
+)
+
+// dateFormats returns the date placeholders in the order of dateValues
+func dateFormats(placeholders config.PlaceholdersConfig) []string {
+	return []string{
+		placeholders.Month.Format, placeholders.MonthName.Format, placeholders.MonthAbbr.Format,
+		placeholders.Season.Format, placeholders.Quarter.Format, placeholders.Day.Format,
+		placeholders.DayMonth.Format, placeholders.MonthYear.Format, placeholders.FullDate.Format,
+	}
+}
+
+// dateValues lists the values of every date placeholder. Names follow the
+// configured locales; DDMM, MMYY and DDMMYYYY cover the dates of the range,
+// DDMM in calendar order and the others in date order.
+func dateValues(cfg config.GeneratorConfig) [][]string {
+	values := make([][]string, dateFullDate+1)
+	values[dateMonth] = paddedRange(1, 12)
+	values[dateMonthName] = wordlist.MonthNames(cfg.Dates.Locales)
+	values[dateMonthAbbr] = wordlist.MonthAbbreviations(cfg.Dates.Locales)
+	values[dateSeason] = wordlist.Seasons(cfg.Dates.Locales)
+	values[dateQuarter] = []string{"Q1", "Q2", "Q3", "Q4"}
+	values[dateDay] = paddedRange(1, 31)
+
+	// An invalid range is reported by validation
+	start, end, err := cfg.Dates.Range(cfg.MinYear, cfg.MaxYear)
+	if err != nil {
+		return values
+	}
+
+	dayMonths := make(map[string]bool)
+	monthYears := make(map[string]bool)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		values[dateFullDate] = append(values[dateFullDate], day.Format("02012006"))
+		dayMonths[day.Format("0201")] = true
+
+		if monthYear := day.Format("0106"); !monthYears[monthYear] {
+			monthYears[monthYear] = true
+			values[dateMonthYear] = append(values[dateMonthYear], monthYear)
+		}
+	}
+
+	// 2000 is a leap year, so its days cover every day and month
+	leapYear := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for day := leapYear; day.Year() == 2000; day = day.AddDate(0, 0, 1) {
+		if dayMonth := day.Format("0201"); dayMonths[dayMonth] {
+			values[dateDayMonth] = append(values[dateDayMonth], dayMonth)
+		}
+	}
+
+	return values
+}
+
+// DateValues returns the values of every date placeholder keyed by format
+func DateValues(cfg config.GeneratorConfig, placeholders config.PlaceholdersConfig) map[string][]string {
+	values := dateValues(cfg)
+
+	dates := make(map[string][]string, len(values))
+	for idx, format := range dateFormats(placeholders) {
+		dates[format] = values[idx]
+	}
+
+	return dates
+}
+
+// paddedRange lists the numbers from first to last as two digits
+func paddedRange(first, last int) []string {
+	numbers := make([]string, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		numbers = append(numbers, fmt.Sprintf("%02d", number))
+	}
+
+	return numbers
+}
@@ -0,0 +1,500 @@
+package generator
+
+import (
+	"bufio"
+	"cmp"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// dedupMergeFanIn caps how many run files a disk dedup merge reads at once,
+// so the open files stay bounded however many runs were spilled
+const dedupMergeFanIn = 64
+
+// Deduplicator drops passwords that were already written to the output.
+// Passwords reach the output in the order they are written, minus duplicates.
+type Deduplicator interface {
+	WritePassword(password string) error
+	// Close flushes any pending passwords to the output. It does not close the output.
+	Close() error
+	Unique() int
+	Duplicates() int
+}
+
+type passwordWriter interface {
+	WritePassword(password string) error
+}
+
+// dedupSeeder is implemented by deduplicators that can be told about
+// passwords written by an earlier run without writing them again
+type dedupSeeder interface {
+	seed(password string)
+}
+
+// NewDeduplicator builds the deduplicator selected by cfg.Mode. expected is
+// the estimated number of candidates and is used to size bounded structures.
+// Auto mode keeps passwords in memory while expected fits cfg.MemoryLimit.
+// Past it, streaming outputs and checkpointed runs need every password written
+// as soon as it is generated, so they fall back to a Bloom filter instead of
+// disk deduplication, which only writes once generation finishes.
+func NewDeduplicator(cfg config.DedupConfig, out passwordWriter, expected int, streaming bool) (Deduplicator, error) {
+	mode := cfg.Mode
+	if mode == config.DedupAuto {
+		mode = config.DedupMemory
+		if expected > cfg.MemoryLimit {
+			mode = config.DedupDisk
+			if streaming {
+				mode = config.DedupBloom
+			}
+		}
+	}
+
+	switch mode {
+	case config.DedupNone:
+		return &passthroughDedup{out: out}, nil
+	case config.DedupMemory:
+		return &memoryDedup{out: out, seen: make(map[string]struct{})}, nil
+	case config.DedupBloom:
+		return &bloomDedup{out: out, filter: NewBloomFilter(expected, cfg.FalsePositiveRate)}, nil
+	case config.DedupDisk:
+		return newDiskDedup(out, cfg.MemoryLimit, cfg.TempDir)
+	default:
+		return nil, fmt.Errorf("unknown dedup mode '%s'", cfg.Mode)
+	}
+}
+
+type dedupCounts struct {
+	unique     int
+	duplicates int
+}
+
+func (dc *dedupCounts) Unique() int {
+	return dc.unique
+}
+
+func (dc *dedupCounts) Duplicates() int {
+	return dc.duplicates
+}
+
+type passthroughDedup struct {
+	dedupCounts
+	out passwordWriter
+}
+
+func (pd *passthroughDedup) WritePassword(password string) error {
+	pd.unique++
+	return pd.out.WritePassword(password)
+}
+
+func (pd *passthroughDedup) seed(password string) {
+	pd.unique++
+}
+
+func (pd *passthroughDedup) Close() error {
+	return nil
+}
+
+type memoryDedup struct {
+	dedupCounts
+	out  passwordWriter
+	seen map[string]struct{}
+}
+
+func (md *memoryDedup) WritePassword(password string) error {
+	if _, exists := md.seen[password]; exists {
+		md.duplicates++
+		return nil
+	}
+
+	md.seen[password] = struct{}{}
+	md.unique++
+
+	return md.out.WritePassword(password)
+}
+
+func (md *memoryDedup) seed(password string) {
+	if _, exists := md.seen[password]; !exists {
+		md.seen[password] = struct{}{}
+		md.unique++
+	}
+}
+
+func (md *memoryDedup) Close() error {
+	md.seen = nil
+	return nil
+}
+
+// bloomDedup keeps memory fixed regardless of the output size at the cost of
+// occasionally dropping a unique password that collides with earlier ones.
+type bloomDedup struct {
+	dedupCounts
+	out    passwordWriter
+	filter *BloomFilter
+}
+
+func (bd *bloomDedup) WritePassword(password string) error {
+	if bd.filter.Add(password) {
+		bd.duplicates++
+		return nil
+	}
+
+	bd.unique++
+
+	return bd.out.WritePassword(password)
+}
+
+func (bd *bloomDedup) seed(password string) {
+	if !bd.filter.Add(password) {
+		bd.unique++
+	}
+}
+
+func (bd *bloomDedup) Close() error {
+	return nil
+}
+
+// diskDedup is an external sort: it buffers up to limit passwords with their
+// sequence numbers, spilling each full buffer to a run file sorted by password.
+// On Close the runs are merged by password, keeping only the first occurrence
+// of each, and the survivors are sorted back by sequence number the same way.
+// Memory stays bounded by limit and the merge fan-in however large the output
+// grows, but nothing reaches the output until Close.
+type diskDedup struct {
+	dedupCounts
+	out    passwordWriter
+	byWord *runSorter
+	seq    uint64
+}
+
+func newDiskDedup(out passwordWriter, limit int, tempDir string) (*diskDedup, error) {
+	dir, err := os.MkdirTemp(tempDir, "craftlist-dedup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dedup directory: %w", err)
+	}
+
+	return &diskDedup{out: out, byWord: newRunSorter(dir, "word", limit, compareByPassword, true)}, nil
+}
+
+func (dd *diskDedup) WritePassword(password string) error {
+	if err := dd.byWord.add(dedupRecord{seq: dd.seq, password: password}); err != nil {
+		return err
+	}
+	dd.seq++
+
+	return nil
+}
+
+func (dd *diskDedup) Close() error {
+	defer os.RemoveAll(dd.byWord.dir)
+
+	bySeq := newRunSorter(dd.byWord.dir, "seq", dd.byWord.limit, compareBySeq, false)
+
+	if err := dd.byWord.drain(bySeq.add); err != nil {
+		return err
+	}
+	dd.duplicates = dd.byWord.dropped
+
+	return bySeq.drain(func(record dedupRecord) error {
+		dd.unique++
+		return dd.out.WritePassword(record.password)
+	})
+}
+
+type dedupRecord struct {
+	seq      uint64
+	password string
+}
+
+// compareByPassword orders equal passwords by sequence number, so the first
+// of a run of equal passwords is always the earliest one generated
+func compareByPassword(a, b dedupRecord) int {
+	if order := strings.Compare(a.password, b.password); order != 0 {
+		return order
+	}
+
+	return cmp.Compare(a.seq, b.seq)
+}
+
+func compareBySeq(a, b dedupRecord) int {
+	return cmp.Compare(a.seq, b.seq)
+}
+
+// runSorter sorts records with a bounded buffer, spilling sorted runs to dir.
+// A distinct sorter drops every record repeating the previous password.
+type runSorter struct {
+	dir      string
+	name     string
+	limit    int
+	compare  func(a, b dedupRecord) int
+	distinct bool
+	buffer   []dedupRecord
+	runs     []string
+	created  int
+	dropped  int
+}
+
+func newRunSorter(dir, name string, limit int, compare func(a, b dedupRecord) int, distinct bool) *runSorter {
+	return &runSorter{dir: dir, name: name, limit: limit, compare: compare, distinct: distinct}
+}
+
+func (rs *runSorter) add(record dedupRecord) error {
+	rs.buffer = append(rs.buffer, record)
+	if len(rs.buffer) < rs.limit {
+		return nil
+	}
+
+	return rs.spill()
+}
+
+func (rs *runSorter) spill() error {
+	slices.SortFunc(rs.buffer, rs.compare)
+
+	run, err := rs.createRun()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range rs.buffer {
+		if err := run.write(record); err != nil {
+			run.close()
+			return err
+		}
+	}
+	rs.buffer = rs.buffer[:0]
+
+	return run.close()
+}
+
+// drain emits every added record in order. Runs are merged dedupMergeFanIn at
+// a time into longer runs until a single merge can read them all.
+func (rs *runSorter) drain(emit func(dedupRecord) error) error {
+	if len(rs.runs) == 0 {
+		slices.SortFunc(rs.buffer, rs.compare)
+		filter := rs.newFilter(emit)
+
+		for _, record := range rs.buffer {
+			if err := filter(record); err != nil {
+				return err
+			}
+		}
+		rs.buffer = nil
+
+		return nil
+	}
+
+	if len(rs.buffer) > 0 {
+		if err := rs.spill(); err != nil {
+			return err
+		}
+	}
+	rs.buffer = nil
+
+	for len(rs.runs) > dedupMergeFanIn {
+		run, err := rs.createRun()
+		if err != nil {
+			return err
+		}
+
+		// The new run was appended last, so it is merged again only after every older run
+		batch := rs.runs[:dedupMergeFanIn]
+		rs.runs = rs.runs[dedupMergeFanIn:]
+
+		if err := rs.merge(batch, run.write); err != nil {
+			run.close()
+			return err
+		}
+		if err := run.close(); err != nil {
+			return err
+		}
+	}
+
+	return rs.merge(rs.runs, emit)
+}
+
+// newFilter wraps emit so a distinct sorter skips repeated passwords. Records
+// arrive sorted, so repeats are always adjacent.
+func (rs *runSorter) newFilter(emit func(dedupRecord) error) func(dedupRecord) error {
+	if !rs.distinct {
+		return emit
+	}
+
+	var last string
+	started := false
+
+	return func(record dedupRecord) error {
+		if started && record.password == last {
+			rs.dropped++
+			return nil
+		}
+		last, started = record.password, true
+
+		return emit(record)
+	}
+}
+
+// createRun opens a new run file and registers it with the sorter. The writer
+// drops repeated passwords as it goes, so runs shrink with every merge.
+func (rs *runSorter) createRun() (*runWriter, error) {
+	path := filepath.Join(rs.dir, fmt.Sprintf("%s-%06d", rs.name, rs.created))
+	rs.created++
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dedup run: %w", err)
+	}
+	rs.runs = append(rs.runs, path)
+
+	run := &runWriter{path: path, file: file, writer: bufio.NewWriter(file)}
+	run.write = rs.newFilter(run.writeRecord)
+
+	return run, nil
+}
+
+// merge reads the runs at paths in order, emits their records and removes them
+func (rs *runSorter) merge(paths []string, emit func(dedupRecord) error) error {
+	queue := &runQueue{compare: rs.compare}
+	filter := rs.newFilter(emit)
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open dedup run: %w", err)
+		}
+		defer os.Remove(path)
+		defer file.Close()
+
+		cursor := &runCursor{reader: bufio.NewReader(file)}
+		if ok, err := cursor.next(); err != nil {
+			return err
+		} else if ok {
+			queue.items = append(queue.items, cursor)
+		}
+	}
+
+	heap.Init(queue)
+
+	for queue.Len() > 0 {
+		cursor := queue.items[0]
+		if err := filter(cursor.record); err != nil {
+			return err
+		}
+
+		ok, err := cursor.next()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			heap.Fix(queue, 0)
+		} else {
+			heap.Pop(queue)
+		}
+	}
+
+	return nil
+}
+
+type runWriter struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	write  func(dedupRecord) error
+}
+
+func (rw *runWriter) writeRecord(record dedupRecord) error {
+	if err := writeRunRecord(rw.writer, record); err != nil {
+		return fmt.Errorf("failed to write dedup run: %w", err)
+	}
+
+	return nil
+}
+
+func (rw *runWriter) close() error {
+	defer rw.file.Close()
+
+	if err := rw.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to flush dedup run: %w", err)
+	}
+
+	return nil
+}
+
+// Run records are a uvarint sequence number, a uvarint length and the raw password bytes
+func writeRunRecord(writer *bufio.Writer, record dedupRecord) error {
+	var header [2 * binary.MaxVarintLen64]byte
+	size := binary.PutUvarint(header[:], record.seq)
+	size += binary.PutUvarint(header[size:], uint64(len(record.password)))
+
+	if _, err := writer.Write(header[:size]); err != nil {
+		return err
+	}
+
+	_, err := writer.WriteString(record.password)
+	return err
+}
+
+func readRunRecord(reader *bufio.Reader) (dedupRecord, error) {
+	seq, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return dedupRecord{}, err
+	}
+
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return dedupRecord{}, io.ErrUnexpectedEOF
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return dedupRecord{}, io.ErrUnexpectedEOF
+	}
+
+	return dedupRecord{seq: seq, password: string(buf)}, nil
+}
+
+type runCursor struct {
+	reader *bufio.Reader
+	record dedupRecord
+}
+
+func (rc *runCursor) next() (bool, error) {
+	record, err := readRunRecord(rc.reader)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read dedup run: %w", err)
+	}
+
+	rc.record = record
+
+	return true, nil
+}
+
+// runQueue is a min-heap of run cursors ordered by the sorter's comparison
+type runQueue struct {
+	items   []*runCursor
+	compare func(a, b dedupRecord) int
+}
+
+func (rq *runQueue) Len() int { return len(rq.items) }
+func (rq *runQueue) Less(i, j int) bool {
+	return rq.compare(rq.items[i].record, rq.items[j].record) < 0
+}
+func (rq *runQueue) Swap(i, j int) { rq.items[i], rq.items[j] = rq.items[j], rq.items[i] }
+func (rq *runQueue) Push(x any)    { rq.items = append(rq.items, x.(*runCursor)) }
+
+func (rq *runQueue) Pop() any {
+	last := rq.items[len(rq.items)-1]
+	rq.items = rq.items[:len(rq.items)-1]
+
+	return last
+}
@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+type sliceWriter struct {
+	passwords []string
+}
+
+func (sw *sliceWriter) WritePassword(password string) error {
+	sw.passwords = append(sw.passwords, password)
+	return nil
+}
+
+func TestDeduplicator(t *testing.T) {
+	input := []string{"acme", "acme2025", "acme", "corp", "acme2025", "", "corp", "admin"}
+
+	tests := []struct {
+		name               string
+		mode               string
+		expected           []string
+		expectedDuplicates int
+	}{
+		{
+			name:               "none keeps duplicates",
+			mode:               config.DedupNone,
+			expected:           input,
+			expectedDuplicates: 0,
+		},
+		{
+			name:               "memory",
+			mode:               config.DedupMemory,
+			expected:           []string{"acme", "acme2025", "corp", "", "admin"},
+			expectedDuplicates: 3,
+		},
+		{
+			name:               "bloom",
+			mode:               config.DedupBloom,
+			expected:           []string{"acme", "acme2025", "corp", "", "admin"},
+			expectedDuplicates: 3,
+		},
+		{
+			name:               "disk preserves first occurrence order",
+			mode:               config.DedupDisk,
+			expected:           []string{"acme", "acme2025", "corp", "", "admin"},
+			expectedDuplicates: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewDefaultDedupConfig()
+			cfg.Mode = tt.mode
+			cfg.MemoryLimit = 3
+			cfg.TempDir = t.TempDir()
+
+			out := &sliceWriter{}
+			dedup, err := NewDeduplicator(cfg, out, len(input), false)
+			if err != nil {
+				t.Fatalf("NewDeduplicator() returned error: %v", err)
+			}
+
+			for _, password := range input {
+				if err := dedup.WritePassword(password); err != nil {
+					t.Fatalf("WritePassword() returned error: %v", err)
+				}
+			}
+
+			if err := dedup.Close(); err != nil {
+				t.Fatalf("Close() returned error: %v", err)
+			}
+
+			if !reflect.DeepEqual(out.passwords, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, out.passwords)
+			}
+
+			if dedup.Duplicates() != tt.expectedDuplicates {
+				t.Errorf("expected %d duplicates, got %d", tt.expectedDuplicates, dedup.Duplicates())
+			}
+
+			if dedup.Unique() != len(tt.expected) {
+				t.Errorf("expected %d unique passwords, got %d", len(tt.expected), dedup.Unique())
+			}
+		})
+	}
+}
+
+func TestDeduplicatorAutoMode(t *testing.T) {
+	cfg := config.NewDefaultDedupConfig()
+	cfg.TempDir = t.TempDir()
+
+	small, err := NewDeduplicator(cfg, &sliceWriter{}, 10, false)
+	if err != nil {
+		t.Fatalf("NewDeduplicator() returned error: %v", err)
+	}
+	if _, ok := small.(*memoryDedup); !ok {
+		t.Errorf("expected memory dedup for small lists, got %T", small)
+	}
+
+	large, err := NewDeduplicator(cfg, &sliceWriter{}, cfg.MemoryLimit+1, false)
+	if err != nil {
+		t.Fatalf("NewDeduplicator() returned error: %v", err)
+	}
+	defer large.Close()
+
+	if _, ok := large.(*diskDedup); !ok {
+		t.Errorf("expected disk dedup for large lists, got %T", large)
+	}
+
+	streamed, err := NewDeduplicator(cfg, &sliceWriter{}, cfg.MemoryLimit+1, true)
+	if err != nil {
+		t.Fatalf("NewDeduplicator() returned error: %v", err)
+	}
+
+	if _, ok := streamed.(*bloomDedup); !ok {
+		t.Errorf("expected bloom dedup for large streamed lists, got %T", streamed)
+	}
+}
+
+func TestDiskDedupMultiPassMerge(t *testing.T) {
+	// 2000 passwords two at a time spill 1000 runs, more than one merge can
+	// read, with each password repeated across many of them
+	var input []string
+	for idx := 0; idx < 2000; idx++ {
+		input = append(input, fmt.Sprintf("pass%d", (idx*7)%300))
+	}
+
+	expected := &sliceWriter{}
+	memory := &memoryDedup{out: expected, seen: make(map[string]struct{})}
+
+	cfg := config.NewDefaultDedupConfig()
+	cfg.Mode = config.DedupDisk
+	cfg.MemoryLimit = 2
+	cfg.TempDir = t.TempDir()
+
+	out := &sliceWriter{}
+	disk, err := NewDeduplicator(cfg, out, len(input), false)
+	if err != nil {
+		t.Fatalf("NewDeduplicator() returned error: %v", err)
+	}
+
+	for _, password := range input {
+		memory.WritePassword(password)
+		if err := disk.WritePassword(password); err != nil {
+			t.Fatalf("WritePassword() returned error: %v", err)
+		}
+	}
+
+	if err := disk.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(out.passwords, expected.passwords) {
+		t.Errorf("expected the first occurrences in generation order, got %d passwords", len(out.passwords))
+	}
+
+	if disk.Unique() != 300 || disk.Duplicates() != 1700 {
+		t.Errorf("expected 300 unique and 1700 duplicates, got %d and %d", disk.Unique(), disk.Duplicates())
+	}
+
+	if entries, _ := os.ReadDir(cfg.TempDir); len(entries) != 0 {
+		t.Errorf("expected the dedup runs to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	bf := NewBloomFilter(100, 0.01)
+
+	if bf.Add("acme") {
+		t.Error("expected first insert to report missing")
+	}
+
+	if !bf.Add("acme") {
+		t.Error("expected second insert to report present")
+	}
+}
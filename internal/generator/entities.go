@@ -0,0 +1,177 @@
+package generator
+
+import (
+	"fmt"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/wordlist"
+)
+
+// Entity holds the prepared variations of the roster fields of one user.
+// Words and Kinds are keyed by the format of the roster placeholder they
+// fill. Names are the parts of the user's name a password policy without
+// usernames rejects.
+type Entity struct {
+	Username string
+	Names    []string
+	Words    map[string][]string
+	Kinds    map[string][]VariationKind
+}
+
+// SetUsers sets the roster users whose fields fill the roster placeholders.
+// Until the variations are prepared, every roster list holds the distinct
+// fields of all users.
+func (g *Generator) SetUsers(users []wordlist.User) {
+	g.users = users
+
+	for field, idx := range g.entityLists() {
+		var values []string
+		for _, user := range users {
+			if value := entityFields(user)[field]; value != "" {
+				values = append(values, value)
+			}
+		}
+		g.words[idx] = g.variations.deduplicate(values)
+	}
+}
+
+// Entities returns the prepared roster fields of every user
+func (g *Generator) Entities() []Entity {
+	return g.entities
+}
+
+// entityFields returns the fields of a user in config.EntityFormats order
+func entityFields(user wordlist.User) []string {
+	return []string{user.Username, user.First, user.Last, user.Initials()}
+}
+
+// entityLists returns the indexes of the roster lists in config.EntityFormats order
+func (g *Generator) entityLists() []int {
+	var lists []int
+	for idx, list := range g.lists {
+		if list.Entity {
+			lists = append(lists, idx)
+		}
+	}
+
+	return lists
+}
+
+// prepareEntities varies the fields of every user on their own. The roster
+// lists of the generator are left with the variations of all users, which
+// only patterns without roster placeholders ever read.
+func (g *Generator) prepareEntities() error {
+	lists := g.entityLists()
+	g.entities = make([]Entity, 0, len(g.users))
+	g.entityPolicies = make(map[string]*passwordPolicy, len(g.users))
+
+	for _, idx := range lists {
+		g.words[idx], g.kinds[idx] = []string{}, []VariationKind{}
+	}
+
+	for _, user := range g.users {
+		entity := Entity{
+			Username: user.Username,
+			Names:    []string{user.Username, user.First, user.Last},
+			Words:    make(map[string][]string, len(lists)),
+			Kinds:    make(map[string][]VariationKind, len(lists)),
+		}
+
+		for field, idx := range lists {
+			list := g.lists[idx]
+
+			var values []string
+			if value := entityFields(user)[field]; value != "" {
+				values = []string{value}
+			}
+
+			words, kinds, err := g.getVariations(values, list.Profile, list.Strategy)
+			if err != nil {
+				return fmt.Errorf("failed to get %s variations of %s: %w", list.Name, user.Username, err)
+			}
+			if g.config.Ranking.Enabled {
+				g.sortByWeight(words, kinds)
+			}
+
+			entity.Words[list.Format], entity.Kinds[list.Format] = words, kinds
+			g.words[idx], g.kinds[idx] = mergeVariations(g.words[idx], g.kinds[idx], words, kinds)
+		}
+
+		g.entities = append(g.entities, entity)
+		g.entityPolicies[user.Username] = g.policy.withNames(entity.Names)
+	}
+
+	return nil
+}
+
+// mergeVariations appends the variations not in words yet
+func mergeVariations(words []string, kinds []VariationKind, more []string, moreKinds []VariationKind) ([]string, []VariationKind) {
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		seen[word] = true
+	}
+
+	for idx, word := range more {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+			kinds = append(kinds, moreKinds[idx])
+		}
+	}
+
+	return words, kinds
+}
+
+// perEntity reports whether the pattern is generated once per user: when it
+// has a roster placeholder, or for every pattern when writing combos
+func (g *Generator) perEntity(compiled compiledPattern) bool {
+	return len(g.entities) > 0 && (g.combo() || compiled.usesEntity(g.lists))
+}
+
+// combo reports whether candidates are written as username:password
+func (g *Generator) combo() bool {
+	return g.config.Roster.Combo && len(g.entities) > 0
+}
+
+// bindings returns the generators the pattern is filled by: one per user
+// with the roster lists bound to that user, or the generator itself
+func (g *Generator) bindings(compiled compiledPattern) []*Generator {
+	if !g.perEntity(compiled) {
+		return []*Generator{g}
+	}
+
+	bound := make([]*Generator, len(g.entities))
+	for idx, entity := range g.entities {
+		bound[idx] = g.forEntity(entity)
+	}
+
+	return bound
+}
+
+// forEntity returns a copy of the generator whose roster lists hold the
+// variations of entity only
+func (g *Generator) forEntity(entity Entity) *Generator {
+	bound := *g
+	bound.user = entity.Username
+	bound.words = append([][]string{}, g.words...)
+	bound.kinds = append([][]VariationKind{}, g.kinds...)
+	bound.policy = g.entityPolicies[entity.Username]
+
+	for _, idx := range g.entityLists() {
+		format := g.lists[idx].Format
+		bound.words[idx], bound.kinds[idx] = entity.Words[format], entity.Kinds[format]
+	}
+
+	return &bound
+}
+
+// usesEntity reports whether the pattern has a roster placeholder
+func (cp *compiledPattern) usesEntity(lists []config.WordList) bool {
+	for idx, list := range lists {
+		if list.Entity && cp.usesList(idx) {
+			return true
+		}
+	}
+
+	return false
+}
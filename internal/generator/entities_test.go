@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/wordlist"
+)
+
+func newRosterGenerator(t *testing.T, patterns []string, configure func(*config.GeneratorConfig)) *Generator {
+	t.Helper()
+
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinYear = 2024
+	cfg.MaxYear = 2025
+	cfg.MinPasswordLen = 1
+	cfg.Substitutions = map[string][]string{"o": {"0"}}
+	cfg.Patterns = patterns
+	cfg.Variations.Custom = config.VariationProfile{}
+	cfg.Roster.Variations = config.VariationProfile{Case: true}
+	cfg.Roster.Strategy = config.VariationStrategy{Case: []string{config.CaseLower, config.CaseTitle}}
+	cfg.Dedup.Mode = config.DedupNone
+	cfg.Deterministic = true
+	if configure != nil {
+		configure(&cfg)
+	}
+
+	g := New(cfg, config.NewDefaultPlaceholdersConfig())
+	g.SetCustomWords([]string{"acme"})
+	g.SetUsers([]wordlist.User{
+		{Username: "jdoe", First: "John", Last: "Doe"},
+		{Username: "asmith", First: "Alice", Last: "Smith"},
+	})
+	if err := g.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	return g
+}
+
+func TestGeneratePerEntity(t *testing.T) {
+	g := newRosterGenerator(t, []string{"<FIRST><LAST><YEAR>", "<CUSTOM|noleet>!", "<INITIALS>@<CUSTOM|noleet|lower>"}, nil)
+
+	output := generateToFile(t, g)
+	expected := strings.Join([]string{
+		"JohnDoe2024", "JohnDoe2025", "Johndoe2024", "Johndoe2025", "johnDoe2024", "johnDoe2025", "johndoe2024", "johndoe2025",
+		"AliceSmith2024", "AliceSmith2025", "Alicesmith2024", "Alicesmith2025", "aliceSmith2024", "aliceSmith2025", "alicesmith2024", "alicesmith2025",
+		"acme!",
+		"jd@acme", "Jd@acme",
+		"as@acme", "As@acme",
+	}, "\n") + "\n"
+	if output != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output)
+	}
+
+	total, stats := g.Counter().CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers())
+	if total != 21 || stats["<FIRST><LAST><YEAR>"] != 16 {
+		t.Errorf("expected the counter to count 21 passwords with 16 of the roster pattern, got %d and %v", total, stats)
+	}
+}
+
+func TestGenerateCombo(t *testing.T) {
+	g := newRosterGenerator(t, []string{"<CUSTOM|noleet|lower><YEAR>", "<USER|lower>!"}, func(cfg *config.GeneratorConfig) {
+		cfg.Roster.Combo = true
+	})
+
+	output := generateToFile(t, g)
+	expected := strings.Join([]string{
+		"jdoe:acme2024", "jdoe:acme2025",
+		"asmith:acme2024", "asmith:acme2025",
+		"jdoe:jdoe!",
+		"asmith:asmith!",
+	}, "\n") + "\n"
+	if output != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output)
+	}
+
+	if total, _ := g.Counter().CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers()); total != 6 {
+		t.Errorf("expected the counter to count 6 combos, got %d", total)
+	}
+}
+
+func TestEntityPolicyRejectsOwnNames(t *testing.T) {
+	g := newRosterGenerator(t, []string{"<LAST>_<CUSTOM|noleet|lower>", "<INITIALS>_<CUSTOM|noleet|lower>"}, func(cfg *config.GeneratorConfig) {
+		cfg.Policy = config.PolicyConfig{NoUsername: true}
+	})
+
+	expected := []string{"<LAST>_<CUSTOM|noleet|lower> can never meet the password policy: it always contains a username"}
+	if conflicts := g.PolicyConflicts(); !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("expected conflicts %v, got %v", expected, conflicts)
+	}
+
+	expectedOutput := "jd_acme\nJd_acme\nas_acme\nAs_acme\n"
+	if output := generateToFile(t, g); output != expectedOutput {
+		t.Errorf("expected\n%s\ngot\n%s", expectedOutput, output)
+	}
+}
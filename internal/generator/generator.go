@@ -3,95 +3,266 @@ package generator
 import (
 	"context"
 	"fmt"
+	"os"
 	"runtime"
-	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/omarelshopky/craftlist/internal/config"
 	"github.com/omarelshopky/craftlist/internal/interfaces"
+	"github.com/omarelshopky/craftlist/internal/wordlist"
 )
 
 type Generator struct {
 	config      	config.GeneratorConfig
 	placeholders 	config.PlaceholdersConfig
-	customWords 	[]string
-	commonWords 	[]string
-	ssids       	[]string
+	lists       	[]config.WordList
+	words       	[][]string
+	kinds       	[][]VariationKind
 	numbers 		[]string
+	dates 			[][]string
+	keywalks 		[]string
+	policy 			*passwordPolicy
+	users 			[]wordlist.User
+	entities 		[]Entity
+	entityPolicies 	map[string]*passwordPolicy
+	user 			string // the roster user a bound copy fills the roster lists with
 	patterns    	*PatternProcessor
 	variations  	*VariationGenerator
 	output      	*OutputManager
 }
 
 func New(cfg config.GeneratorConfig, placeholders config.PlaceholdersConfig) *Generator {
-	return &Generator{
+	lists := config.WordLists(cfg, placeholders)
+
+	g := &Generator{
 		config:     	cfg,
 		placeholders: 	placeholders,
+		lists:      	lists,
+		words:      	make([][]string, len(lists)),
+		kinds:      	make([][]VariationKind, len(lists)),
+		dates:      	dateValues(cfg),
+		keywalks:   	KeywalkValues(cfg),
+		policy:     	newPasswordPolicy(cfg.Policy),
 		patterns:   	NewPatternProcessor(cfg, placeholders),
 		variations: 	NewVariationGenerator(cfg),
 		output:     	NewOutputManager(),
 	}
+	g.SetWords(placeholders.CommonWord.Format, cfg.CommonWords)
+
+	return g
 }
 
 func (g *Generator) SetCustomWords(words []string) {
-	g.customWords = words
+	g.SetWords(g.placeholders.CustomWord.Format, words)
 }
 
 func (g *Generator) SetSSIDs(ssids []string) {
-	g.ssids = ssids
+	g.SetWords(g.placeholders.SSID.Format, ssids)
+}
+
+// SetWords sets the words of the word list filling placeholder format
+func (g *Generator) SetWords(format string, words []string) {
+	if idx := g.listIndex(format); idx >= 0 {
+		g.words[idx] = words
+	}
 }
 
 func (g *Generator) GetCustomWords() []string {
-	return g.customWords
+	return g.GetWords(g.placeholders.CustomWord.Format)
 }
 
 func (g *Generator) GetCommonWords() []string {
-	return g.commonWords
+	return g.GetWords(g.placeholders.CommonWord.Format)
 }
 
 func (g *Generator) GetSSIDs() []string {
-	return g.ssids
+	return g.GetWords(g.placeholders.SSID.Format)
+}
+
+// GetWords returns the words of the word list filling placeholder format
+func (g *Generator) GetWords(format string) []string {
+	if idx := g.listIndex(format); idx >= 0 {
+		return g.words[idx]
+	}
+
+	return nil
+}
+
+// GetKinds returns the variation kind of every word of the word list
+// filling placeholder format, once the variations are prepared
+func (g *Generator) GetKinds(format string) []VariationKind {
+	if idx := g.listIndex(format); idx >= 0 {
+		return g.kinds[idx]
+	}
+
+	return nil
+}
+
+// GetWordLists returns the words of every word list keyed by placeholder
+func (g *Generator) GetWordLists() map[string][]string {
+	lists := make(map[string][]string, len(g.lists))
+	for idx, list := range g.lists {
+		lists[list.Format] = g.words[idx]
+	}
+
+	return lists
 }
 
 func (g *Generator) GetNumbers() []string {
 	return g.numbers
 }
 
+// SetKeywalks replaces the walks on the built-in layout, when the layout
+// comes from a file
+func (g *Generator) SetKeywalks(walks []string) {
+	g.keywalks = walks
+}
+
+func (g *Generator) GetKeywalks() []string {
+	return g.keywalks
+}
+
+func (g *Generator) listIndex(format string) int {
+	for idx, list := range g.lists {
+		if list.Format == format {
+			return idx
+		}
+	}
+
+	return -1
+}
+
 func (g *Generator) PrepareVariations() error {
 	var err error
 
-	g.customWords, err = g.getVariations(g.customWords)
-	if err != nil {
-		return fmt.Errorf("failed to get custom word variations: %w", err)
-	}
+	for idx, list := range g.lists {
+		if list.Entity {
+			continue
+		}
 
-	g.commonWords, err = g.getVariations(g.config.CommonWords)
-	if err != nil {
-		return fmt.Errorf("failed to get common word variations: %w", err)
+		g.words[idx], g.kinds[idx], err = g.getVariations(g.words[idx], list.Profile, list.Strategy)
+		if err != nil {
+			return fmt.Errorf("failed to get %s word variations: %w", list.Name, err)
+		}
 	}
 
-	g.ssids, err = g.getVariations(g.ssids)
-	if err != nil {
-		return fmt.Errorf("failed to get SSID variations: %w", err)
+	g.numbers = g.patterns.GenerateAllNumberPatterns()
+
+	if err := g.prepareEntities(); err != nil {
+		return err
 	}
 
-	g.numbers = g.patterns.GenerateAllNumberPatterns()
+	// Ranked generation walks each list from the most to the least likely variation
+	if g.config.Ranking.Enabled {
+		for idx := range g.lists {
+			g.sortByWeight(g.words[idx], g.kinds[idx])
+		}
+	}
 
 	return nil
 }
 
-func (g *Generator) Generate(ctx context.Context, outputFile string, printer interfaces.Printer) error {
-	// Create output file
-	writer, err := g.output.CreateWriter(outputFile)
+// Counter returns a Counter that knows the user-defined word lists and the
+// roster users, once the variations are prepared
+func (g *Generator) Counter() *Counter {
+	counter := NewCounter(g.config, g.placeholders)
+	for idx, list := range g.lists {
+		if idx >= config.BuiltinWordLists {
+			counter.SetWords(list.Format, g.words[idx])
+		}
+		counter.SetKinds(list.Format, g.kinds[idx])
+	}
+	counter.SetKeywalks(g.keywalks)
+	counter.SetEntities(g.entities)
+
+	return counter
+}
+
+func (g *Generator) Generate(ctx context.Context, output config.OutputConfig, printer interfaces.Printer) error {
+	if output.Spray() && !g.combo() {
+		return fmt.Errorf("output format %s needs the combos of roster users", output.Format)
+	}
+
+	ks, err := g.buildKeyspace()
+	if err != nil {
+		return err
+	}
+
+	checkpoints := g.config.Checkpoint.Enabled || g.config.Checkpoint.Resume
+	checkpointPath := CheckpointFilename(output.Filename)
+
+	start, end := g.streamWindow(ks)
+	var resumed *Checkpoint
+	var writer PasswordWriter
+
+	if g.config.Checkpoint.Resume {
+		if resumed, err = g.loadResumeCheckpoint(output); err != nil {
+			return err
+		}
+		start = max(start, resumed.Position)
+
+		writer, err = g.output.ResumeWriter(output, resumed.Bytes)
+	} else {
+		// Create output file
+		writer, err = g.output.CreateWriter(output)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
 	defer writer.Close()
 
+	// Validation limits checkpointed runs to a single plain file, so its byte count locates every checkpoint
+	fileWriter, _ := writer.(*OutputWriter)
+
+	// Spray outputs cap every user to a number of attempts, and stop once all users are full
+	spray, _ := writer.(*sprayWriter)
+
+	expected, _ := g.Counter().CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.numbers)
+
+	top := g.config.Ranking.Top
+	if top > 0 && top < expected {
+		expected = top
+	}
+
+	dedup, err := NewDeduplicator(g.config.Dedup, writer, expected, output.Filename == StdoutFilename || checkpoints || spray != nil)
+	if err != nil {
+		return fmt.Errorf("failed to create deduplicator: %w", err)
+	}
+
+	// Only large lists reach disk dedup in auto mode, so their output would otherwise stay empty for a long time unannounced
+	if _, onDisk := dedup.(*diskDedup); onDisk && g.config.Dedup.Mode == config.DedupAuto {
+		printer.Warning(fmt.Sprintf("About %d candidates exceed the dedup memory limit of %d, so they are deduplicated on disk "+
+			"and written once generation finishes (use --dedup bloom to write them as they are generated)", expected, g.config.Dedup.MemoryLimit))
+	}
+
+	resumedDuplicates := 0
+	if resumed != nil {
+		if err := seedDedup(dedup, output.Filename, resumed.Bytes); err != nil {
+			return err
+		}
+		resumedDuplicates = resumed.Duplicates
+
+		printer.Info(fmt.Sprintf("Resuming at candidate %d of %d (%s)", start, end, resumed.Pattern))
+	}
+
+	// Stopping at the top cutoff cancels the remaining work without failing the run
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Setup concurrent processing
 	numWorkers := runtime.NumCPU()
-	jobChan := make(chan PasswordJob, 1000)
-	resultChan := make(chan string, 1000)
+	jobChan := make(chan jobBatch, numWorkers*2)
+	resultChan := make(chan resultBatch, numWorkers*2)
+
+	// In deterministic, ranked and checkpointed modes results are written in
+	// job order, which needs a bounded window of batches that may be processed
+	// ahead of the writer
+	var window chan struct{}
+	if g.config.Deterministic || g.config.Ranking.Enabled || checkpoints {
+		window = make(chan struct{}, numWorkers*8)
+	}
 
 	var wg sync.WaitGroup
 	var writerWg sync.WaitGroup
@@ -107,27 +278,102 @@ func (g *Generator) Generate(ctx context.Context, outputFile string, printer int
 
 	// Start writer goroutine
 	writerWg.Add(1)
-	passwordCount := 0
+	candidateCount := 0
+	var writeErr error
+
+	// writePasswords reports whether the whole batch made it to the output
+	writePasswords := func(passwords []string) bool {
+		for _, password := range passwords {
+			// Keep draining after a failure so the workers are never blocked
+			if writeErr != nil || (top > 0 && dedup.Unique() >= top) {
+				cancel()
+				return false
+			}
 
-	go func() {
-		defer writerWg.Done()
-		for password := range resultChan {
-			if err := writer.WritePassword(password); err != nil {
-				return
+			if err := dedup.WritePassword(password); err != nil {
+				writeErr = err
+				cancel()
+				return false
+			}
+			candidateCount++
+
+			if spray != nil && spray.full(len(g.entities)) {
+				cancel()
+				return false
 			}
-			passwordCount++
 
-			if passwordCount%10000 == 0 {
-				printer.PrintProgress(passwordCount)
+			if candidateCount%10000 == 0 {
+				printer.PrintProgress(candidateCount)
 				writer.Flush()
 			}
 		}
+
+		return true
+	}
+
+	// written is the stream position up to which every candidate is in the output
+	written := start
+	configHash := g.configHash()
+	saveCheckpoint := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+
+		segment, offset := ks.locate(written)
+		checkpoint := Checkpoint{
+			ConfigHash: configHash,
+			Segment:    segment,
+			Offset:     offset,
+			Position:   written,
+			Bytes:      fileWriter.Written(),
+			Unique:     dedup.Unique(),
+			Duplicates: resumedDuplicates + dedup.Duplicates(),
+		}
+		if segment < len(ks.spaces) {
+			checkpoint.Pattern = ks.spaces[segment].pattern
+		}
+
+		return checkpoint.save(checkpointPath)
+	}
+
+	lastCheckpoint := time.Now()
+	interval := time.Duration(g.config.Checkpoint.Interval) * time.Second
+
+	go func() {
+		defer writerWg.Done()
+
+		reorderer := newResultReorderer(window)
+		for batch := range resultChan {
+			if window == nil {
+				writePasswords(batch.passwords)
+				continue
+			}
+
+			reorderer.push(batch, func(batch resultBatch) {
+				if !writePasswords(batch.passwords) {
+					return
+				}
+				written = batch.end
+
+				if checkpoints && time.Since(lastCheckpoint) >= interval {
+					if err := saveCheckpoint(); err != nil {
+						writeErr = err
+						cancel()
+					}
+					lastCheckpoint = time.Now()
+				}
+			})
+		}
 	}()
 
 	// Generate jobs based on patterns
 	go func() {
 		defer close(jobChan)
-		g.generateJobs(ctx, jobChan)
+
+		batcher := newJobBatcher(ctx, jobChan, window, start)
+		if g.generateJobs(ks, batcher, start, end) {
+			batcher.flush()
+		}
 	}()
 
 	// Wait for all workers to finish
@@ -137,153 +383,197 @@ func (g *Generator) Generate(ctx context.Context, outputFile string, printer int
 	// Wait for writer to finish
 	writerWg.Wait()
 
-	printer.PrintFinalCount(passwordCount)
+	if writeErr != nil {
+		dedup.Close()
+
+		// The consumer of a piped output stopped reading, which ends the run normally
+		if IsBrokenPipe(writeErr) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to write password: %w", writeErr)
+	}
+
+	if checkpoints {
+		if written == end || (top > 0 && dedup.Unique() >= top) {
+			os.Remove(checkpointPath)
+		} else {
+			if err := saveCheckpoint(); err != nil {
+				return err
+			}
+			printer.PrintCheckpointSaved(checkpointPath)
+		}
+	}
+
+	if err := dedup.Close(); err != nil {
+		if IsBrokenPipe(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to deduplicate passwords: %w", err)
+	}
+
+	unique := dedup.Unique()
+	if spray != nil {
+		unique -= spray.Capped()
+	}
+
+	printer.PrintFinalCount(unique)
+	printer.PrintDuplicatesCount(resumedDuplicates + dedup.Duplicates())
+	if spray != nil && spray.Capped() > 0 {
+		printer.Info(fmt.Sprintf("Dropped %d candidates over the per-user attempt cap", spray.Capped()))
+	}
 
 	return nil
 }
 
-func (g *Generator) worker(ctx context.Context, jobs <-chan PasswordJob, results chan<- string) {
+func (g *Generator) worker(ctx context.Context, jobs <-chan jobBatch, results chan<- resultBatch) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case job, ok := <-jobs:
+		case batch, ok := <-jobs:
 			if !ok {
 				return
 			}
 
-			password := g.patterns.ProcessPattern(job)
-			if password != "" && len(password) >= g.config.MinPasswordLen && len(password) <= g.config.MaxPasswordLen {
-				select {
-				case results <- password:
-				case <-ctx.Done():
-					return
+			passwords := make([]string, 0, len(batch.jobs))
+			for _, job := range batch.jobs {
+				password := g.patterns.ProcessPattern(job)
+				length := utf8.RuneCountInString(password)
+				if password == "" || length < g.config.MinPasswordLen || length > g.config.MaxPasswordLen {
+					continue
+				}
+
+				policy := g.policy
+				if job.User != "" {
+					policy = g.entityPolicies[job.User]
+				}
+				if policy != nil && !policy.allows(password) {
+					continue
 				}
+
+				if g.combo() {
+					password = job.User + comboSeparator + password
+				}
+				passwords = append(passwords, password)
+			}
+
+			// Empty batches are still sent so ordered writers can advance
+			select {
+			case results <- resultBatch{seq: batch.seq, end: batch.end, passwords: passwords}:
+			case <-ctx.Done():
+				return
 			}
 		}
 	}
 }
 
-func (g *Generator) generateJobs(ctx context.Context, jobChan chan<- PasswordJob) {
-	for _, pattern := range g.config.Patterns {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
-
-		// Ignore patterns with SSID if not entered
-		if len(g.ssids) == 0 && strings.Contains(pattern, g.placeholders.SSID.Format) {
-			continue
-		}
-
-		g.generateJobsForPattern(ctx, jobChan, pattern)
+// minLength is the shortest candidate both the length limits and the
+// password policy accept
+func (g *Generator) minLength() int {
+	if g.policy == nil {
+		return g.config.MinPasswordLen
 	}
+
+	return max(g.config.MinPasswordLen, g.policy.minLength)
 }
 
-func (g *Generator) generateJobsForPattern(ctx context.Context, jobChan chan<- PasswordJob, pattern string) {
-	// Determine what placeholders exist in the pattern
-	hasCustom := strings.Contains(pattern, g.placeholders.CustomWord.Format)
-	hasCommon := strings.Contains(pattern, g.placeholders.CommonWord.Format)
-	hasSSID := strings.Contains(pattern, g.placeholders.SSID.Format)
-	hasYear := strings.Contains(pattern, g.placeholders.Year.Format) || strings.Contains(pattern, g.placeholders.ShortYear.Format)
-	hasNum := strings.Contains(pattern, g.placeholders.Number.Format)
+// generateJobs queues the jobs of the stream positions from start up to end
+// in order and reports whether it ran to completion. Positions whose
+// candidates cannot meet the length limits are skipped without a job.
+func (g *Generator) generateJobs(ks keyspace, batcher *jobBatcher, start, end uint64) bool {
+	segment, offset := ks.locate(start)
+	position := start
+
+	for ; segment < len(ks.spaces) && position < end; segment++ {
+		space := &ks.spaces[segment]
+
+		for offset < space.size && position < end {
+			if next := space.nextFitting(offset, g.minLength(), g.config.MaxPasswordLen); next > offset {
+				skipped := min(next-offset, end-position)
+				batcher.skip(skipped)
+				offset += skipped
+				position += skipped
+				continue
+			}
 
-	separatorCount := strings.Count(pattern, g.placeholders.Separator.Format)
+			if !batcher.add(space.job(offset)) {
+				return false
+			}
+			offset++
+			position++
+		}
 
-	// Generate combinations based on pattern requirements
-	customRange := []string{""}
-	if hasCustom {
-		customRange = g.customWords
+		offset = 0
 	}
 
-	commonRange := []string{""}
-	if hasCommon {
-		commonRange = g.commonWords
+	return true
+}
+
+// getVariations expands words through the word, case and substitution
+// pipelines the profile enables, capped by the word list's strategy, and
+// reports which transformations produced each variation
+func (g *Generator) getVariations(words []string, profile config.VariationProfile, strategy config.VariationStrategy) ([]string, []VariationKind, error) {
+	if len(words) == 0 {
+		return []string{}, []VariationKind{}, nil
 	}
 
-	ssidRange := []string{""}
-	if hasSSID {
-		ssidRange = g.ssids
+	unchanged := func(word string) []string { return []string{word} }
+
+	wordVariationFunc := unchanged
+	if profile.Words {
+		wordVariationFunc = g.variations.GenerateWordVariations
 	}
 
-	yearRange := []int{0}
-	if hasYear {
-		yearRange = make([]int, 0, g.config.MaxYear-g.config.MinYear+1)
-		for y := g.config.MinYear; y <= g.config.MaxYear; y++ {
-			yearRange = append(yearRange, y)
-		}
+	wordVariations, err := g.generateVariations(words, wordVariationFunc)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	numberRange := []string{""}
-	if hasNum {
-		numberRange = g.numbers
+	kinds := make(map[string]VariationKind, len(wordVariations))
+	for _, word := range wordVariations {
+		kinds[word] = VariationOriginal
 	}
 
-	for _, customWord := range customRange {
-		for _, commonWord := range commonRange {
-			for _, ssid := range ssidRange {
-				for _, year := range yearRange {
-					for _, number := range numberRange {
-						g.generateSeparatorCombinations(ctx, jobChan, pattern, separatorCount, customWord, commonWord, ssid, year, number, []string{})
-					}
+	caseVariations := wordVariations
+	if profile.Case {
+		caseVariations, err = g.generateVariations(wordVariations, func(word string) []string {
+			variations := g.variations.CaseVariations(word, strategy)
+			for _, variation := range variations {
+				if _, exists := kinds[variation]; !exists {
+					kinds[variation] = VariationCase
 				}
 			}
+			return variations
+		})
+		if err != nil {
+			return nil, nil, err
 		}
 	}
-}
-
-func (g *Generator) generateSeparatorCombinations(ctx context.Context, jobChan chan<- PasswordJob, pattern string, remainingSeparators int, customWord, commonWord, ssid string, year int, number string, separators []string) {
 
-	// Base case: no more separators to process
-	if remainingSeparators == 0 {
-		select {
-		case <-ctx.Done():
-			return
-		case jobChan <- PasswordJob{
-			Pattern:    pattern,
-			CustomWord: customWord,
-			CommonWord: commonWord,
-			SSID:       ssid,
-			Year:       year,
-			Number:     number,
-			Separators: separators,
-		}:
+	subVariations := caseVariations
+	if profile.Leet {
+		subVariations, err = g.generateVariations(caseVariations, func(word string) []string {
+			variations := g.variations.ApplySubstitutions(word, strategy)
+			for _, variation := range variations {
+				if _, exists := kinds[variation]; !exists {
+					kinds[variation] = kinds[word].withLeet()
+				}
+			}
+			return variations
+		})
+		if err != nil {
+			return nil, nil, err
 		}
-		return
-	}
-
-	// Recursive case: generate combinations for remaining separators
-	for _, separator := range g.config.Separators {
-		newSeparators := make([]string, len(separators)+1)
-		copy(newSeparators, separators)
-		newSeparators[len(separators)] = separator
-
-		g.generateSeparatorCombinations(ctx, jobChan, pattern, remainingSeparators-1, customWord, commonWord, ssid, year, number, newSeparators)
-	}
-}
-
-func (g *Generator) getVariations(words []string) ([]string, error) {
-	if len(words) == 0 {
-		return []string{}, nil
-	}
-
-	wordVariations, err := g.generateVariations(words, g.variations.GenerateWordVariations)
-	if err != nil {
-		return nil, err
 	}
 
-	caseVariations, err := g.generateVariations(wordVariations, g.variations.GenerateCaseVariations)
-	if err != nil {
-		return nil, err
+	subKinds := make([]VariationKind, len(subVariations))
+	for idx, variation := range subVariations {
+		subKinds[idx] = kinds[variation]
 	}
 
-	subVariations, err := g.generateVariations(caseVariations, g.variations.ApplyAllSubstitutions)
-	if err != nil {
-		return nil, err
-	}
-
-	return subVariations, nil
+	return subVariations, subKinds, nil
 }
 
 func (g *Generator) generateVariations(words []string, variationFunc func(string) []string) ([]string, error) {
@@ -291,12 +581,10 @@ func (g *Generator) generateVariations(words []string, variationFunc func(string
 		return nil, fmt.Errorf("no words provided")
 	}
 
-	variations := make(map[string]struct{})
+	var variations []string
 	for _, word := range words {
-		for _, variation := range variationFunc(word) {
-			variations[variation] = struct{}{}
-		}
+		variations = append(variations, variationFunc(word)...)
 	}
 
-	return g.variations.ConvertSetToSlice(variations), nil
+	return g.variations.deduplicate(variations), nil
 }
\ No newline at end of file
@@ -1,11 +1,16 @@
 package generator
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"reflect"
-	"testing"
 	"sort"
+	"strings"
+	"testing"
 
 	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/ui"
 )
 
 func TestGetVariations(t *testing.T) {
@@ -52,7 +57,7 @@ func TestGetVariations(t *testing.T) {
 				}),
 			}
 
-			got, _ := g.getVariations(tt.words)
+			got, _, _ := g.getVariations(tt.words, config.NewDefaultVariationProfiles().Custom, config.VariationStrategy{})
 
 			// If no expected values, skip validation
 			if len(tt.expected) == 0 {
@@ -71,3 +76,240 @@ func TestGetVariations(t *testing.T) {
 		})
 	}
 }
+
+func newTestGenerator(t *testing.T, patterns []string) *Generator {
+	t.Helper()
+
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinYear = 2020
+	cfg.MaxYear = 2025
+	cfg.MinPasswordLen = 1
+	cfg.CommonWords = []string{"admin", "wifi"}
+	cfg.Separators = []string{"", "_", "-"}
+	cfg.NumberPatterns = []string{"dd"}
+	cfg.Substitutions = map[string][]string{"a": {"4", "@"}, "e": {"3"}}
+	cfg.Patterns = patterns
+	cfg.Dedup.Mode = config.DedupNone
+
+	return prepareTestGenerator(t, cfg, map[string][]string{"<CUSTOM>": {"acme", "evil"}})
+}
+
+// newTestConfig returns a deterministic, undeduplicated config generating
+// patterns with no minimum length
+func newTestConfig(patterns []string) config.GeneratorConfig {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinPasswordLen = 1
+	cfg.Patterns = patterns
+	cfg.Dedup.Mode = config.DedupNone
+	cfg.Deterministic = true
+
+	return cfg
+}
+
+// prepareTestGenerator builds a generator from cfg, fills the word lists
+// keyed by placeholder format and prepares its variations
+func prepareTestGenerator(t *testing.T, cfg config.GeneratorConfig, words map[string][]string) *Generator {
+	t.Helper()
+
+	g := New(cfg, config.NewDefaultPlaceholdersConfig())
+	for format, list := range words {
+		g.SetWords(format, list)
+	}
+
+	if err := g.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	return g
+}
+
+// generateLines runs the generator and checks its counter predicted exactly
+// the lines it wrote
+func generateLines(t *testing.T, g *Generator) []string {
+	t.Helper()
+
+	lines := strings.Split(strings.TrimSuffix(generateToFile(t, g), "\n"), "\n")
+
+	if total, _ := g.Counter().CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers()); total != len(lines) {
+		t.Errorf("expected the counter to count %d passwords, got %d", len(lines), total)
+	}
+
+	return lines
+}
+
+func generateToFile(t *testing.T, g *Generator) string {
+	t.Helper()
+
+	outputFile := filepath.Join(t.TempDir(), "passwords.txt")
+	if err := g.Generate(context.Background(), config.OutputConfig{Filename: outputFile}, ui.NewPrinter()); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	return string(data)
+}
+
+func TestGenerateDeterministic(t *testing.T) {
+	patterns := []string{"<CUSTOM><SEP><NUM>", "<COMMON><SEP><CUSTOM>", "<CUSTOM><SEP><YEAR>"}
+
+	first := newTestGenerator(t, patterns)
+	first.config.Deterministic = true
+	expected := generateToFile(t, first)
+
+	for run := 0; run < 3; run++ {
+		g := newTestGenerator(t, patterns)
+		g.config.Deterministic = true
+
+		if got := generateToFile(t, g); got != expected {
+			t.Fatalf("run %d produced a different output order", run)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	if lines[0] != "acme00" {
+		t.Errorf("expected output to start with the first word and number, got %q", lines[0])
+	}
+}
+
+func TestGenerateUserPlaceholder(t *testing.T) {
+	cfg := newTestConfig([]string{"<CITY><SEP><CUSTOM>", "<MONTH>"})
+	cfg.Separators = []string{"", "_"}
+	cfg.Placeholders = []config.UserPlaceholder{
+		{Format: "<CITY>", Words: []string{"paris"}, Variations: &config.VariationProfile{Case: true},
+			Strategy: config.VariationStrategy{Case: []string{config.CaseTitle}}},
+		{Format: "<MONTH>", Builtin: "months_short", Variations: &config.VariationProfile{}},
+	}
+	cfg.Variations.Custom = config.VariationProfile{}
+	cfg.Dedup.Mode = config.DedupMemory
+
+	g := prepareTestGenerator(t, cfg, map[string][]string{
+		"<CUSTOM>": {"acme"},
+		"<CITY>":   {"paris"},
+		"<MONTH>":  {"jan", "feb"},
+	})
+
+	expected := []string{"parisacme", "paris_acme", "Parisacme", "Paris_acme", "jan", "feb"}
+	if lines := generateLines(t, g); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+}
+
+func TestGenerateRepeatedPlaceholders(t *testing.T) {
+	cfg := newTestConfig([]string{"<CUSTOM><SEP><CUSTOM>", "<CUSTOM:1>.<CUSTOM=1>", "<YEAR:1><SHORTYEAR=1>", "<SHORTYEAR><SHORTYEAR>"})
+	cfg.MinYear = 2024
+	cfg.MaxYear = 2025
+	cfg.Separators = []string{"_"}
+	cfg.Variations.Custom = config.VariationProfile{}
+
+	g := prepareTestGenerator(t, cfg, map[string][]string{"<CUSTOM>": {"acme", "corp"}})
+
+	expected := []string{
+		"acme_acme", "acme_corp", "corp_acme", "corp_corp",
+		"acme.acme", "corp.corp",
+		"202424", "202525",
+		"2424", "2425", "2524", "2525",
+	}
+	if lines := generateLines(t, g); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+}
+
+func TestGeneratePlaceholderModifiers(t *testing.T) {
+	cfg := newTestConfig([]string{"<CUSTOM|upper>", "<CUSTOM|noleet|first2>", "<CUSTOM:1|title><CUSTOM=1|reverse>", "<SHORTYEAR|rev>"})
+	cfg.MinYear = 2024
+	cfg.MaxYear = 2025
+	cfg.Substitutions = map[string][]string{"a": {"4"}}
+	cfg.Variations.Custom = config.VariationProfile{Leet: true}
+
+	g := prepareTestGenerator(t, cfg, map[string][]string{"<CUSTOM>": {"acme"}})
+
+	expected := []string{
+		"ACME", "4CME",
+		"ac",
+		"Acmeemca", "4cmeemc4",
+		"42", "52",
+	}
+	if lines := generateLines(t, g); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+}
+
+func TestGenerateInlineMasks(t *testing.T) {
+	cfg := newTestConfig([]string{"<CUSTOM><MASK:[!@]{1,2}>", "<MASK:?d?u>", "<CUSTOM><MASK:?d{3,4}>"})
+	cfg.MaxPasswordLen = 7
+	cfg.Variations = config.VariationProfiles{}
+
+	g := prepareTestGenerator(t, cfg, map[string][]string{"<CUSTOM>": {"acme"}})
+
+	lines := generateLines(t, g)
+
+	expected := []string{"acme!", "acme@", "acme!!", "acme!@", "acme@!", "acme@@", "0A", "0B"}
+	if !reflect.DeepEqual(lines[:len(expected)], expected) {
+		t.Errorf("expected %v, got %v", expected, lines[:len(expected)])
+	}
+
+	// 6 suffixes, 260 digit and letter pairs and the 1000 three-digit
+	// suffixes; four digits make acme too long
+	if expected := 6 + 260 + 1000; len(lines) != expected {
+		t.Errorf("expected %d passwords, got %d", expected, len(lines))
+	}
+}
+
+func TestGenerateKeywalks(t *testing.T) {
+	cfg := newTestConfig([]string{"<KEYWALK>"})
+	cfg.Keywalk = config.NewDefaultKeywalkConfig()
+	cfg.Keywalk.Shift = true
+
+	g := prepareTestGenerator(t, cfg, nil)
+
+	lines := generateLines(t, g)
+
+	seen := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		if seen[line] {
+			t.Errorf("walk %q generated twice", line)
+		}
+		seen[line] = true
+	}
+
+	for _, walk := range []string{"qwer", "qwerty", "poiu", "1qaz", "zaq1", "1qaz2wsx", "zaq12wsx", "qweasd", "!QAZ2wsx", "1qaz@WSX"} {
+		if !seen[walk] {
+			t.Errorf("expected walk %q", walk)
+		}
+	}
+
+	for _, walk := range []string{"qwe", "qwertyuio", "qwas1"} {
+		if seen[walk] {
+			t.Errorf("unexpected walk %q", walk)
+		}
+	}
+}
+
+func TestGenerateDatePlaceholders(t *testing.T) {
+	cfg := newTestConfig([]string{"<SEASON|first3>", "<QUARTER>", "<DDMM>", "<MMYY>", "<DDMMYYYY>", "<MONTHABBR><MONTHNUM>"})
+	cfg.Dates = config.DatesConfig{Locales: []string{"en", "de"}, Start: "2024-12-30", End: "2025-01-02"}
+
+	g := prepareTestGenerator(t, cfg, nil)
+
+	lines := generateLines(t, g)
+
+	expected := []string{
+		"Spr", "Sum", "Aut", "Fal", "Win", "Frü", "Som", "Her",
+		"Q1", "Q2", "Q3", "Q4",
+		"0101", "0201", "3012", "3112",
+		"1224", "0125",
+		"30122024", "31122024", "01012025", "02012025",
+	}
+	if !reflect.DeepEqual(lines[:len(expected)], expected) {
+		t.Errorf("expected %v, got %v", expected, lines[:len(expected)])
+	}
+
+	// 16 distinct abbreviations across both locales, each with 12 month numbers
+	if got := len(lines) - len(expected); got != 16*12 {
+		t.Errorf("expected %d month combinations, got %d", 16*12, got)
+	}
+}
@@ -0,0 +1,434 @@
+package generator
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"unicode/utf8"
+)
+
+// segmentSpace indexes every job of a segment. An index is decoded as a
+// mixed-radix number with one digit per independent value of the pattern.
+// From the most to the least significant, the digits pick a word for each
+// word list the pattern uses (in config.WordLists order: custom, common,
+// SSID, then the user-defined placeholders), then the years, the dates, the
+// keyboard walks, the numbers and the separators, repeated placeholders of a
+// kind in pattern order. This is the same order the nested generation loops
+// used, so index i is always the i-th job of the segment.
+//
+// Modified values whose slots all write them the same way are stored already
+// written (prepared); the others are written per slot as jobs are decoded.
+// Inline masks have no stored values and are decoded from their index.
+//
+// lengths holds the length every value adds to a candidate (nil for masks,
+// which add between minLengths and maxLengths), and restMin and restMax
+// bound the length the dims from idx on add. With them, job generation
+// skips every index whose leading values already make the candidate too
+// long or too short, without building it.
+type segmentSpace struct {
+	pattern    string
+	user       string
+	slots      []patternSlot
+	values     [][]string
+	masks      []*maskValues
+	sizes      []uint64
+	prepared   []bool
+	size       uint64
+	literal    int
+	lengths    [][]int32
+	minLengths []int
+	maxLengths []int
+	restMin    []int
+	restMax    []int
+}
+
+// keyspace is the whole candidate stream: the segments laid end to end.
+// A position in the stream is stable across runs with the same inputs.
+type keyspace struct {
+	spaces []segmentSpace
+	size   uint64
+}
+
+// buildKeyspace lays out the segments, and fails when the candidate stream
+// has more positions than a uint64 can index
+func (g *Generator) buildKeyspace() (keyspace, error) {
+	var ks keyspace
+
+	for _, segment := range g.buildSegments() {
+		space, err := g.newSegmentSpace(segment)
+		if err != nil {
+			return keyspace{}, err
+		}
+		ks.spaces = append(ks.spaces, space)
+
+		var fits bool
+		if ks.size, fits = addSize(ks.size, space.size); !fits {
+			return keyspace{}, fmt.Errorf("the patterns have more than 2^64 candidates together, which the candidate stream cannot index")
+		}
+	}
+
+	return ks, nil
+}
+
+func (g *Generator) newSegmentSpace(segment patternSegment) (segmentSpace, error) {
+	compiled := segment.compiled
+
+	space := segmentSpace{
+		pattern:  compiled.pattern,
+		user:     segment.user,
+		slots:    compiled.slots,
+		values:   make([][]string, len(compiled.dims)),
+		masks:    make([]*maskValues, len(compiled.dims)),
+		sizes:    make([]uint64, len(compiled.dims)),
+		prepared: make([]bool, len(compiled.dims)),
+		size:     1,
+		literal:  compiled.literalLength,
+	}
+	overflow := fmt.Errorf("pattern %s has more than 2^64 candidates, which the candidate stream cannot index", compiled.pattern)
+
+	for idx, dim := range compiled.dims {
+		switch dim.kind {
+		case slotWord:
+			space.values[idx] = segment.words[idx]
+		case slotMask:
+			masks, fits := newMaskValues(dim.mask)
+			if !fits {
+				return segmentSpace{}, overflow
+			}
+			space.masks[idx], space.sizes[idx] = masks, masks.size
+			if space.size, fits = mulSize(space.size, space.sizes[idx]); !fits {
+				return segmentSpace{}, overflow
+			}
+			continue
+		default:
+			space.values[idx] = g.sourceValues(dim)
+		}
+
+		space.values[idx], space.prepared[idx] = compiled.dimValues(idx, space.values[idx])
+		space.sizes[idx] = uint64(len(space.values[idx]))
+
+		var fits bool
+		if space.size, fits = mulSize(space.size, space.sizes[idx]); !fits {
+			return segmentSpace{}, overflow
+		}
+	}
+
+	space.measureLengths(compiled)
+
+	return space, nil
+}
+
+// mulSize multiplies two keyspace sizes, and returns false when the product
+// does not fit in a uint64
+func mulSize(a, b uint64) (uint64, bool) {
+	hi, lo := bits.Mul64(a, b)
+
+	return lo, hi == 0
+}
+
+// addSize adds two keyspace sizes, and returns false when the sum does not
+// fit in a uint64
+func addSize(a, b uint64) (uint64, bool) {
+	sum, carry := bits.Add64(a, b, 0)
+
+	return sum, carry == 0
+}
+
+// sourceValues returns the values of a dim that holds neither words nor a mask
+func (g *Generator) sourceValues(dim patternDim) []string {
+	switch dim.kind {
+	case slotYear:
+		return g.years()
+	case slotDate:
+		return g.dates[dim.list]
+	case slotKeywalk:
+		return g.keywalks
+	case slotNumber:
+		return g.numbers
+	}
+
+	return g.config.Separators
+}
+
+// measureLengths records the length every value of every dim adds, summed
+// over the slots that write it
+func (s *segmentSpace) measureLengths(compiled compiledPattern) {
+	s.lengths = make([][]int32, len(compiled.dims))
+	s.minLengths = make([]int, len(compiled.dims))
+	s.maxLengths = make([]int, len(compiled.dims))
+
+	for idx, dim := range compiled.dims {
+		if s.masks[idx] != nil {
+			first := true
+			for length := range dim.mask.Lengths() {
+				if first || length < s.minLengths[idx] {
+					s.minLengths[idx] = length
+				}
+				if first || length > s.maxLengths[idx] {
+					s.maxLengths[idx] = length
+				}
+				first = false
+			}
+			continue
+		}
+
+		lengths := make([]int32, len(s.values[idx]))
+		for valueIdx, value := range s.values[idx] {
+			length := 0
+			for _, slot := range dim.slots {
+				if s.prepared[idx] {
+					length += utf8.RuneCountInString(value)
+				} else {
+					length += utf8.RuneCountInString(s.slots[slot].write(value))
+				}
+			}
+			lengths[valueIdx] = int32(length)
+
+			if valueIdx == 0 || length < s.minLengths[idx] {
+				s.minLengths[idx] = length
+			}
+			if valueIdx == 0 || length > s.maxLengths[idx] {
+				s.maxLengths[idx] = length
+			}
+		}
+		s.lengths[idx] = lengths
+	}
+
+	s.restMin = make([]int, len(compiled.dims)+1)
+	s.restMax = make([]int, len(compiled.dims)+1)
+	for idx := len(compiled.dims) - 1; idx >= 0; idx-- {
+		s.restMin[idx] = s.restMin[idx+1] + s.minLengths[idx]
+		s.restMax[idx] = s.restMax[idx+1] + s.maxLengths[idx]
+	}
+}
+
+// nextFitting returns the first index from index on whose candidate can
+// have a length between minLength and maxLength, or the size when none is
+// left. Values are decoded from the most significant; as soon as the values
+// picked so far rule the length out, the whole sub-tree of the less
+// significant values is skipped.
+func (s *segmentSpace) nextFitting(index uint64, minLength, maxLength int) uint64 {
+	if s.literal+s.restMin[0] > maxLength || s.literal+s.restMax[0] < minLength {
+		return s.size
+	}
+
+	for index < s.size {
+		stride := s.size
+		shortest, longest := s.literal, s.literal
+		fits := true
+
+		for idx, size := range s.sizes {
+			stride /= size
+			if s.lengths[idx] != nil {
+				length := int(s.lengths[idx][index/stride%size])
+				shortest += length
+				longest += length
+			} else {
+				shortest += s.minLengths[idx]
+				longest += s.maxLengths[idx]
+			}
+
+			if shortest+s.restMin[idx+1] > maxLength || longest+s.restMax[idx+1] < minLength {
+				index = (index/stride + 1) * stride
+				fits = false
+				break
+			}
+		}
+
+		if fits {
+			return index
+		}
+	}
+
+	return s.size
+}
+
+// years lists every year of the configured range as four digits; short
+// years are the last two
+func (g *Generator) years() []string {
+	return yearRange(g.config.MinYear, g.config.MaxYear)
+}
+
+func yearRange(minYear, maxYear int) []string {
+	years := make([]string, 0, max(0, maxYear-minYear+1))
+	for y := minYear; y <= maxYear; y++ {
+		years = append(years, strconv.Itoa(y))
+	}
+
+	return years
+}
+
+// job decodes the job at index, which must be below the segment size
+func (s *segmentSpace) job(index uint64) PasswordJob {
+	// Most patterns have few values, so they are decoded without allocating
+	var buffer [16]string
+	var values []string
+	if len(s.values) <= len(buffer) {
+		values = buffer[:len(s.values)]
+	} else {
+		values = make([]string, len(s.values))
+	}
+
+	for idx := len(s.values) - 1; idx >= 0; idx-- {
+		if s.masks[idx] != nil {
+			values[idx] = s.masks[idx].value(index % s.sizes[idx])
+		} else {
+			values[idx] = s.values[idx][index%s.sizes[idx]]
+		}
+		index /= s.sizes[idx]
+	}
+
+	slots := make([]Slot, len(s.slots))
+	for idx, slot := range s.slots {
+		value := values[slot.dim]
+		if !s.prepared[slot.dim] {
+			value = slot.write(value)
+		}
+		slots[idx] = Slot{Placeholder: slot.placeholder, Word: value}
+	}
+
+	return PasswordJob{Pattern: s.pattern, Slots: slots, User: s.user}
+}
+
+// ExpandPattern fills pattern with every combination of the words set on
+// the generator and of the years, numbers and separators, in keyspace
+// order. Candidates are not filtered by length.
+func (g *Generator) ExpandPattern(pattern string) ([]string, error) {
+	if g.numbers == nil {
+		g.numbers = g.patterns.GenerateAllNumberPatterns()
+	}
+
+	compiled := g.compilePattern(pattern)
+	segment := patternSegment{pattern: pattern, compiled: compiled, words: make([][]string, len(compiled.dims))}
+	for idx, dim := range compiled.dims {
+		if dim.kind == slotWord {
+			segment.words[idx], _ = g.dimWords(compiled, idx)
+		}
+	}
+
+	space, err := g.newSegmentSpace(segment)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := make([]string, 0, space.size)
+	for idx := uint64(0); idx < space.size; idx++ {
+		expanded = append(expanded, g.patterns.ProcessPattern(space.job(idx)))
+	}
+
+	return expanded, nil
+}
+
+// locate returns the segment holding position and the offset inside it.
+// Positions at or past the end return the number of segments.
+func (ks *keyspace) locate(position uint64) (int, uint64) {
+	for idx, space := range ks.spaces {
+		if position < space.size {
+			return idx, position
+		}
+		position -= space.size
+	}
+
+	return len(ks.spaces), 0
+}
+
+// partRange returns the stream positions [start, end) of part index out of
+// total. Parts are 1-based, contiguous and differ in size by at most one.
+func (ks *keyspace) partRange(index, total int) (uint64, uint64) {
+	return ks.partBoundary(index-1, total), ks.partBoundary(index, total)
+}
+
+// partBoundary computes size*index/total without overflowing
+func (ks *keyspace) partBoundary(index, total int) uint64 {
+	hi, lo := bits.Mul64(ks.size, uint64(index))
+	quotient, _ := bits.Div64(hi, lo, uint64(total))
+
+	return quotient
+}
+
+// streamWindow returns the stream positions this run is responsible for:
+// its part of the stream, narrowed by the skip and limit window
+func (g *Generator) streamWindow(ks keyspace) (uint64, uint64) {
+	start, end := uint64(0), ks.size
+	if g.config.Partition.Total > 0 {
+		start, end = ks.partRange(g.config.Partition.Index, g.config.Partition.Total)
+	}
+
+	if skip := g.config.Window.Skip; skip >= end-start {
+		start = end
+	} else {
+		start += skip
+	}
+	if limit := g.config.Window.Limit; limit > 0 && limit < end-start {
+		end = start + limit
+	}
+
+	return start, end
+}
+
+// KeyspaceSize returns the number of positions in the candidate stream,
+// counted before length filtering and deduplication
+func (g *Generator) KeyspaceSize() (uint64, error) {
+	ks, err := g.buildKeyspace()
+
+	return ks.size, err
+}
+
+// EstimateWindowCounts scales the per-pattern counts of the Counter down to
+// the share of every pattern this run generates
+func (g *Generator) EstimateWindowCounts(stats map[string]int) (map[string]int, error) {
+	ks, err := g.buildKeyspace()
+	if err != nil {
+		return nil, err
+	}
+	start, end := g.streamWindow(ks)
+
+	return ks.scaleCounts(stats, start, end), nil
+}
+
+// EstimatePartCounts scales the per-pattern counts of the Counter down to the
+// share of every pattern that falls into each of total parts of the stream
+func (g *Generator) EstimatePartCounts(stats map[string]int, total int) ([]map[string]int, error) {
+	ks, err := g.buildKeyspace()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]map[string]int, total)
+	for idx := range parts {
+		start, end := ks.partRange(idx+1, total)
+		parts[idx] = ks.scaleCounts(stats, start, end)
+	}
+
+	return parts, nil
+}
+
+// scaleCounts keeps the share of each pattern count that falls into the
+// positions [start, end). Candidates are assumed to be spread evenly over a
+// pattern, so the result is an estimate like the Counter's totals.
+func (ks *keyspace) scaleCounts(stats map[string]int, start, end uint64) map[string]int {
+	patternSizes := make(map[string]uint64)
+	overlaps := make(map[string]uint64)
+
+	var offset uint64
+	for _, space := range ks.spaces {
+		patternSizes[space.pattern] += space.size
+
+		lo, hi := max(start, offset), min(end, offset+space.size)
+		if hi > lo {
+			overlaps[space.pattern] += hi - lo
+		}
+		offset += space.size
+	}
+
+	scaled := make(map[string]int, len(stats))
+	for pattern, count := range stats {
+		if patternSizes[pattern] == 0 {
+			scaled[pattern] = 0
+			continue
+		}
+		scaled[pattern] = int(float64(count) * float64(overlaps[pattern]) / float64(patternSizes[pattern]))
+	}
+
+	return scaled
+}
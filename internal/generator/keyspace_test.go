@@ -0,0 +1,240 @@
+package generator
+
+import (
+	"context"
+	"io"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/ui"
+)
+
+func buildTestKeyspace(t *testing.T, g *Generator) keyspace {
+	t.Helper()
+
+	ks, err := g.buildKeyspace()
+	if err != nil {
+		t.Fatalf("buildKeyspace() returned error: %v", err)
+	}
+
+	return ks
+}
+
+func TestSegmentSpaceJobOrder(t *testing.T) {
+	g := newTestGenerator(t, []string{"<CUSTOM><SEP><SHORTYEAR><SEP>"})
+	g.config.MaxYear = 2021
+	g.SetCustomWords([]string{"acme", "evil"})
+	g.config.Separators = []string{"", "_"}
+
+	ks := buildTestKeyspace(t, g)
+	space := ks.spaces[0]
+
+	if space.size != 16 {
+		t.Fatalf("expected 16 jobs, got %d", space.size)
+	}
+
+	var got []string
+	for idx := uint64(0); idx < space.size; idx++ {
+		got = append(got, g.patterns.ProcessPattern(space.job(idx)))
+	}
+
+	// The custom word changes slowest and the last separator fastest
+	expected := []string{
+		"acme20", "acme20_", "acme_20", "acme_20_", "acme21", "acme21_", "acme_21", "acme_21_",
+		"evil20", "evil20_", "evil_20", "evil_20_", "evil21", "evil21_", "evil_21", "evil_21_",
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestSegmentSpaceNextFitting(t *testing.T) {
+	g := newTestGenerator(t, []string{"<CUSTOM><SEP><SHORTYEAR><SEP>"})
+	g.config.MaxYear = 2021
+	g.SetCustomWords([]string{"acme", "evil corp"})
+	g.config.Separators = []string{"", "_"}
+
+	space := buildTestKeyspace(t, g).spaces[0]
+
+	var fitting []string
+	for idx := space.nextFitting(0, 6, 7); idx < space.size; idx = space.nextFitting(idx+1, 6, 7) {
+		fitting = append(fitting, g.patterns.ProcessPattern(space.job(idx)))
+	}
+
+	// evil corp is too long with any year, so its whole half is skipped, and
+	// acme with both separators is too long
+	expected := []string{"acme20", "acme20_", "acme_20", "acme21", "acme21_", "acme_21"}
+	if !reflect.DeepEqual(fitting, expected) {
+		t.Errorf("expected %v, got %v", expected, fitting)
+	}
+
+	if next := space.nextFitting(8, 6, 7); next != space.size {
+		t.Errorf("expected the evil corp half to be skipped to %d, got %d", space.size, next)
+	}
+}
+
+func TestGenerateSkipsCandidatesOutsideLengthLimits(t *testing.T) {
+	patterns := []string{"<CUSTOM><SEP><NUM>", "<COMMON><SEP><YEAR><MASK:?d{1,2}>", "<CUSTOM:1><CUSTOM=1|upper>"}
+
+	g := newTestGenerator(t, patterns)
+	g.config.Deterministic = true
+	g.config.MinPasswordLen = 8
+	g.config.MaxPasswordLen = 10
+	pruned := generateToFile(t, g)
+
+	// Filtering the full expansion by length gives the same candidates
+	unpruned := newTestGenerator(t, patterns)
+	var expected []string
+	for _, pattern := range patterns {
+		candidates, err := unpruned.ExpandPattern(pattern)
+		if err != nil {
+			t.Fatalf("ExpandPattern() returned error: %v", err)
+		}
+		for _, candidate := range candidates {
+			if len(candidate) >= 8 && len(candidate) <= 10 {
+				expected = append(expected, candidate)
+			}
+		}
+	}
+
+	if got := strings.Split(strings.TrimSuffix(pruned, "\n"), "\n"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %d candidates, got %d", len(expected), len(got))
+	}
+}
+
+func TestKeyspaceLocate(t *testing.T) {
+	ks := keyspace{spaces: []segmentSpace{{size: 3}, {size: 0}, {size: 5}}, size: 8}
+
+	tests := []struct {
+		position        uint64
+		expectedSegment int
+		expectedOffset  uint64
+	}{
+		{0, 0, 0},
+		{2, 0, 2},
+		{3, 2, 0},
+		{7, 2, 4},
+		{8, 3, 0},
+	}
+
+	for _, tt := range tests {
+		segment, offset := ks.locate(tt.position)
+		if segment != tt.expectedSegment || offset != tt.expectedOffset {
+			t.Errorf("locate(%d) = (%d, %d), expected (%d, %d)",
+				tt.position, segment, offset, tt.expectedSegment, tt.expectedOffset)
+		}
+	}
+}
+
+func TestKeyspacePartRange(t *testing.T) {
+	ks := keyspace{size: 10}
+
+	var previous uint64
+	for idx := 1; idx <= 3; idx++ {
+		start, end := ks.partRange(idx, 3)
+		if start != previous {
+			t.Errorf("part %d starts at %d, expected %d", idx, start, previous)
+		}
+		if end-start < 3 || end-start > 4 {
+			t.Errorf("part %d has %d positions, expected 3 or 4", idx, end-start)
+		}
+		previous = end
+	}
+
+	if previous != ks.size {
+		t.Errorf("parts end at %d, expected %d", previous, ks.size)
+	}
+}
+
+func TestGeneratePartsCoverStream(t *testing.T) {
+	patterns := []string{"<CUSTOM><SEP><NUM>", "<COMMON><SEP><CUSTOM>", "<CUSTOM><SEP><YEAR>"}
+
+	full := newTestGenerator(t, patterns)
+	full.config.Deterministic = true
+	expected := generateToFile(t, full)
+
+	var combined string
+	for idx := 1; idx <= 4; idx++ {
+		g := newTestGenerator(t, patterns)
+		g.config.Deterministic = true
+		g.config.Partition = config.PartitionConfig{Index: idx, Total: 4}
+
+		part := generateToFile(t, g)
+		if part == "" {
+			t.Errorf("part %d is empty", idx)
+		}
+		combined += part
+	}
+
+	if combined != expected {
+		t.Errorf("concatenated parts differ from the full stream")
+	}
+}
+
+func TestGenerateSkipLimit(t *testing.T) {
+	patterns := []string{"<CUSTOM><SEP><NUM>", "<COMMON><SEP><CUSTOM>"}
+
+	full := newTestGenerator(t, patterns)
+	full.config.Deterministic = true
+	lines := strings.SplitAfter(generateToFile(t, full), "\n")
+
+	tests := []struct {
+		name     string
+		window   config.WindowConfig
+		expected []string
+	}{
+		{"skip into the second pattern", config.WindowConfig{Skip: uint64(len(lines) - 5)}, lines[len(lines)-5:]},
+		{"window across patterns", config.WindowConfig{Skip: 21590, Limit: 20}, lines[21590:21610]},
+		{"limit past the end", config.WindowConfig{Skip: 10, Limit: 1 << 40}, lines[10:]},
+		{"skip past the end", config.WindowConfig{Skip: 1 << 40}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, patterns)
+			g.config.Window = tt.window
+
+			if got := generateToFile(t, g); got != strings.Join(tt.expected, "") {
+				t.Errorf("expected %d lines of the full stream, got %q", len(tt.expected), got)
+			}
+		})
+	}
+}
+
+func TestStreamWindowClampsSkip(t *testing.T) {
+	g := newTestGenerator(t, []string{"<CUSTOM><SEP><NUM>"})
+	g.config.Partition = config.PartitionConfig{Index: 2, Total: 2}
+	g.config.Window = config.WindowConfig{Skip: math.MaxUint64}
+
+	ks := buildTestKeyspace(t, g)
+	if start, end := g.streamWindow(ks); start != ks.size || end != ks.size {
+		t.Errorf("expected an empty window at %d, got [%d, %d)", ks.size, start, end)
+	}
+}
+
+func TestKeyspaceRejectsOverflow(t *testing.T) {
+	g := newTestGenerator(t, []string{"<MASK:?a{9}>"})
+	if size, err := g.KeyspaceSize(); err != nil || size != 630249409724609375 {
+		t.Errorf("expected 95^9 positions, got %d (%v)", size, err)
+	}
+	if total, _ := g.Counter().CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers()); total != 630249409724609375 {
+		t.Errorf("expected the counter to count 95^9 passwords, got %d", total)
+	}
+
+	// Every custom word variation multiplies the mask past 2^64 positions
+	g = newTestGenerator(t, []string{"<CUSTOM><MASK:?a{9}>"})
+	if _, err := g.KeyspaceSize(); err == nil || !strings.Contains(err.Error(), "2^64") {
+		t.Errorf("expected an error about 2^64 candidates, got %v", err)
+	}
+	if err := g.Generate(context.Background(), config.OutputConfig{Filename: filepath.Join(t.TempDir(), "out.txt")}, ui.NewPrinterTo(io.Discard, io.Discard)); err == nil {
+		t.Error("expected Generate() to refuse a keyspace it cannot index")
+	}
+	if total, _ := g.Counter().CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers()); total != math.MaxInt {
+		t.Errorf("expected the count to saturate at %d, got %d", math.MaxInt, total)
+	}
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/wordlist"
+)
+
+type keyPosition struct {
+	row int
+	col int
+}
+
+func (p keyPosition) add(step keyPosition) keyPosition {
+	return keyPosition{row: p.row + step.row, col: p.col + step.col}
+}
+
+var walkSteps = map[string]keyPosition{
+	config.WalkRight: {col: 1},
+	config.WalkLeft:  {col: -1},
+	config.WalkDown:  {row: 1},
+	config.WalkUp:    {row: -1},
+}
+
+// Keywalks lists every walk the config allows on the layout, in
+// direction, segment count and length order. A walk is one or more runs of
+// keys of the same length. Each run starts next to the previous one, one row
+// down for horizontal runs and one column right for vertical runs: either
+// next to its start, so all runs go the same way (qweasd, 1qaz2wsx), or
+// next to its end and going back (zaq12wsx).
+func Keywalks(layout wordlist.KeyboardLayout, cfg config.KeywalkConfig) []string {
+	var walks []string
+	seen := make(map[string]bool)
+
+	for _, direction := range cfg.Directions {
+		step := walkSteps[direction]
+		next := keyPosition{row: 1}
+		if step.row != 0 {
+			next = keyPosition{col: 1}
+		}
+
+		for segments := 1; segments <= cfg.MaxSegments; segments++ {
+			for runLength := 2; runLength*segments <= cfg.MaxLength; runLength++ {
+				if runLength*segments < cfg.MinLength {
+					continue
+				}
+
+				for row := range layout.Rows {
+					for col := range layout.Rows[row] {
+						for _, zigzag := range []bool{false, true} {
+							if zigzag && segments == 1 {
+								continue
+							}
+
+							positions, ok := walkPositions(layout, keyPosition{row, col}, step, next, runLength, segments, zigzag)
+							if !ok {
+								continue
+							}
+
+							for _, walk := range typeWalk(layout, positions, runLength, cfg.Shift) {
+								if !seen[walk] {
+									seen[walk] = true
+									walks = append(walks, walk)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return walks
+}
+
+// walkPositions lays out the keys of a walk, or reports that it leaves the keyboard
+func walkPositions(layout wordlist.KeyboardLayout, start, step, next keyPosition, runLength, segments int, zigzag bool) ([]keyPosition, bool) {
+	positions := make([]keyPosition, 0, runLength*segments)
+
+	runStart := start
+	for segment := 0; segment < segments; segment++ {
+		position := runStart
+		for key := 0; key < runLength; key++ {
+			if position.row < 0 || position.row >= len(layout.Rows) || position.col < 0 || position.col >= len(layout.Rows[position.row]) {
+				return nil, false
+			}
+
+			positions = append(positions, position)
+			if key < runLength-1 {
+				position = position.add(step)
+			}
+		}
+
+		if zigzag {
+			runStart = position.add(next)
+			step = keyPosition{row: -step.row, col: -step.col}
+		} else {
+			runStart = runStart.add(next)
+		}
+	}
+
+	return positions, true
+}
+
+// typeWalk types the keys of a walk, and with shift also every combination
+// of runs typed with shift (!QAZ2wsx, 1qaz@WSX)
+func typeWalk(layout wordlist.KeyboardLayout, positions []keyPosition, runLength int, shift bool) []string {
+	segments := len(positions) / runLength
+
+	combinations := 1
+	if shift && len(layout.Shifted) > 0 {
+		combinations = 1 << segments
+	}
+
+	walks := make([]string, 0, combinations)
+	for mask := 0; mask < combinations; mask++ {
+		var walk strings.Builder
+		for idx, position := range positions {
+			if mask&(1<<(idx/runLength)) != 0 {
+				walk.WriteRune(layout.Shifted[position.row][position.col])
+			} else {
+				walk.WriteRune(layout.Rows[position.row][position.col])
+			}
+		}
+		walks = append(walks, walk.String())
+	}
+
+	return walks
+}
+
+// KeywalkValues lists the walks on the configured built-in layout. The walks
+// on a layout file are made by the caller and set with SetKeywalks.
+func KeywalkValues(cfg config.GeneratorConfig) []string {
+	layout, exists := wordlist.Keyboard(cfg.Keywalk.Layout)
+	if !exists || cfg.Keywalk.File != "" {
+		return nil
+	}
+
+	return Keywalks(layout, cfg.Keywalk)
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// maskValues enumerates the candidates of an inline mask by index, so a mask
+// is never held in memory. Candidates with the same repetition counts come
+// together, the fewest repetitions of the first position first. Like the
+// segments of a keyspace, the repetition counts are decoded from the index
+// instead of being listed, so wide repeat ranges cost no memory.
+type maskValues struct {
+	positions []maskPosition
+	size      uint64
+}
+
+// maskPosition is a mask position with the candidate counts its
+// repetitions lead to
+type maskPosition struct {
+	charset   []rune
+	minRepeat int
+	powers    []uint64 // len(charset)^repeat, from minRepeat on
+	rest      uint64   // candidates of this and every following position
+}
+
+// newMaskValues indexes the candidates of mask, and returns false when they
+// do not fit in a uint64
+func newMaskValues(mask config.Mask) (*maskValues, bool) {
+	positions := make([]maskPosition, len(mask.Positions))
+	rest := uint64(1)
+
+	for idx := len(mask.Positions) - 1; idx >= 0; idx-- {
+		position := mask.Positions[idx]
+		current := maskPosition{charset: position.Charset, minRepeat: position.MinRepeat}
+
+		power, fits := uint64(1), true
+		for range position.MinRepeat - 1 {
+			if power, fits = mulSize(power, uint64(len(position.Charset))); !fits {
+				return nil, false
+			}
+		}
+
+		var candidates uint64
+		for repeat := position.MinRepeat; repeat <= position.MaxRepeat; repeat++ {
+			if power, fits = mulSize(power, uint64(len(position.Charset))); !fits {
+				return nil, false
+			}
+			current.powers = append(current.powers, power)
+
+			if candidates, fits = addSize(candidates, power); !fits {
+				return nil, false
+			}
+		}
+
+		if rest, fits = mulSize(rest, candidates); !fits {
+			return nil, false
+		}
+		current.rest = rest
+		positions[idx] = current
+	}
+
+	return &maskValues{positions: positions, size: rest}, true
+}
+
+// value returns the candidate at index, which must be below the size. The
+// repetition counts are picked from the first position on, each one taking
+// a block of the candidates of the positions after it; what is left of the
+// index then picks the characters, the last one varying fastest.
+func (m *maskValues) value(index uint64) string {
+	repeats := make([]int, len(m.positions))
+	chosen := uint64(1)
+	length := 0
+
+	for idx, position := range m.positions {
+		following := uint64(1)
+		if idx+1 < len(m.positions) {
+			following = m.positions[idx+1].rest
+		}
+
+		for offset, power := range position.powers {
+			// Never past the size, which is known to fit
+			block := chosen * power * following
+			if index < block || offset == len(position.powers)-1 {
+				repeats[idx] = position.minRepeat + offset
+				chosen *= power
+				break
+			}
+			index -= block
+		}
+		length += repeats[idx]
+	}
+
+	chars := make([]rune, length)
+	pos := length - 1
+	for idx := len(m.positions) - 1; idx >= 0; idx-- {
+		charset := m.positions[idx].charset
+		for range repeats[idx] {
+			chars[pos] = charset[index%uint64(len(charset))]
+			index /= uint64(len(charset))
+			pos--
+		}
+	}
+
+	return string(chars)
+}
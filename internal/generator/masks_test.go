@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// listMask lists the candidates of mask in the documented order, repetition
+// counts first, by building every combination
+func listMask(mask config.Mask) []string {
+	type variant struct{ charsets [][]rune }
+	variants := []variant{{}}
+
+	for _, position := range mask.Positions {
+		var next []variant
+		for _, current := range variants {
+			for repeat := position.MinRepeat; repeat <= position.MaxRepeat; repeat++ {
+				charsets := append([][]rune{}, current.charsets...)
+				for range repeat {
+					charsets = append(charsets, position.Charset)
+				}
+				next = append(next, variant{charsets: charsets})
+			}
+		}
+		variants = next
+	}
+
+	var candidates []string
+	for _, current := range variants {
+		words := []string{""}
+		for _, charset := range current.charsets {
+			var longer []string
+			for _, word := range words {
+				for _, char := range charset {
+					longer = append(longer, word+string(char))
+				}
+			}
+			words = longer
+		}
+		candidates = append(candidates, words...)
+	}
+
+	return candidates
+}
+
+func TestMaskValuesOrder(t *testing.T) {
+	for _, text := range []string{"?d", "[ab]{1,3}", "[ab]{1,2}[xyz]{2,3}", "?d[!@]{1,2}x{2}"} {
+		mask, err := config.ParseMask(text)
+		if err != nil {
+			t.Fatalf("ParseMask(%q) returned error: %v", text, err)
+		}
+
+		values, ok := newMaskValues(mask)
+		if !ok {
+			t.Fatalf("newMaskValues(%q) reported an overflow", text)
+		}
+
+		expected := listMask(mask)
+		if values.size != uint64(len(expected)) {
+			t.Fatalf("%s: expected %d candidates, got %d", text, len(expected), values.size)
+		}
+
+		for idx, candidate := range expected {
+			if got := values.value(uint64(idx)); got != candidate {
+				t.Fatalf("%s: expected candidate %d to be %q, got %q", text, idx, candidate, got)
+			}
+		}
+	}
+}
+
+func TestMaskValuesWideRepeats(t *testing.T) {
+	// 16^8 repetition combinations, far too many to list
+	mask, err := config.ParseMask(strings.Repeat("[a]{1,16}", 8))
+	if err != nil {
+		t.Fatalf("ParseMask() returned error: %v", err)
+	}
+
+	values, ok := newMaskValues(mask)
+	if !ok {
+		t.Fatal("newMaskValues() reported an overflow")
+	}
+
+	if values.size != 1<<32 {
+		t.Errorf("expected %d candidates, got %d", uint64(1)<<32, values.size)
+	}
+
+	if got := values.value(0); got != "aaaaaaaa" {
+		t.Errorf("expected the shortest candidate first, got %q", got)
+	}
+
+	if got := values.value(values.size - 1); got != strings.Repeat("a", 128) {
+		t.Errorf("expected the longest candidate last, got %d characters", len(got))
+	}
+}
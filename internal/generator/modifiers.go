@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// applyModifiers transforms value by every modifier in turn. Filtering
+// modifiers leave the value as it is; they act on the word list instead.
+func applyModifiers(value string, modifiers []config.Modifier) string {
+	for _, modifier := range modifiers {
+		switch modifier.Name {
+		case config.ModifierUpper:
+			value = strings.ToUpper(value)
+		case config.ModifierLower:
+			value = strings.ToLower(value)
+		case config.ModifierTitle:
+			value = titleCase(value)
+		case config.ModifierReverse:
+			runes := []rune(value)
+			slices.Reverse(runes)
+			value = string(runes)
+		case config.ModifierFirst:
+			if runes := []rune(value); len(runes) > modifier.Length {
+				value = string(runes[:modifier.Length])
+			}
+		case config.ModifierLast:
+			if runes := []rune(value); len(runes) > modifier.Length {
+				value = string(runes[len(runes)-modifier.Length:])
+			}
+		}
+	}
+
+	return value
+}
+
+// write returns the text the slot writes for a value of its dim
+func (s *patternSlot) write(value string) string {
+	if s.kind == slotShortYear {
+		value = value[2:]
+	}
+
+	return applyModifiers(value, s.modifiers)
+}
+
+// keepsKind reports whether the filtering modifiers on the slots of dim keep
+// word variations of kind
+func (cp *compiledPattern) keepsKind(dim int, kind VariationKind) bool {
+	for _, slot := range cp.dims[dim].slots {
+		for _, modifier := range cp.slots[slot].modifiers {
+			switch {
+			case modifier.Name == config.ModifierNoLeet && (kind == VariationLeet || kind == VariationCaseLeet):
+				return false
+			case modifier.Name == config.ModifierNoCase && (kind == VariationCase || kind == VariationCaseLeet):
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// filterWords keeps the words of dim whose variation kind its modifiers
+// allow. Without kinds every word counts as an original.
+func (cp *compiledPattern) filterWords(dim int, words []string, kinds []VariationKind) ([]string, []VariationKind) {
+	if len(kinds) != len(words) || (cp.keepsKind(dim, VariationCase) && cp.keepsKind(dim, VariationLeet)) {
+		return words, kinds
+	}
+
+	var keptWords []string
+	var keptKinds []VariationKind
+	for idx, word := range words {
+		if cp.keepsKind(dim, kinds[idx]) {
+			keptWords = append(keptWords, word)
+			keptKinds = append(keptKinds, kinds[idx])
+		}
+	}
+
+	return keptWords, keptKinds
+}
+
+// modified reports whether any slot of dim has modifiers
+func (cp *compiledPattern) modified(dim int) bool {
+	for _, slot := range cp.dims[dim].slots {
+		if len(cp.slots[slot].modifiers) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dimValues returns the values dim takes. When every slot of a modified dim
+// writes its value the same way, the values are returned already written
+// and without the duplicates the modifiers create (<CUSTOM|upper> writes
+// acme and Acme once as ACME), and prepared is true.
+func (cp *compiledPattern) dimValues(dim int, values []string) ([]string, bool) {
+	if !cp.modified(dim) {
+		return values, false
+	}
+
+	slots := cp.dims[dim].slots
+	first := cp.slots[slots[0]]
+	for _, slot := range slots[1:] {
+		if cp.slots[slot].kind != first.kind || !slices.Equal(cp.slots[slot].modifiers, first.modifiers) {
+			return values, false
+		}
+	}
+
+	written := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = first.write(value)
+		if !seen[value] {
+			seen[value] = true
+			written = append(written, value)
+		}
+	}
+
+	return written, true
+}
+
+// writtenValues returns the text every slot of dim writes for values, slot
+// after slot
+func (cp *compiledPattern) writtenValues(dim int, values []string) []string {
+	slots := cp.dims[dim].slots
+	if len(slots) == 1 && len(cp.slots[slots[0]].modifiers) == 0 && cp.slots[slots[0]].kind != slotShortYear {
+		return values
+	}
+
+	written := make([]string, 0, len(values)*len(slots))
+	for _, slot := range slots {
+		for _, value := range values {
+			written = append(written, cp.slots[slot].write(value))
+		}
+	}
+
+	return written
+}
@@ -2,48 +2,446 @@ package generator
 
 import (
 	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/omarelshopky/craftlist/internal/config"
 )
 
+// StdoutFilename is the output filename that streams passwords to stdout
+const StdoutFilename = "-"
+
+// PasswordWriter receives every password that makes it to the output
+type PasswordWriter interface {
+	WritePassword(password string) error
+	Flush() error
+	Close() error
+}
+
+// Compressor wraps an output stream in a compressing writer
+type Compressor struct {
+	Name      string
+	Extension string
+	NewWriter func(w io.Writer) (CompressWriter, error)
+}
+
+type CompressWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+var compressors = map[string]Compressor{
+	config.CompressionGzip: {
+		Name:      config.CompressionGzip,
+		Extension: ".gz",
+		NewWriter: func(w io.Writer) (CompressWriter, error) {
+			return gzip.NewWriter(w), nil
+		},
+	},
+	config.CompressionZstd: {
+		Name:      config.CompressionZstd,
+		Extension: ".zst",
+		NewWriter: func(w io.Writer) (CompressWriter, error) {
+			return zstd.NewWriter(w)
+		},
+	},
+}
+
 type OutputManager struct{}
 
+// OutputWriter writes passwords to a single file or to stdout
 type OutputWriter struct {
-	file   *os.File
-	writer *bufio.Writer
+	file       *os.File
+	checksum   *checksumWriter
+	compressor CompressWriter
+	writer     *bufio.Writer
+	lines      int64
+	size       int64
+	offset     int64
 }
 
 func NewOutputManager() *OutputManager {
 	return &OutputManager{}
 }
 
-func (om *OutputManager) CreateWriter(filename string) (*OutputWriter, error) {
+// CreateWriter opens the output described by cfg, splitting it into chunks
+// when a line or size limit is set. The spray formats write roster combos.
+func (om *OutputManager) CreateWriter(cfg config.OutputConfig) (PasswordWriter, error) {
+	compressor, err := om.resolveCompressor(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Spray() {
+		return newSprayWriter(om, cfg, compressor)
+	}
+
+	return om.openWriter(cfg, compressor)
+}
+
+// openWriter opens the plain line output of cfg
+func (om *OutputManager) openWriter(cfg config.OutputConfig, compressor *Compressor) (PasswordWriter, error) {
+	if cfg.Filename == StdoutFilename {
+		return om.createStdoutWriter(compressor)
+	}
+
+	if cfg.SplitLines > 0 || cfg.SplitBytes > 0 {
+		return newSplitWriter(om, cfg, compressor), nil
+	}
+
+	return om.createFileWriter(cfg.Filename, compressor)
+}
+
+// resolveCompressor picks the compressor named in cfg or, in auto mode, the
+// one matching the output file extension. A nil compressor means plain text.
+func (om *OutputManager) resolveCompressor(cfg config.OutputConfig) (*Compressor, error) {
+	switch cfg.Compression {
+	case config.CompressionNone:
+		return nil, nil
+	case config.CompressionAuto, "":
+		for _, compressor := range compressors {
+			if strings.HasSuffix(cfg.Filename, compressor.Extension) {
+				return &compressor, nil
+			}
+		}
+		return nil, nil
+	}
+
+	compressor, exists := compressors[cfg.Compression]
+	if !exists {
+		return nil, fmt.Errorf("unknown compression '%s'", cfg.Compression)
+	}
+
+	return &compressor, nil
+}
+
+func (om *OutputManager) createFileWriter(filename string, compressor *Compressor) (*OutputWriter, error) {
 	file, err := os.Create(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create output file: %w", err)
 	}
 
-	writer := bufio.NewWriter(file)
+	writer, err := newOutputWriter(file, compressor)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	writer.file = file
 
-	return &OutputWriter{
-		file:   file,
-		writer: writer,
-	}, nil
+	return writer, nil
+}
+
+// appendFileWriter reopens an output file to write after its content
+func (om *OutputManager) appendFileWriter(filename string, compressor *Compressor) (*OutputWriter, error) {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	writer, err := newOutputWriter(file, compressor)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	writer.file = file
+
+	return writer, nil
+}
+
+// ResumeWriter reopens a plain output file, drops anything past offset and
+// appends from there
+func (om *OutputManager) ResumeWriter(cfg config.OutputConfig, offset int64) (*OutputWriter, error) {
+	file, err := os.OpenFile(cfg.Filename, os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate output file: %w", err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek output file: %w", err)
+	}
+
+	writer, err := newOutputWriter(file, nil)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	writer.file = file
+	writer.offset = offset
+
+	return writer, nil
+}
+
+// createStdoutWriter streams passwords to stdout for piping into other tools
+func (om *OutputManager) createStdoutWriter(compressor *Compressor) (*OutputWriter, error) {
+	// Without this the runtime kills the process on the first write after the
+	// reader goes away; ignoring SIGPIPE turns it into an EPIPE write error
+	signal.Ignore(syscall.SIGPIPE)
+
+	return newOutputWriter(os.Stdout, compressor)
+}
+
+func newOutputWriter(out io.Writer, compressor *Compressor) (*OutputWriter, error) {
+	ow := &OutputWriter{checksum: newChecksumWriter(out)}
+
+	var target io.Writer = ow.checksum
+	if compressor != nil {
+		compressWriter, err := compressor.NewWriter(ow.checksum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create compressor: %w", err)
+		}
+		ow.compressor = compressWriter
+		target = compressWriter
+	}
+
+	ow.writer = bufio.NewWriterSize(target, 64*1024)
+
+	return ow, nil
 }
 
 func (ow *OutputWriter) WritePassword(password string) error {
-	_, err := fmt.Fprintln(ow.writer, password)
-	return err
+	if _, err := ow.writer.WriteString(password); err != nil {
+		return err
+	}
+	if err := ow.writer.WriteByte('\n'); err != nil {
+		return err
+	}
+
+	ow.lines++
+	ow.size += int64(len(password)) + 1
+
+	return nil
 }
 
 func (ow *OutputWriter) Flush() error {
-	return ow.writer.Flush()
+	if err := ow.writer.Flush(); err != nil {
+		return err
+	}
+
+	if ow.compressor != nil {
+		return ow.compressor.Flush()
+	}
+
+	return nil
+}
+
+// Written returns the size of the output file once buffered passwords are flushed
+func (ow *OutputWriter) Written() int64 {
+	return ow.offset + ow.checksum.bytes
 }
 
 func (ow *OutputWriter) Close() error {
-	if err := ow.writer.Flush(); err != nil {
-		ow.file.Close()
-		return err
+	err := ow.writer.Flush()
+
+	if ow.compressor != nil {
+		if closeErr := ow.compressor.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if ow.file != nil {
+		if closeErr := ow.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// IsBrokenPipe reports whether err means the reading end of the output went away
+func IsBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
+
+// checksumWriter counts and hashes the bytes that reach the underlying output
+type checksumWriter struct {
+	out   io.Writer
+	hash  hash.Hash
+	bytes int64
+}
+
+func newChecksumWriter(out io.Writer) *checksumWriter {
+	return &checksumWriter{out: out, hash: sha256.New()}
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	n, err := cw.out.Write(p)
+	cw.hash.Write(p[:n])
+	cw.bytes += int64(n)
+
+	return n, err
+}
+
+// Manifest describes the chunks of a split output
+type Manifest struct {
+	Compression string      `json:"compression"`
+	TotalLines  int64       `json:"total_lines"`
+	Chunks      []ChunkInfo `json:"chunks"`
+}
+
+type ChunkInfo struct {
+	File   string `json:"file"`
+	Lines  int64  `json:"lines"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// splitWriter rotates to a new numbered chunk whenever the current one
+// reaches the configured line count or uncompressed size, and writes a
+// manifest of all chunks on Close
+type splitWriter struct {
+	manager    *OutputManager
+	config     config.OutputConfig
+	compressor *Compressor
+	current    *OutputWriter
+	manifest   Manifest
+}
+
+func newSplitWriter(manager *OutputManager, cfg config.OutputConfig, compressor *Compressor) *splitWriter {
+	sw := &splitWriter{manager: manager, config: cfg, compressor: compressor}
+
+	sw.manifest.Compression = config.CompressionNone
+	if compressor != nil {
+		sw.manifest.Compression = compressor.Name
 	}
-	return ow.file.Close()
-}
\ No newline at end of file
+
+	return sw
+}
+
+func (sw *splitWriter) WritePassword(password string) error {
+	if sw.current != nil && sw.isFull(int64(len(password))+1) {
+		if err := sw.closeChunk(); err != nil {
+			return err
+		}
+	}
+
+	if sw.current == nil {
+		writer, err := sw.manager.createFileWriter(sw.chunkPath(len(sw.manifest.Chunks)), sw.compressor)
+		if err != nil {
+			return err
+		}
+		sw.current = writer
+	}
+
+	return sw.current.WritePassword(password)
+}
+
+func (sw *splitWriter) isFull(nextSize int64) bool {
+	if sw.config.SplitLines > 0 && sw.current.lines >= sw.config.SplitLines {
+		return true
+	}
+
+	return sw.config.SplitBytes > 0 && sw.current.size+nextSize > sw.config.SplitBytes
+}
+
+func (sw *splitWriter) Flush() error {
+	if sw.current == nil {
+		return nil
+	}
+
+	return sw.current.Flush()
+}
+
+func (sw *splitWriter) Close() error {
+	if sw.current != nil {
+		if err := sw.closeChunk(); err != nil {
+			return err
+		}
+	}
+
+	return sw.writeManifest()
+}
+
+func (sw *splitWriter) closeChunk() error {
+	chunk := sw.current
+	sw.current = nil
+
+	if err := chunk.Close(); err != nil {
+		return fmt.Errorf("failed to close output chunk: %w", err)
+	}
+
+	sw.manifest.Chunks = append(sw.manifest.Chunks, ChunkInfo{
+		File:   filepath.Base(chunk.file.Name()),
+		Lines:  chunk.lines,
+		Bytes:  chunk.checksum.bytes,
+		SHA256: hex.EncodeToString(chunk.checksum.hash.Sum(nil)),
+	})
+	sw.manifest.TotalLines += chunk.lines
+
+	return nil
+}
+
+func (sw *splitWriter) writeManifest() error {
+	data, err := json.MarshalIndent(sw.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.WriteFile(ManifestFilename(sw.config.Filename), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// chunkPath numbers a chunk before the file extensions, so passwords.txt.gz
+// becomes passwords.000.txt.gz
+func (sw *splitWriter) chunkPath(index int) string {
+	base, extensions := splitOutputFilename(sw.config.Filename)
+
+	return fmt.Sprintf("%s.%03d%s", base, index, extensions)
+}
+
+// ManifestFilename returns where the manifest of a split output is written
+func ManifestFilename(filename string) string {
+	base, _ := splitOutputFilename(filename)
+
+	return base + ".manifest.json"
+}
+
+// SuffixFilename inserts -suffix before the extensions of an output
+// filename, so passwords.txt.gz becomes passwords-suffix.txt.gz. Stdout is
+// left as it is.
+func SuffixFilename(filename, suffix string) string {
+	if filename == StdoutFilename {
+		return filename
+	}
+
+	base, ext := splitOutputFilename(filename)
+
+	return base + "-" + suffix + ext
+}
+
+// splitOutputFilename separates the file extension and any compression
+// extension from the rest of the path
+func splitOutputFilename(filename string) (string, string) {
+	compressionExt := ""
+	for _, compressor := range compressors {
+		if strings.HasSuffix(filename, compressor.Extension) {
+			compressionExt = compressor.Extension
+			filename = strings.TrimSuffix(filename, compressionExt)
+			break
+		}
+	}
+
+	ext := filepath.Ext(filename)
+
+	return strings.TrimSuffix(filename, ext), ext + compressionExt
+}
@@ -0,0 +1,156 @@
+package generator
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+func writePasswords(t *testing.T, cfg config.OutputConfig, passwords []string) {
+	t.Helper()
+
+	writer, err := NewOutputManager().CreateWriter(cfg)
+	if err != nil {
+		t.Fatalf("CreateWriter() returned error: %v", err)
+	}
+
+	for _, password := range passwords {
+		if err := writer.WritePassword(password); err != nil {
+			t.Fatalf("WritePassword() returned error: %v", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to read gzip header of %s: %v", path, err)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to decompress %s: %v", path, err)
+	}
+
+	return string(data)
+}
+
+func TestCreateWriterCompressionByExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwords.txt.gz")
+
+	writePasswords(t, config.OutputConfig{Filename: path, Compression: config.CompressionAuto}, []string{"acme", "evil"})
+
+	if got := readGzip(t, path); got != "acme\nevil\n" {
+		t.Errorf("expected gzip content %q, got %q", "acme\nevil\n", got)
+	}
+}
+
+func TestSplitWriter(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.OutputConfig{
+		Filename:    filepath.Join(dir, "passwords.txt.gz"),
+		Compression: config.CompressionAuto,
+		SplitLines:  2,
+	}
+
+	writePasswords(t, cfg, []string{"acme", "evil", "acme2025", "evil2025", "corp"})
+
+	expectedChunks := []struct {
+		file    string
+		content string
+	}{
+		{"passwords.000.txt.gz", "acme\nevil\n"},
+		{"passwords.001.txt.gz", "acme2025\nevil2025\n"},
+		{"passwords.002.txt.gz", "corp\n"},
+	}
+
+	for _, chunk := range expectedChunks {
+		if got := readGzip(t, filepath.Join(dir, chunk.file)); got != chunk.content {
+			t.Errorf("expected %s to contain %q, got %q", chunk.file, chunk.content, got)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "passwords.manifest.json"))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+
+	if manifest.Compression != config.CompressionGzip {
+		t.Errorf("expected gzip compression in manifest, got %s", manifest.Compression)
+	}
+
+	if manifest.TotalLines != 5 || len(manifest.Chunks) != len(expectedChunks) {
+		t.Fatalf("expected 5 lines in %d chunks, got %d lines in %d chunks",
+			len(expectedChunks), manifest.TotalLines, len(manifest.Chunks))
+	}
+
+	for idx, chunk := range manifest.Chunks {
+		if chunk.File != expectedChunks[idx].file {
+			t.Errorf("expected chunk %d to be %s, got %s", idx, expectedChunks[idx].file, chunk.File)
+		}
+		if len(chunk.SHA256) != 64 {
+			t.Errorf("expected a sha256 checksum for %s, got %q", chunk.File, chunk.SHA256)
+		}
+	}
+}
+
+func TestSplitWriterBySize(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.OutputConfig{
+		Filename:    filepath.Join(dir, "passwords.txt"),
+		Compression: config.CompressionNone,
+		SplitBytes:  10,
+	}
+
+	writePasswords(t, cfg, []string{"acme", "evil", "acme2025"})
+
+	for file, expected := range map[string]string{
+		"passwords.000.txt": "acme\nevil\n",
+		"passwords.001.txt": "acme2025\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if string(data) != expected {
+			t.Errorf("expected %s to contain %q, got %q", file, expected, string(data))
+		}
+	}
+}
+
+func TestSuffixFilename(t *testing.T) {
+	tests := map[string]string{
+		"passwords.txt":    "passwords-admins.txt",
+		"out/passwords.gz": "out/passwords-admins.gz",
+		"list.txt.zst":     "list-admins.txt.zst",
+		"-":                "-",
+	}
+
+	for filename, expected := range tests {
+		if got := SuffixFilename(filename, "admins"); got != expected {
+			t.Errorf("SuffixFilename(%q) = %q, expected %q", filename, got, expected)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package generator
 
 import (
-	"strconv"
 	"strings"
 
 	"github.com/omarelshopky/craftlist/internal/config"
@@ -12,87 +11,98 @@ type PatternProcessor struct {
 	placeholders 	config.PlaceholdersConfig
 }
 
+// Slot fills one placeholder occurrence of a pattern with its value
+type Slot struct {
+	Placeholder string
+	Word        string
+}
+
+// PasswordJob fills one pattern. Slots holds a value for every placeholder
+// occurrence in pattern order; a slice keeps the per-job allocation small.
+// User is the roster user the job was bound to, if any.
 type PasswordJob struct {
-	Pattern    string
-	CustomWord string
-	CommonWord string
-	SSID       string
-	Year       int
-	Number     string
-	Separators []string
+	Pattern string
+	Slots   []Slot
+	User    string
 }
 
 func NewPatternProcessor(cfg config.GeneratorConfig, placeholders config.PlaceholdersConfig) *PatternProcessor {
 	return &PatternProcessor{config: cfg, placeholders: placeholders}
 }
 
+// ProcessPattern fills the pattern in a single pass. Each slot replaces the
+// next occurrence of its placeholder, so repeated placeholders can take
+// different values; placeholders without a slot are kept as they are.
 func (pp *PatternProcessor) ProcessPattern(job PasswordJob) string {
-	password := job.Pattern
-
-	password = strings.ReplaceAll(password, pp.placeholders.CustomWord.Format, job.CustomWord)
-	password = strings.ReplaceAll(password, pp.placeholders.CommonWord.Format, job.CommonWord)
-	password = strings.ReplaceAll(password, pp.placeholders.SSID.Format, job.SSID)
-	password = strings.ReplaceAll(password, pp.placeholders.Number.Format, job.Number)
-
-	if job.Year > 0 {
-		yearStr := strconv.Itoa(job.Year)
-		shortYear := yearStr[2:]
-		password = strings.ReplaceAll(password, pp.placeholders.Year.Format, yearStr)
-		password = strings.ReplaceAll(password, pp.placeholders.ShortYear.Format, shortYear)
-	}
-
-	// Handle multiple separators by replacing each <SEP> sequentially
-	if strings.Contains(password, pp.placeholders.Separator.Format) {
-		separatorIndex := 0
-		for strings.Contains(password, pp.placeholders.Separator.Format) && separatorIndex < len(job.Separators) {
-			password = strings.Replace(password, pp.placeholders.Separator.Format, job.Separators[separatorIndex], 1)
-			separatorIndex++
+	var password strings.Builder
+	password.Grow(len(job.Pattern) + 8*len(job.Slots))
+
+	rest := job.Pattern
+	for _, slot := range job.Slots {
+		idx := strings.Index(rest, slot.Placeholder)
+		if idx < 0 {
+			continue
 		}
+
+		password.WriteString(rest[:idx])
+		password.WriteString(slot.Word)
+		rest = rest[idx+len(slot.Placeholder):]
 	}
+	password.WriteString(rest)
 
-	return password
+	return password.String()
 }
 
+// GenerateAllNumberPatterns expands every number pattern in config order.
+// Patterns and their total size are checked when the config is validated,
+// so a pattern that does not parse is skipped.
 func (pp *PatternProcessor) GenerateAllNumberPatterns() []string {
 	var allNumbers []string
 
 	for _, pattern := range pp.config.NumberPatterns {
-		if strings.Contains(pattern, "d") {
-			// Handle digit replacement
-			expanded := pp.expandDigitPattern(pattern)
-			allNumbers = append(allNumbers, expanded...)
-		} else {
-			allNumbers = append(allNumbers, pattern)
-		}
+		allNumbers = append(allNumbers, pp.expandNumberPattern(pattern)...)
 	}
 
 	return allNumbers
 }
 
-func (pp *PatternProcessor) expandDigitPattern(pattern string) []string {
-	if !strings.Contains(pattern, "d") {
-		return []string{pattern}
+func (pp *PatternProcessor) expandNumberPattern(pattern string) []string {
+	parsed, err := config.ParseNumberPattern(pattern)
+	if err != nil {
+		return nil
 	}
 
 	var results []string
-	pp.generateDigitCombinations(pattern, "", 0, &results)
-	
+	pp.generateNumberCombinations(parsed.Atoms, "", &results)
+
 	return results
 }
 
-func (pp *PatternProcessor) generateDigitCombinations(pattern, current string, index int, results *[]string) {
-	if index == len(pattern) {
+// generateNumberCombinations appends every value of atoms after current. An
+// atom repeated n to m times takes all its n-fold values before the longer ones.
+func (pp *PatternProcessor) generateNumberCombinations(atoms []config.NumberAtom, current string, results *[]string) {
+	if len(atoms) == 0 {
 		*results = append(*results, current)
 		return
 	}
 
-	if pattern[index] == 'd' {
-		// Replace with digits 0-9
-		for digit := 0; digit <= 9; digit++ {
-			pp.generateDigitCombinations(pattern, current+strconv.Itoa(digit), index+1, results)
-		}
-	} else {
-		// Keep the original character
-		pp.generateDigitCombinations(pattern, current+string(pattern[index]), index+1, results)
+	atom := atoms[0]
+	for repeat := atom.MinRepeat; repeat <= atom.MaxRepeat; repeat++ {
+		pp.generateRepeats(atom.Values, repeat, current, func(value string) {
+			pp.generateNumberCombinations(atoms[1:], value, results)
+		})
 	}
-}
\ No newline at end of file
+}
+
+// generateRepeats calls emit with current followed by every sequence of
+// count values
+func (pp *PatternProcessor) generateRepeats(values []string, count int, current string, emit func(string)) {
+	if count == 0 {
+		emit(current)
+		return
+	}
+
+	for _, value := range values {
+		pp.generateRepeats(values, count-1, current+value, emit)
+	}
+}
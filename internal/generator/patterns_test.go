@@ -2,7 +2,6 @@ package generator
 
 import (
 	"reflect"
-	"sort"
 	"testing"
 
 	"github.com/omarelshopky/craftlist/internal/config"
@@ -29,38 +28,37 @@ func TestProcessPattern(t *testing.T) {
 		{
 			name: "all placeholders replaced",
 			job: PasswordJob{
-				Pattern:    "<CUSTOM><SEP><COMMON><SEP><SSID><SEP><YEAR><SEP><SHORTYEAR><SEP><NUM>",
-				CustomWord: "custom",
-				CommonWord: "common",
-				SSID:       "ssid",
-				Year:       2025,
-				Number:     "123",
-				Separators: []string{"-", "_", "+", "!", "#"},
+				Pattern: "<CUSTOM><SEP><COMMON><SEP><SSID><SEP><YEAR><SEP><SHORTYEAR><SEP><NUM>",
+				Slots: []Slot{{"<CUSTOM>", "custom"}, {"<SEP>", "-"}, {"<COMMON>", "common"}, {"<SEP>", "_"},
+					{"<SSID>", "ssid"}, {"<SEP>", "+"}, {"<YEAR>", "2025"}, {"<SEP>", "!"}, {"<SHORTYEAR>", "25"},
+					{"<SEP>", "#"}, {"<NUM>", "123"}},
 			},
 			expected: "custom-common_ssid+2025!25#123",
 		},
 		{
 			name: "no year and separators",
 			job: PasswordJob{
-				Pattern:    "<CUSTOM><COMMON><SSID><NUM>",
-				CustomWord: "c",
-				CommonWord: "m",
-				SSID:       "s",
-				Number:     "1",
+				Pattern: "<CUSTOM><COMMON><SSID><NUM>",
+				Slots:   []Slot{{"<CUSTOM>", "c"}, {"<COMMON>", "m"}, {"<SSID>", "s"}, {"<NUM>", "1"}},
 			},
 			expected: "cms1",
 		},
 		{
 			name: "more <SEP> than separators provided",
 			job: PasswordJob{
-				Pattern:    "<CUSTOM><SEP><COMMON><SEP><SSID><SEP>",
-				CustomWord: "A",
-				CommonWord: "B",
-				SSID:       "C",
-				Separators: []string{"-"},
+				Pattern: "<CUSTOM><SEP><COMMON><SEP><SSID><SEP>",
+				Slots:   []Slot{{"<CUSTOM>", "A"}, {"<SEP>", "-"}, {"<COMMON>", "B"}, {"<SSID>", "C"}},
 			},
 			expected: "A-B<SEP>C<SEP>",
 		},
+		{
+			name: "repeated placeholders take their own values",
+			job: PasswordJob{
+				Pattern: "<CUSTOM>.<CUSTOM:1>.<CUSTOM=1>",
+				Slots:   []Slot{{"<CUSTOM>", "acme"}, {"<CUSTOM:1>", "corp"}, {"<CUSTOM=1>", "corp"}},
+			},
+			expected: "acme.corp.corp",
+		},
 	}
 
 	for _, tt := range tests {
@@ -96,7 +94,7 @@ func TestGenerateAllNumberPatterns(t *testing.T) {
 	}
 }
 
-func TestExpandDigitPattern(t *testing.T) {
+func TestExpandNumberPattern(t *testing.T) {
 	pp := NewPatternProcessor(config.GeneratorConfig{}, config.PlaceholdersConfig{})
 
 	tests := []struct {
@@ -108,11 +106,21 @@ func TestExpandDigitPattern(t *testing.T) {
 		{"one digit", "d", 10},
 		{"two digits", "dd", 100},
 		{"two digits with only one expandable", "5d", 10},
+		{"escaped d", "\\dd", 10},
+		{"padded range", "[00-31]", 32},
+		{"stepped range", "[0-98/2]", 50},
+		{"range list", "[1,5-7]", 4},
+		{"repetition", "d{1,3}", 1110},
+		{"repeated range", "[1-2]{2}", 4},
+		{"sequential", "@sequential{4}", 16},
+		{"repeated digits", "@repeated{3,4}", 20},
+		{"palindromic", "@palindromic{4}", 100},
+		{"invalid pattern", "[3-1]", 0},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := pp.expandDigitPattern(tt.pattern)
+			got := pp.expandNumberPattern(tt.pattern)
 			if len(got) != tt.expected {
 				t.Errorf("expected %d results, got %d", tt.expected, len(got))
 			}
@@ -120,15 +128,37 @@ func TestExpandDigitPattern(t *testing.T) {
 	}
 }
 
-func TestGenerateDigitCombinations(t *testing.T) {
+func TestGenerateNumberCombinations(t *testing.T) {
 	pp := NewPatternProcessor(config.GeneratorConfig{}, config.PlaceholdersConfig{})
-	results := []string{}
-	pp.generateDigitCombinations("d", "", 0, &results)
 
-	sort.Strings(results)
-	expected := []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"d", []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}},
+		{"\\d[0-9/4]", []string{"d0", "d4", "d8"}},
+		{"[8-10]", []string{"8", "9", "10"}},
+		{"[08-10]", []string{"08", "09", "10"}},
+		{"[1-2]{1,2}!", []string{"1!", "2!", "11!", "12!", "21!", "22!"}},
+		{"@sequential{9}", []string{"012345678", "123456789", "234567890", "876543210", "987654321", "098765432"}},
+		{"@palindromic{3}", []string{"000", "010", "020", "030", "040", "050", "060", "070", "080", "090"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			results := []string{}
+			parsed, err := config.ParseNumberPattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("ParseNumberPattern() returned error: %v", err)
+			}
+			pp.generateNumberCombinations(parsed.Atoms, "", &results)
 
-	if !reflect.DeepEqual(results, expected) {
-		t.Errorf("expected %v, got %v", expected, results)
+			if len(results) > len(tt.expected) {
+				results = results[:len(tt.expected)]
+			}
+			if !reflect.DeepEqual(results, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, results)
+			}
+		})
 	}
 }
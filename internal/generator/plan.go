@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// patternSegment is a contiguous block of the candidate stream: a single
+// pattern restricted to a slice of the word list of each word value. words
+// is indexed by the dims of the compiled pattern and is nil for dims that do
+// not hold a word. Every candidate of a segment shares the same rank score.
+// Segments of a pattern generated per roster user carry the user.
+type patternSegment struct {
+	pattern  string
+	compiled compiledPattern
+	words    [][]string
+	score    float64
+	user     string
+}
+
+// buildSegments lays out the candidate stream. Without ranking there is one
+// segment per pattern in config order, and per user for the patterns
+// generated per roster user; with ranking every pattern is split by word
+// tiers and the segments are ordered by descending score.
+func (g *Generator) buildSegments() []patternSegment {
+	var segments []patternSegment
+
+	for _, pattern := range g.config.Patterns {
+		compiled := g.compilePattern(pattern)
+
+		for _, bound := range g.bindings(compiled) {
+			segments = append(segments, bound.patternSegments(compiled)...)
+		}
+	}
+
+	if g.config.Ranking.Enabled {
+		sort.SliceStable(segments, func(i, j int) bool {
+			return segments[i].score > segments[j].score
+		})
+	}
+
+	return segments
+}
+
+// patternSegments returns the segments of one pattern, or none when the
+// pattern has nothing to generate
+func (g *Generator) patternSegments(compiled compiledPattern) []patternSegment {
+	// Ignore patterns using a word list that has no words, such as SSIDs when none were entered
+	if g.missingWords(compiled) {
+		return nil
+	}
+
+	// Patterns no candidate of which can pass the password policy are not worth enumerating
+	if len(g.policyConflicts(compiled)) > 0 {
+		return nil
+	}
+
+	if g.config.Ranking.Enabled {
+		return g.rankedSegments(compiled)
+	}
+
+	words := make([][]string, len(compiled.dims))
+	for idx, dim := range compiled.dims {
+		if dim.kind == slotWord {
+			words[idx], _ = g.dimWords(compiled, idx)
+		}
+	}
+
+	return []patternSegment{{
+		pattern:  compiled.pattern,
+		compiled: compiled,
+		words:    words,
+		score:    1,
+		user:     g.user,
+	}}
+}
+
+func (g *Generator) compilePattern(pattern string) compiledPattern {
+	return compilePattern(pattern, g.lists, g.placeholders)
+}
+
+// missingWords reports whether a word value of the pattern has no words,
+// either because its list is empty or because its modifiers filter out
+// every variation
+func (g *Generator) missingWords(compiled compiledPattern) bool {
+	for idx, dim := range compiled.dims {
+		if dim.kind != slotWord {
+			continue
+		}
+		if words, _ := g.dimWords(compiled, idx); len(words) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PolicyConflicts describes every pattern that is skipped because none of
+// its candidates can pass the password policy. A pattern generated per
+// roster user may be skipped for some users only.
+func (g *Generator) PolicyConflicts() []string {
+	var conflicts []string
+
+	for _, pattern := range g.config.Patterns {
+		compiled := g.compilePattern(pattern)
+
+		var rules []string
+		var user string
+		filled, skipped := 0, 0
+		for _, bound := range g.bindings(compiled) {
+			if bound.missingWords(compiled) {
+				continue
+			}
+			filled++
+
+			if boundRules := bound.policyConflicts(compiled); len(boundRules) > 0 {
+				if skipped == 0 {
+					rules, user = boundRules, bound.user
+				}
+				skipped++
+			}
+		}
+
+		switch {
+		case skipped == 0:
+		case skipped == filled:
+			conflicts = append(conflicts, fmt.Sprintf("%s can never meet the password policy: it %s", pattern, strings.Join(rules, " and ")))
+		default:
+			conflicts = append(conflicts, fmt.Sprintf("%s can never meet the password policy for %d of %d users, such as %s: it %s",
+				pattern, skipped, filled, user, strings.Join(rules, " and ")))
+		}
+	}
+
+	return conflicts
+}
+
+// policyConflicts returns the rules of the password policy no candidate of
+// the pattern can meet, judged by the character classes of the literal text
+// and of every value the pattern combines
+func (g *Generator) policyConflicts(compiled compiledPattern) []string {
+	if g.policy == nil {
+		return nil
+	}
+
+	ranges := []classRange{g.policy.valuesRange([]string{compiled.literal})}
+	for idx, dim := range compiled.dims {
+		var values []string
+		switch dim.kind {
+		case slotMask:
+			ranges = append(ranges, maskRange(dim.mask))
+			continue
+		case slotWord:
+			values, _ = g.dimWords(compiled, idx)
+		default:
+			values = g.sourceValues(dim)
+		}
+
+		values, prepared := compiled.dimValues(idx, values)
+		if !prepared {
+			values = compiled.writtenValues(idx, values)
+		}
+		ranges = append(ranges, g.policy.valuesRange(values))
+	}
+
+	return g.policy.unmetRules(ranges)
+}
+
+// dimWords returns the words and variation kinds of word value dim, left
+// after the filtering modifiers of the pattern
+func (g *Generator) dimWords(compiled compiledPattern, dim int) ([]string, []VariationKind) {
+	list := compiled.dims[dim].list
+
+	return compiled.filterWords(dim, g.words[list], g.kinds[list])
+}
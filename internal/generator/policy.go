@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// usernameClass marks a value containing a username next to the character
+// class bits, so the Counter can carry it through its distributions
+const usernameClass uint8 = 1 << 4
+
+// classDescriptions names the required classes in policy warnings
+var classDescriptions = map[string]string{
+	config.ClassLower:   "lowercase letter",
+	config.ClassUpper:   "uppercase letter",
+	config.ClassDigit:   "digit",
+	config.ClassSpecial: "special character",
+	config.ClassLetter:  "letter",
+}
+
+// minUsernameLength is the shortest username the policy looks for, as
+// Active Directory ignores shorter account names
+const minUsernameLength = 3
+
+// passwordPolicy checks candidates against a resolved config.PolicyConfig
+type passwordPolicy struct {
+	minLength  int
+	minClasses int
+	required   []uint8
+	classNames []string // of required
+	noUsername bool
+	usernames  []string
+}
+
+// classRange sums up the values of a pattern component for the policy:
+// every class bit some value has, and whether every value contains a
+// username
+type classRange struct {
+	classes  uint8
+	username bool
+}
+
+// newPasswordPolicy returns nil when the policy has no rule
+func newPasswordPolicy(cfg config.PolicyConfig) *passwordPolicy {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	resolved := cfg.Resolve()
+	policy := &passwordPolicy{minLength: resolved.MinLength, minClasses: resolved.MinClasses, noUsername: resolved.NoUsername}
+
+	for _, class := range resolved.Require {
+		policy.required = append(policy.required, config.ClassBits(class))
+		policy.classNames = append(policy.classNames, class)
+	}
+
+	return policy.withNames(resolved.Usernames)
+}
+
+// withNames returns a copy of the policy that also rejects the names, when
+// it rejects usernames
+func (p *passwordPolicy) withNames(names []string) *passwordPolicy {
+	if p == nil || !p.noUsername {
+		return p
+	}
+
+	policy := *p
+	policy.usernames = append([]string{}, p.usernames...)
+	for _, name := range names {
+		if utf8.RuneCountInString(name) >= minUsernameLength {
+			policy.usernames = append(policy.usernames, strings.ToLower(name))
+		}
+	}
+
+	return &policy
+}
+
+// allows reports whether password meets every rule of the policy
+func (p *passwordPolicy) allows(password string) bool {
+	return utf8.RuneCountInString(password) >= p.minLength && p.allowsClasses(p.valueClasses(password))
+}
+
+// allowsClasses reports whether a candidate with the class bits classes
+// meets the class and username rules
+func (p *passwordPolicy) allowsClasses(classes uint8) bool {
+	if classes&usernameClass != 0 {
+		return false
+	}
+
+	if bits.OnesCount8(classes) < p.minClasses {
+		return false
+	}
+
+	for _, required := range p.required {
+		if classes&required == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *passwordPolicy) containsUsername(text string) bool {
+	if len(p.usernames) == 0 {
+		return false
+	}
+
+	lower := strings.ToLower(text)
+	for _, username := range p.usernames {
+		if strings.Contains(lower, username) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// valueClasses returns the class bits of a value, with usernameClass when
+// it contains a username
+func (p *passwordPolicy) valueClasses(value string) uint8 {
+	classes := config.CharClasses(value)
+	if p.containsUsername(value) {
+		classes |= usernameClass
+	}
+
+	return classes
+}
+
+// valuesRange returns the classRange of the values written by a pattern
+// component
+func (p *passwordPolicy) valuesRange(values []string) classRange {
+	valueRange := classRange{username: len(values) > 0}
+	for _, value := range values {
+		classes := p.valueClasses(value)
+		valueRange.classes |= classes
+		valueRange.username = valueRange.username && classes&usernameClass != 0
+	}
+
+	return valueRange
+}
+
+// maskRange returns the classRange of an inline mask
+func maskRange(mask config.Mask) classRange {
+	var maskRange classRange
+	for _, position := range mask.Positions {
+		for _, char := range position.Charset {
+			maskRange.classes |= config.CharClass(char)
+		}
+	}
+
+	return maskRange
+}
+
+// unmetRules lists the rules of the policy that no combination of the
+// components can meet. Classes are judged by what each component can
+// contribute, so a pattern without a digit anywhere never reaches a rule
+// requiring one.
+func (p *passwordPolicy) unmetRules(ranges []classRange) []string {
+	var rules []string
+	var classes uint8
+	username := false
+
+	for _, componentRange := range ranges {
+		classes |= componentRange.classes &^ usernameClass
+		username = username || componentRange.username
+	}
+
+	if username {
+		rules = append(rules, "always contains a username")
+	}
+
+	if count := bits.OnesCount8(classes); count < p.minClasses {
+		rules = append(rules, fmt.Sprintf("has at most %d of the %d required character classes", count, p.minClasses))
+	}
+
+	for idx, required := range p.required {
+		if classes&required == 0 {
+			rules = append(rules, "has no "+classDescriptions[p.classNames[idx]])
+		}
+	}
+
+	return rules
+}
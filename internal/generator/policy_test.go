@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+func TestPasswordPolicyAllows(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   config.PolicyConfig
+		password string
+		allowed  bool
+	}{
+		{"ad three classes", config.PolicyConfig{Preset: config.PolicyADDefault}, "Acme2024", true},
+		{"ad two classes", config.PolicyConfig{Preset: config.PolicyADDefault}, "acme2024", false},
+		{"ad too short", config.PolicyConfig{Preset: config.PolicyADDefault}, "Ac_202", false},
+		{"ad username", config.PolicyConfig{Preset: config.PolicyADDefault, Usernames: []string{"jdoe"}}, "JDoe_2024", false},
+		{"short username ignored", config.PolicyConfig{Preset: config.PolicyADDefault, Usernames: []string{"jd"}}, "JDoe_2024", true},
+		{"pci letter and digit", config.PolicyConfig{Preset: config.PolicyPCI}, "summerofcode1", true},
+		{"pci no digit", config.PolicyConfig{Preset: config.PolicyPCI}, "summerofcodes", false},
+		{"required special", config.PolicyConfig{Require: []string{config.ClassSpecial}}, "acme!", true},
+		{"missing special", config.PolicyConfig{Require: []string{config.ClassSpecial}}, "acme1", false},
+		{"length in characters", config.PolicyConfig{MinLength: 7}, "grün24!", true},
+		{"multibyte too short", config.PolicyConfig{MinLength: 7}, "grüß24", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := newPasswordPolicy(tt.policy).allows(tt.password); allowed != tt.allowed {
+				t.Errorf("allows(%q) = %v, expected %v", tt.password, allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestGenerateHonoursPolicy(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinYear = 2020
+	cfg.MaxYear = 2025
+	cfg.MinPasswordLen = 1
+	cfg.CommonWords = []string{"admin", "wifi"}
+	cfg.Separators = []string{"", "_"}
+	cfg.NumberPatterns = []string{"dd"}
+	cfg.Substitutions = map[string][]string{"a": {"4", "@"}, "e": {"3"}}
+	cfg.Patterns = []string{"<CUSTOM><SEP><YEAR>", "<COMMON|upper><NUM>", "<CUSTOM><MASK:[!a]{1,2}>"}
+	cfg.Dedup.Mode = config.DedupNone
+	cfg.Deterministic = true
+	cfg.Policy = config.PolicyConfig{Preset: config.PolicyADDefault, MinLength: 8, Usernames: []string{"evil"}}
+
+	g := New(cfg, config.NewDefaultPlaceholdersConfig())
+	g.SetCustomWords([]string{"acme", "evil"})
+	if err := g.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(generateToFile(t, g), "\n"), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		t.Fatal("expected some passwords to pass the policy")
+	}
+
+	for _, line := range lines {
+		if !g.policy.allows(line) || len(line) < 8 {
+			t.Errorf("%q does not meet the policy", line)
+		}
+		if strings.Contains(strings.ToLower(line), "evil") {
+			t.Errorf("%q contains the username", line)
+		}
+	}
+
+	counter := g.Counter()
+	total, _ := counter.CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers())
+	if total != len(lines) {
+		t.Errorf("expected the counter to count %d passwords, got %d", len(lines), total)
+	}
+
+	candidates := counter.CountCandidates(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers())
+	sum := 0
+	for _, count := range candidates {
+		sum += count
+	}
+	if sum <= total {
+		t.Errorf("expected more candidates (%d) than passwords passing the policy (%d)", sum, total)
+	}
+}
+
+func TestGeneratePolicyCountsCharacters(t *testing.T) {
+	cfg := newTestConfig([]string{"<CUSTOM><YEAR>", "<CUSTOM><MASK:[ä!]{1,3}>"})
+	cfg.MinYear = 2024
+	cfg.MaxYear = 2024
+	cfg.Variations = config.VariationProfiles{}
+	cfg.Policy = config.PolicyConfig{MinLength: 7}
+
+	g := prepareTestGenerator(t, cfg, map[string][]string{"<CUSTOM>": {"grün", "acme"}})
+
+	// grün!! is seven bytes but only six characters
+	seen := make(map[string]bool)
+	for _, line := range generateLines(t, g) {
+		if utf8.RuneCountInString(line) < 7 {
+			t.Errorf("%q is shorter than the policy minimum", line)
+		}
+		seen[line] = true
+	}
+
+	for _, password := range []string{"grün2024", "grün!ä!", "acmeäää"} {
+		if !seen[password] {
+			t.Errorf("expected %q", password)
+		}
+	}
+	if seen["grün!!"] {
+		t.Error("expected grün!! to be rejected as too short")
+	}
+}
+
+func TestPolicyConflicts(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinPasswordLen = 1
+	cfg.CommonWords = []string{"admin"}
+	cfg.Separators = []string{"", "_"}
+	cfg.Substitutions = map[string][]string{"a": {"4"}}
+	cfg.Patterns = []string{"<CUSTOM>", "<CUSTOM><SEP><YEAR>", "<COMMON|noleet><YEAR>", "<YEAR><MASK:?l!>", "<CUSTOM|upper>1"}
+	cfg.Dedup.Mode = config.DedupNone
+	cfg.Deterministic = true
+	cfg.Policy = config.PolicyConfig{Require: []string{config.ClassDigit, config.ClassSpecial}, NoUsername: true, Usernames: []string{"acme"}}
+
+	g := New(cfg, config.NewDefaultPlaceholdersConfig())
+	g.SetCustomWords([]string{"acme", "evil"})
+	if err := g.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	conflicts := g.PolicyConflicts()
+	expected := []string{
+		"<CUSTOM> can never meet the password policy: it has no special character",
+		"<COMMON|noleet><YEAR> can never meet the password policy: it has no special character",
+		"<CUSTOM|upper>1 can never meet the password policy: it has no special character",
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("expected conflicts %v, got %v", expected, conflicts)
+	}
+
+	ks := buildTestKeyspace(t, g)
+	var patterns []string
+	for _, space := range ks.spaces {
+		patterns = append(patterns, space.pattern)
+	}
+	if !reflect.DeepEqual(patterns, []string{"<CUSTOM><SEP><YEAR>", "<YEAR><MASK:?l!>"}) {
+		t.Errorf("expected the conflicting patterns to be left out of the keyspace, got %v", patterns)
+	}
+
+	_, stats := g.Counter().CountPasswords(g.GetCustomWords(), g.GetCommonWords(), g.GetSSIDs(), g.GetNumbers())
+	for _, conflict := range expected {
+		pattern, _, _ := strings.Cut(conflict, " ")
+		if stats[pattern] != 0 {
+			t.Errorf("expected the counter to skip %s, got %d", pattern, stats[pattern])
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(generateToFile(t, g), "\n"), "\n")
+	total := stats["<CUSTOM><SEP><YEAR>"] + stats["<YEAR><MASK:?l!>"]
+	if total != len(lines) {
+		t.Errorf("expected the counter to count %d passwords, got %d", len(lines), total)
+	}
+}
+
+func TestPolicyConflictsAlwaysUsername(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinPasswordLen = 1
+	cfg.Patterns = []string{"<CUSTOM|noleet>!<YEAR>", "<CUSTOM>!<YEAR>"}
+	cfg.Policy = config.PolicyConfig{Preset: config.PolicyNIST, Usernames: []string{"acme"}}
+
+	g := New(cfg, config.NewDefaultPlaceholdersConfig())
+	g.SetCustomWords([]string{"acme"})
+	if err := g.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	expected := []string{"<CUSTOM|noleet>!<YEAR> can never meet the password policy: it always contains a username"}
+	if conflicts := g.PolicyConflicts(); !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("expected conflicts %v, got %v", expected, conflicts)
+	}
+}
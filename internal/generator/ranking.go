@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"sort"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// VariationKind records which transformations produced a word variation
+type VariationKind int
+
+const (
+	VariationOriginal VariationKind = iota
+	VariationCase
+	VariationLeet
+	VariationCaseLeet
+)
+
+// withLeet returns the kind of a leet substitution applied on top of k
+func (k VariationKind) withLeet() VariationKind {
+	if k == VariationOriginal {
+		return VariationLeet
+	}
+
+	return VariationCaseLeet
+}
+
+// weight returns the likelihood weight of a variation kind. Case and leet
+// weights multiply when both transformations were applied.
+func (k VariationKind) weight(weights config.VariationWeights) float64 {
+	switch k {
+	case VariationCase:
+		return weights.Case
+	case VariationLeet:
+		return weights.Leet
+	case VariationCaseLeet:
+		return weights.Case * weights.Leet
+	default:
+		return weights.Original
+	}
+}
+
+// wordTier is a run of consecutive words sharing the same weight
+type wordTier struct {
+	words  []string
+	weight float64
+}
+
+// sortByWeight reorders words (and their kinds) by descending weight, keeping
+// the generation order among words of equal weight
+func (g *Generator) sortByWeight(words []string, kinds []VariationKind) {
+	weights := g.config.Ranking.VariationWeights
+
+	sort.Stable(&weightedWords{words: words, kinds: kinds, weights: weights})
+}
+
+// wordTiers splits a weight sorted list into tiers of equal weight
+func (g *Generator) wordTiers(words []string, kinds []VariationKind) []wordTier {
+	weights := g.config.Ranking.VariationWeights
+
+	var tiers []wordTier
+	start := 0
+
+	for idx := 1; idx <= len(words); idx++ {
+		if idx < len(words) && kinds[idx].weight(weights) == kinds[start].weight(weights) {
+			continue
+		}
+
+		tiers = append(tiers, wordTier{
+			words:  words[start:idx],
+			weight: kinds[start].weight(weights),
+		})
+		start = idx
+	}
+
+	return tiers
+}
+
+// rankedSegments splits a pattern into one segment per combination of word
+// tiers, scored by the pattern weight times the weight of each tier
+func (g *Generator) rankedSegments(compiled compiledPattern) []patternSegment {
+	patternWeight := 1.0
+	if weight, exists := g.config.Ranking.PatternWeights[compiled.pattern]; exists {
+		patternWeight = weight
+	}
+
+	// Every combination of one tier per word value, starting from the first tiers
+	segments := []patternSegment{{pattern: compiled.pattern, compiled: compiled, score: patternWeight, user: g.user}}
+	for dimIdx, dim := range compiled.dims {
+		tiers := []wordTier{{weight: 1}}
+		if dim.kind == slotWord {
+			tiers = g.wordTiers(g.dimWords(compiled, dimIdx))
+		}
+
+		var next []patternSegment
+		for _, segment := range segments {
+			for _, tier := range tiers {
+				words := append(append([][]string{}, segment.words...), tier.words)
+				next = append(next, patternSegment{pattern: compiled.pattern, compiled: compiled, words: words, score: segment.score * tier.weight, user: g.user})
+			}
+		}
+		segments = next
+	}
+
+	return segments
+}
+
+type weightedWords struct {
+	words   []string
+	kinds   []VariationKind
+	weights config.VariationWeights
+}
+
+func (ww *weightedWords) Len() int {
+	return len(ww.words)
+}
+
+func (ww *weightedWords) Less(i, j int) bool {
+	return ww.kinds[i].weight(ww.weights) > ww.kinds[j].weight(ww.weights)
+}
+
+func (ww *weightedWords) Swap(i, j int) {
+	ww.words[i], ww.words[j] = ww.words[j], ww.words[i]
+	ww.kinds[i], ww.kinds[j] = ww.kinds[j], ww.kinds[i]
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+func TestGetVariationsKinds(t *testing.T) {
+	g := &Generator{
+		variations: NewVariationGenerator(config.GeneratorConfig{
+			Substitutions: map[string][]string{"a": {"4"}},
+		}),
+	}
+
+	words, kinds, err := g.getVariations([]string{"ab"}, config.NewDefaultVariationProfiles().Custom, config.VariationStrategy{})
+	if err != nil {
+		t.Fatalf("getVariations() returned error: %v", err)
+	}
+
+	expected := map[string]VariationKind{
+		"ab": VariationOriginal,
+		"aB": VariationCase,
+		"Ab": VariationCase,
+		"AB": VariationCase,
+		"4b": VariationLeet,
+		"4B": VariationCaseLeet,
+	}
+
+	if len(words) != len(expected) {
+		t.Fatalf("expected %d variations, got %v", len(expected), words)
+	}
+
+	for idx, word := range words {
+		if kinds[idx] != expected[word] {
+			t.Errorf("expected %q to be kind %d, got %d", word, expected[word], kinds[idx])
+		}
+	}
+}
+
+func TestRankedSegments(t *testing.T) {
+	patterns := []string{"<CUSTOM>", "<CUSTOM><SEP><NUM>", "<COMMON><SEP><CUSTOM>"}
+
+	g := newTestGenerator(t, patterns)
+	g.config.Ranking.Enabled = true
+	g.config.Ranking.PatternWeights = map[string]float64{"<CUSTOM><SEP><NUM>": 2}
+	g.sortByWeight(g.words[0], g.kinds[0])
+	g.sortByWeight(g.words[1], g.kinds[1])
+
+	segments := g.buildSegments()
+
+	if segments[0].pattern != "<CUSTOM><SEP><NUM>" {
+		t.Errorf("expected the heaviest pattern first, got %s", segments[0].pattern)
+	}
+
+	for idx := 1; idx < len(segments); idx++ {
+		if segments[idx].score > segments[idx-1].score {
+			t.Fatalf("segment %d has a higher score than the one before it", idx)
+		}
+	}
+
+	for _, word := range segments[0].words[0] {
+		if word != "acme" && word != "evil" {
+			t.Errorf("expected only original words in the top segment, got %q", word)
+		}
+	}
+}
+
+func TestGenerateRankedTop(t *testing.T) {
+	g := newTestGenerator(t, []string{"<CUSTOM>", "<CUSTOM><SEP><NUM>"})
+	g.config.Ranking.Enabled = true
+	g.config.Ranking.Top = 5
+	g.sortByWeight(g.words[0], g.kinds[0])
+
+	lines := strings.Split(strings.TrimSuffix(generateToFile(t, g), "\n"), "\n")
+
+	expected := []string{"acme", "evil", "acme00", "acme_00", "acme-00"}
+	if strings.Join(lines, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, lines)
+	}
+}
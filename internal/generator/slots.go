@@ -0,0 +1,241 @@
+package generator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+type slotKind int
+
+const (
+	slotWord slotKind = iota
+	slotYear
+	slotShortYear
+	slotNumber
+	slotSeparator
+	slotDate
+	slotKeywalk
+	slotMask
+)
+
+// patternSlot is one placeholder occurrence in a pattern, with the
+// modifiers that transform the value it writes
+type patternSlot struct {
+	placeholder string
+	kind        slotKind
+	dim         int
+	modifiers   []config.Modifier
+}
+
+// patternDim is an independent value of a pattern. Every plain placeholder
+// occurrence has its own dim; labelled occurrences (<X:n>) and their
+// back-references (<X=n>) share one, so they repeat the same value.
+type patternDim struct {
+	kind  slotKind // slotYear for both full and short years
+	list  int      // word list index for slotWord, date index for slotDate
+	mask  config.Mask
+	slots []int
+	first int
+}
+
+// compiledPattern splits a pattern into its literal text and placeholder
+// occurrences. dims are in digit order, most significant first: word lists
+// in config.WordLists order, then years, dates, keyboard walks, numbers,
+// separators and inline masks, each in order of first occurrence.
+type compiledPattern struct {
+	pattern       string
+	slots         []patternSlot
+	dims          []patternDim
+	literal       string
+	literalLength int
+}
+
+type placeholderFormat struct {
+	format string
+	kind   slotKind
+	list   int
+}
+
+// compilePattern parses pattern against the word lists and built-in
+// placeholders. A back-reference to a label that was never defined starts
+// a new value, which validation reports before generation gets here.
+func compilePattern(pattern string, lists []config.WordList, placeholders config.PlaceholdersConfig) compiledPattern {
+	formats := []placeholderFormat{
+		{format: placeholders.Year.Format, kind: slotYear},
+		{format: placeholders.ShortYear.Format, kind: slotShortYear},
+		{format: placeholders.Number.Format, kind: slotNumber},
+		{format: placeholders.Separator.Format, kind: slotSeparator},
+		{format: placeholders.Keywalk.Format, kind: slotKeywalk},
+	}
+	for idx, list := range lists {
+		formats = append(formats, placeholderFormat{format: list.Format, kind: slotWord, list: idx})
+	}
+	for idx, format := range dateFormats(placeholders) {
+		formats = append(formats, placeholderFormat{format: format, kind: slotDate, list: idx})
+	}
+
+	compiled := compiledPattern{pattern: pattern}
+	labels := make(map[string]int)
+
+	for pos := 0; pos < len(pattern); {
+		// Every inline mask is a value of its own
+		if mask, text, ok := matchMask(pattern[pos:], placeholders.Mask.Format); ok {
+			compiled.dims = append(compiled.dims, patternDim{kind: slotMask, mask: mask, slots: []int{len(compiled.slots)}, first: len(compiled.slots)})
+			compiled.slots = append(compiled.slots, patternSlot{placeholder: text, kind: slotMask, dim: len(compiled.dims) - 1})
+			pos += len(text)
+			continue
+		}
+
+		format, text, ref, ok := matchPlaceholder(pattern[pos:], formats)
+		if !ok {
+			compiled.literal += pattern[pos : pos+1]
+			if utf8.RuneStart(pattern[pos]) {
+				compiled.literalLength++
+			}
+			pos++
+			continue
+		}
+
+		kind := format.kind
+		dimKind := kind
+		if dimKind == slotShortYear {
+			dimKind = slotYear
+		}
+
+		dim := -1
+		labelKey := ""
+		if ref.Label != "" {
+			// Full and short years share labels, so <SHORTYEAR=1> can follow <YEAR:1>
+			labelKey = strconv.Itoa(int(dimKind)) + "/" + strconv.Itoa(format.list) + "/" + ref.Label
+			if existing, exists := labels[labelKey]; exists {
+				dim = existing
+			}
+		}
+
+		if dim < 0 {
+			dim = len(compiled.dims)
+			compiled.dims = append(compiled.dims, patternDim{kind: dimKind, list: format.list, first: len(compiled.slots)})
+			if labelKey != "" {
+				labels[labelKey] = dim
+			}
+		}
+
+		compiled.dims[dim].slots = append(compiled.dims[dim].slots, len(compiled.slots))
+		compiled.slots = append(compiled.slots, patternSlot{placeholder: text, kind: kind, dim: dim, modifiers: ref.ParseModifiers()})
+		pos += len(text)
+	}
+
+	compiled.sortDims(len(lists))
+
+	return compiled
+}
+
+// matchPlaceholder matches a placeholder at the start of text, either
+// exactly or with a :n label, an =n back-reference or |modifiers before its
+// closing >
+func matchPlaceholder(text string, formats []placeholderFormat) (placeholderFormat, string, config.PlaceholderRef, bool) {
+	for _, format := range formats {
+		if format.format != "" && strings.HasPrefix(text, format.format) {
+			return format, format.format, config.PlaceholderRef{Format: format.format}, true
+		}
+	}
+
+	end := strings.IndexByte(text, '>')
+	if !strings.HasPrefix(text, "<") || end < 0 {
+		return placeholderFormat{}, "", config.PlaceholderRef{}, false
+	}
+
+	ref, ok := config.ParsePlaceholderRef(text[:end+1])
+	if !ok {
+		return placeholderFormat{}, "", config.PlaceholderRef{}, false
+	}
+
+	for _, format := range formats {
+		if format.format == ref.Format {
+			return format, text[:end+1], ref, true
+		}
+	}
+
+	return placeholderFormat{}, "", config.PlaceholderRef{}, false
+}
+
+// matchMask matches an inline mask such as <MASK:?d?d> at the start of text
+func matchMask(text, format string) (config.Mask, string, bool) {
+	end := strings.IndexByte(text, '>')
+	if end < 0 {
+		return config.Mask{}, "", false
+	}
+
+	maskText, ok := config.MaskText(text[:end+1], format)
+	if !ok {
+		return config.Mask{}, "", false
+	}
+
+	mask, err := config.ParseMask(maskText)
+	if err != nil {
+		return config.Mask{}, "", false
+	}
+
+	return mask, text[:end+1], true
+}
+
+// sortDims puts the dims in digit order and renumbers the slots to match
+func (cp *compiledPattern) sortDims(lists int) {
+	rank := func(dim patternDim) int {
+		switch dim.kind {
+		case slotWord:
+			return dim.list
+		case slotYear:
+			return lists
+		case slotDate:
+			return lists + 1
+		case slotKeywalk:
+			return lists + 2
+		case slotNumber:
+			return lists + 3
+		case slotSeparator:
+			return lists + 4
+		default:
+			return lists + 5
+		}
+	}
+
+	order := make([]int, len(cp.dims))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := cp.dims[order[i]], cp.dims[order[j]]
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return a.first < b.first
+	})
+
+	renumbered := make([]int, len(cp.dims))
+	sorted := make([]patternDim, len(cp.dims))
+	for newIdx, oldIdx := range order {
+		renumbered[oldIdx] = newIdx
+		sorted[newIdx] = cp.dims[oldIdx]
+	}
+
+	for idx := range cp.slots {
+		cp.slots[idx].dim = renumbered[cp.slots[idx].dim]
+	}
+	cp.dims = sorted
+}
+
+// usesList reports whether the pattern has a placeholder of word list list
+func (cp *compiledPattern) usesList(list int) bool {
+	for _, dim := range cp.dims {
+		if dim.kind == slotWord && dim.list == list {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,371 @@
+package generator
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+)
+
+// comboSeparator joins the username and the password of a combo candidate
+// until the output formats them. Account names cannot contain it.
+const comboSeparator = ":"
+
+// maxOpenWriters caps the files a spray output keeps open at once. Per-user
+// output opens a file for every roster user, so the least recently written
+// one is closed to make room and reopened for appending when needed.
+const maxOpenWriters = 64
+
+// Schedule describes the rounds of a schedule output. Every round gives each
+// user at most AttemptsPerWindow attempts, and starts a lockout window after
+// the previous one.
+type Schedule struct {
+	AttemptsPerWindow int             `json:"attempts_per_window"`
+	LockoutWindow     string          `json:"lockout_window,omitempty"`
+	Rounds            []ScheduleRound `json:"rounds"`
+}
+
+// ScheduleRound is a run of consecutive lines of a schedule output.
+// StartAfter is the time since the first round began.
+type ScheduleRound struct {
+	Round      int    `json:"round"`
+	FirstLine  int64  `json:"first_line"`
+	Lines      int64  `json:"lines"`
+	StartAfter string `json:"start_after,omitempty"`
+}
+
+// sprayWriter writes combo candidates in one of the spray formats, capping
+// every user to the attempts of the configured lockout windows. Candidates
+// arrive in generation order, so each user keeps their first ones.
+type sprayWriter struct {
+	manager    *OutputManager
+	config     config.OutputConfig
+	compressor *Compressor
+	limit      int
+	attempts   map[string]int
+	reached    int
+	capped     int
+	out        PasswordWriter    // combo
+	files      *writerCache      // per-user and schedule
+	userPaths  map[string]string // per-user, by user
+	paths      map[string]bool
+	rounds     []int64 // schedule, lines per round
+}
+
+func newSprayWriter(manager *OutputManager, cfg config.OutputConfig, compressor *Compressor) (*sprayWriter, error) {
+	sw := &sprayWriter{
+		manager:    manager,
+		config:     cfg,
+		compressor: compressor,
+		attempts:   make(map[string]int),
+		files:      newWriterCache(manager, maxOpenWriters),
+		userPaths:  make(map[string]string),
+		paths:      make(map[string]bool),
+	}
+	if cfg.Attempts > 0 && cfg.Windows > 0 {
+		sw.limit = cfg.Attempts * cfg.Windows
+	}
+
+	if cfg.Format == config.OutputCombo {
+		out, err := manager.openWriter(cfg, compressor)
+		if err != nil {
+			return nil, err
+		}
+		sw.out = out
+	}
+
+	return sw, nil
+}
+
+func (sw *sprayWriter) WritePassword(line string) error {
+	user, password, _ := strings.Cut(line, comboSeparator)
+
+	attempt := sw.attempts[user]
+	if sw.limit > 0 && attempt >= sw.limit {
+		sw.capped++
+		return nil
+	}
+	sw.attempts[user]++
+	if sw.attempts[user] == sw.limit {
+		sw.reached++
+	}
+
+	switch sw.config.Format {
+	case config.OutputPerUser:
+		writer, err := sw.userWriter(user)
+		if err != nil {
+			return err
+		}
+		return writer.WritePassword(password)
+	case config.OutputSchedule:
+		round := attempt / sw.config.Attempts
+		writer, err := sw.roundWriter(round)
+		if err != nil {
+			return err
+		}
+		sw.rounds[round]++
+		return writer.WritePassword(sw.combo(user, password))
+	}
+
+	return sw.out.WritePassword(sw.combo(user, password))
+}
+
+// full reports whether every one of users reached the cap, after which no
+// candidate can be written anymore
+func (sw *sprayWriter) full(users int) bool {
+	return sw.limit > 0 && sw.reached >= users
+}
+
+// Capped returns how many candidates were dropped by the per-user cap
+func (sw *sprayWriter) Capped() int {
+	return sw.capped
+}
+
+// combo joins the escaped username and password with the delimiter
+func (sw *sprayWriter) combo(user, password string) string {
+	return sw.escape(user) + sw.config.Delimiter + sw.escape(password)
+}
+
+// escape makes a field safe to split on the delimiter: backslash prefixes
+// backslashes and delimiters, csv quotes fields that need it
+func (sw *sprayWriter) escape(field string) string {
+	delimiter := sw.config.Delimiter
+
+	switch sw.config.Escape {
+	case config.EscapeBackslash:
+		field = strings.ReplaceAll(field, `\`, `\\`)
+		return strings.ReplaceAll(field, delimiter, `\`+delimiter)
+	case config.EscapeCSV:
+		if strings.Contains(field, delimiter) || strings.ContainsAny(field, "\"\r\n") || strings.TrimSpace(field) != field {
+			return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+	}
+
+	return field
+}
+
+// userWriter returns the password file of user, named after the output
+// file with the username as suffix
+func (sw *sprayWriter) userWriter(user string) (*OutputWriter, error) {
+	path, exists := sw.userPaths[user]
+	if !exists {
+		path = SuffixFilename(sw.config.Filename, fileSafe(user))
+		for suffix := 2; sw.paths[path]; suffix++ {
+			path = SuffixFilename(sw.config.Filename, fmt.Sprintf("%s-%d", fileSafe(user), suffix))
+		}
+		sw.paths[path] = true
+		sw.userPaths[user] = path
+	}
+
+	return sw.files.get(path, sw.compressor)
+}
+
+// roundWriter returns the temporary file of a schedule round, which Close
+// joins into the output
+func (sw *sprayWriter) roundWriter(round int) (*OutputWriter, error) {
+	for len(sw.rounds) <= round {
+		sw.rounds = append(sw.rounds, 0)
+	}
+
+	return sw.files.get(sw.roundPath(round), nil)
+}
+
+func (sw *sprayWriter) roundPath(round int) string {
+	return fmt.Sprintf("%s.round-%03d.tmp", sw.config.Filename, round+1)
+}
+
+func (sw *sprayWriter) Flush() error {
+	if sw.out != nil {
+		return sw.out.Flush()
+	}
+
+	return sw.files.flush()
+}
+
+func (sw *sprayWriter) Close() error {
+	switch sw.config.Format {
+	case config.OutputPerUser:
+		return sw.files.close()
+	case config.OutputSchedule:
+		return sw.writeSchedule()
+	}
+
+	return sw.out.Close()
+}
+
+// writeSchedule joins the rounds into the output in order and describes
+// them in the schedule file
+func (sw *sprayWriter) writeSchedule() error {
+	defer func() {
+		for round := range sw.rounds {
+			os.Remove(sw.roundPath(round))
+		}
+	}()
+
+	if err := sw.files.close(); err != nil {
+		return fmt.Errorf("failed to close schedule round: %w", err)
+	}
+
+	out, err := sw.manager.openWriter(sw.config, sw.compressor)
+	if err != nil {
+		return err
+	}
+
+	schedule := Schedule{AttemptsPerWindow: sw.config.Attempts}
+	if sw.config.LockoutWindow > 0 {
+		schedule.LockoutWindow = sw.config.LockoutWindow.String()
+	}
+
+	line := int64(1)
+	for round, lines := range sw.rounds {
+		if err := copyLines(out, sw.roundPath(round)); err != nil {
+			out.Close()
+			return err
+		}
+
+		scheduleRound := ScheduleRound{Round: round + 1, FirstLine: line, Lines: lines}
+		if sw.config.LockoutWindow > 0 {
+			scheduleRound.StartAfter = (time.Duration(round) * sw.config.LockoutWindow).String()
+		}
+		schedule.Rounds = append(schedule.Rounds, scheduleRound)
+		line += lines
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(schedule, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule: %w", err)
+	}
+
+	if err := os.WriteFile(ScheduleFilename(sw.config.Filename), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write schedule: %w", err)
+	}
+
+	return nil
+}
+
+// writerCache keeps at most limit output files open, closing the least
+// recently used one to open another. A file closed that way is reopened for
+// appending; compressed files then continue in a new gzip member or zstd
+// frame, which decompressors read as one stream.
+type writerCache struct {
+	manager *OutputManager
+	limit   int
+	open    map[string]*list.Element
+	recent  *list.List // of *cachedWriter, most recently used first
+	created map[string]bool
+}
+
+type cachedWriter struct {
+	path   string
+	writer *OutputWriter
+}
+
+func newWriterCache(manager *OutputManager, limit int) *writerCache {
+	return &writerCache{
+		manager: manager,
+		limit:   limit,
+		open:    make(map[string]*list.Element),
+		recent:  list.New(),
+		created: make(map[string]bool),
+	}
+}
+
+// get returns the open writer of path, creating the file on first use
+func (wc *writerCache) get(path string, compressor *Compressor) (*OutputWriter, error) {
+	if element, exists := wc.open[path]; exists {
+		wc.recent.MoveToFront(element)
+		return element.Value.(*cachedWriter).writer, nil
+	}
+
+	if wc.recent.Len() >= wc.limit {
+		oldest := wc.recent.Remove(wc.recent.Back()).(*cachedWriter)
+		delete(wc.open, oldest.path)
+		if err := oldest.writer.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	var writer *OutputWriter
+	var err error
+	if wc.created[path] {
+		writer, err = wc.manager.appendFileWriter(path, compressor)
+	} else {
+		writer, err = wc.manager.createFileWriter(path, compressor)
+	}
+	if err != nil {
+		return nil, err
+	}
+	wc.created[path] = true
+	wc.open[path] = wc.recent.PushFront(&cachedWriter{path: path, writer: writer})
+
+	return writer, nil
+}
+
+func (wc *writerCache) flush() error {
+	for element := wc.recent.Front(); element != nil; element = element.Next() {
+		if err := element.Value.(*cachedWriter).writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close closes every open writer and returns the first error
+func (wc *writerCache) close() error {
+	var err error
+	for element := wc.recent.Front(); element != nil; element = element.Next() {
+		if closeErr := element.Value.(*cachedWriter).writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	wc.open = make(map[string]*list.Element)
+	wc.recent.Init()
+
+	return err
+}
+
+func copyLines(out PasswordWriter, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read schedule round: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if err := out.WritePassword(scanner.Text()); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ScheduleFilename returns where the rounds of a schedule output are described
+func ScheduleFilename(filename string) string {
+	base, _ := splitOutputFilename(filename)
+
+	return base + ".schedule.json"
+}
+
+// fileSafe replaces the characters of a username that do not belong in a
+// file name
+func fileSafe(user string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, user)
+}
@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/ui"
+)
+
+var sprayCombos = []string{"jdoe:Acme2024", "jdoe:acme;2024", "asmith:Acme2024", "jdoe:Acme2025", "asmith:a\"b c", "jdoe:acme!"}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+
+	return string(data)
+}
+
+func TestSprayWriterCombo(t *testing.T) {
+	tests := []struct {
+		name     string
+		escape   string
+		expected string
+	}{
+		{"none", config.EscapeNone, "jdoe;Acme2024\njdoe;acme;2024\nasmith;Acme2024\nasmith;a\"b c\n"},
+		{"backslash", config.EscapeBackslash, "jdoe;Acme2024\njdoe;acme\\;2024\nasmith;Acme2024\nasmith;a\"b c\n"},
+		{"csv", config.EscapeCSV, "jdoe;Acme2024\njdoe;\"acme;2024\"\nasmith;Acme2024\nasmith;\"a\"\"b c\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "combos.txt")
+			writePasswords(t, config.OutputConfig{
+				Filename:  path,
+				Format:    config.OutputCombo,
+				Delimiter: ";",
+				Escape:    tt.escape,
+				Attempts:  2,
+				Windows:   1,
+			}, sprayCombos)
+
+			if got := readFile(t, path); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSprayWriterPerUser(t *testing.T) {
+	dir := t.TempDir()
+	writePasswords(t, config.OutputConfig{
+		Filename: filepath.Join(dir, "passwords.txt"),
+		Format:   config.OutputPerUser,
+		Attempts: 3,
+		Windows:  1,
+	}, sprayCombos)
+
+	expected := map[string]string{
+		"passwords-jdoe.txt":   "Acme2024\nacme;2024\nAcme2025\n",
+		"passwords-asmith.txt": "Acme2024\na\"b c\n",
+	}
+	for file, content := range expected {
+		if got := readFile(t, filepath.Join(dir, file)); got != content {
+			t.Errorf("expected %s to hold %q, got %q", file, content, got)
+		}
+	}
+}
+
+func TestSprayWriterPerUserReopensEvictedFiles(t *testing.T) {
+	users := []string{"u1", "u2", "u3", "u4", "u5"}
+
+	for _, extension := range []string{".txt", ".txt.gz"} {
+		t.Run(extension, func(t *testing.T) {
+			dir := t.TempDir()
+			output := config.OutputConfig{Filename: filepath.Join(dir, "passwords"+extension), Format: config.OutputPerUser}
+			writer, err := NewOutputManager().CreateWriter(output)
+			if err != nil {
+				t.Fatalf("CreateWriter() returned error: %v", err)
+			}
+			spray := writer.(*sprayWriter)
+			spray.files.limit = 2
+
+			// Every user's file is closed and reopened between two of their passwords
+			for _, password := range []string{"first", "second", "third"} {
+				for _, user := range users {
+					if err := spray.WritePassword(user + comboSeparator + password); err != nil {
+						t.Fatalf("WritePassword() returned error: %v", err)
+					}
+				}
+				if open := spray.files.recent.Len(); open > 2 {
+					t.Errorf("expected at most 2 open files, got %d", open)
+				}
+			}
+			if err := spray.Close(); err != nil {
+				t.Fatalf("Close() returned error: %v", err)
+			}
+
+			for _, user := range users {
+				path := SuffixFilename(output.Filename, user)
+				got := ""
+				if extension == ".txt.gz" {
+					got = readGzip(t, path)
+				} else {
+					got = readFile(t, path)
+				}
+				if expected := "first\nsecond\nthird\n"; got != expected {
+					t.Errorf("expected %s to hold %q, got %q", path, expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSprayWriterSchedule(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "spray.txt")
+	writePasswords(t, config.OutputConfig{
+		Filename:      path,
+		Format:        config.OutputSchedule,
+		Delimiter:     ":",
+		Escape:        config.EscapeNone,
+		Attempts:      2,
+		LockoutWindow: 30 * time.Minute,
+	}, sprayCombos)
+
+	expected := "jdoe:Acme2024\njdoe:acme;2024\nasmith:Acme2024\nasmith:a\"b c\njdoe:Acme2025\njdoe:acme!\n"
+	if got := readFile(t, path); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	var schedule Schedule
+	if err := json.Unmarshal([]byte(readFile(t, filepath.Join(dir, "spray.schedule.json"))), &schedule); err != nil {
+		t.Fatalf("failed to parse schedule: %v", err)
+	}
+
+	expectedSchedule := Schedule{
+		AttemptsPerWindow: 2,
+		LockoutWindow:     "30m0s",
+		Rounds: []ScheduleRound{
+			{Round: 1, FirstLine: 1, Lines: 4, StartAfter: "0s"},
+			{Round: 2, FirstLine: 5, Lines: 2, StartAfter: "30m0s"},
+		},
+	}
+	if !reflect.DeepEqual(schedule, expectedSchedule) {
+		t.Errorf("expected schedule %+v, got %+v", expectedSchedule, schedule)
+	}
+
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftovers) > 0 {
+		t.Errorf("expected the round files to be removed, found %v", leftovers)
+	}
+}
+
+func TestGenerateSprayCapsEveryUser(t *testing.T) {
+	g := newRosterGenerator(t, []string{"<FIRST><YEAR>", "<CUSTOM|noleet><YEAR>"}, func(cfg *config.GeneratorConfig) {
+		cfg.Roster.Combo = true
+		cfg.Ranking.Enabled = true
+	})
+
+	path := filepath.Join(t.TempDir(), "combos.txt")
+	output := config.OutputConfig{Filename: path, Format: config.OutputCombo, Delimiter: ":", Escape: config.EscapeNone, Attempts: 3, Windows: 1}
+	if err := g.Generate(context.Background(), output, ui.NewPrinter()); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+
+	// Ranked, the names and custom words as written go before their case variations
+	expected := "jdoe:John2024\njdoe:John2025\nasmith:Alice2024\nasmith:Alice2025\njdoe:acme2024\nasmith:acme2024\n"
+	if got := readFile(t, path); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
@@ -1,7 +1,9 @@
 package generator
 
 import (
+	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/omarelshopky/craftlist/internal/config"
 )
@@ -72,48 +74,160 @@ func (vg *VariationGenerator) GenerateCaseVariations(word string) []string {
 	return vg.deduplicate(variations)
 }
 
+// GenerateStrategyCaseVariations returns the word as written plus the case
+// styles the strategy selects, instead of every upper/lower combination
+func (vg *VariationGenerator) GenerateStrategyCaseVariations(word string, strategy config.VariationStrategy) []string {
+	if len(word) == 0 {
+		return []string{}
+	}
+
+	variations := []string{word}
+	for _, style := range strategy.Case {
+		switch style {
+		case config.CaseAll:
+			return vg.GenerateCaseVariations(word)
+		case config.CaseLower:
+			variations = append(variations, strings.ToLower(word))
+		case config.CaseUpper:
+			variations = append(variations, strings.ToUpper(word))
+		case config.CaseTitle:
+			variations = append(variations, titleCase(word))
+		case config.CaseCamel:
+			variations = append(variations, camelCase(word))
+		case config.CaseToggleFirst:
+			runes := []rune(word)
+			runes[0] = toggleRune(runes[0])
+			variations = append(variations, string(runes))
+		case config.CaseInverted:
+			variations = append(variations, strings.Map(toggleRune, word))
+		}
+	}
+
+	return vg.deduplicate(variations)
+}
+
+// CaseVariations applies the strategy's case styles, or every upper/lower
+// combination when the strategy does not select any
+func (vg *VariationGenerator) CaseVariations(word string, strategy config.VariationStrategy) []string {
+	if len(strategy.Case) == 0 {
+		return vg.GenerateCaseVariations(word)
+	}
+
+	return vg.GenerateStrategyCaseVariations(word, strategy)
+}
+
 func (vg *VariationGenerator) ApplyAllSubstitutions(word string) []string {
-	variations := make(map[string]struct{})
-	variations[word] = struct{}{} // Original word
+	return vg.ApplySubstitutions(word, config.VariationStrategy{})
+}
+
+// ApplySubstitutions substitutes at most strategy.MaxSubstitutions characters,
+// and only within the last strategy.SubstituteLast characters when it is set
+func (vg *VariationGenerator) ApplySubstitutions(word string, strategy config.VariationStrategy) []string {
+	variations := []string{word} // Original word
+
+	maxSubstitutions := strategy.MaxSubstitutions
+	if maxSubstitutions == 0 {
+		maxSubstitutions = len(word)
+	}
+
+	firstSubstituted := 0
+	if strategy.SubstituteLast > 0 {
+		firstSubstituted = max(0, len(word)-strategy.SubstituteLast)
+	}
 
 	// Get all possible substitution combinations
-	vg.generateSubstitutionCombinations(word, "", 0, variations)
+	vg.generateSubstitutionCombinations(word, "", 0, maxSubstitutions, firstSubstituted, &variations)
 
-	return vg.ConvertSetToSlice(variations)
+	return vg.deduplicate(variations)
 }
 
-func (vg *VariationGenerator) generateSubstitutionCombinations(original, current string, index int, variations map[string]struct{}) {
+func (vg *VariationGenerator) generateSubstitutionCombinations(original, current string, index, remaining, firstSubstituted int, variations *[]string) {
 	if index == len(original) {
-		variations[current] = struct{}{}
+		*variations = append(*variations, current)
 		return
 	}
 
 	char := string(original[index])
 
 	// Option 1: Keep original character
-	vg.generateSubstitutionCombinations(original, current+char, index+1, variations)
+	vg.generateSubstitutionCombinations(original, current+char, index+1, remaining, firstSubstituted, variations)
+
+	if remaining == 0 || index < firstSubstituted {
+		return
+	}
 
 	// Option 2: Apply substitutions if available
 	if substitutes, exists := vg.config.Substitutions[char]; exists {
 		for _, substitute := range substitutes {
-			vg.generateSubstitutionCombinations(original, current+substitute, index+1, variations)
+			vg.generateSubstitutionCombinations(original, current+substitute, index+1, remaining-1, firstSubstituted, variations)
 		}
 	}
 }
 
+// ConvertSetToSlice returns the set members in sorted order so the result is
+// identical across runs regardless of map iteration order.
 func (vg *VariationGenerator) ConvertSetToSlice(set map[string]struct{}) []string {
 	result := make([]string, 0, len(set))
 	for value := range set {
 		result = append(result, value)
 	}
+	sort.Strings(result)
 
 	return result
 }
 
+// deduplicate drops repeated values while keeping the first occurrence order
 func (vg *VariationGenerator) deduplicate(slice []string) []string {
-	set := make(map[string]struct{})
+	set := make(map[string]struct{}, len(slice))
+	result := make([]string, 0, len(slice))
+
 	for _, value := range slice {
+		if _, exists := set[value]; exists {
+			continue
+		}
 		set[value] = struct{}{}
+		result = append(result, value)
+	}
+
+	return result
+}
+
+// wordBoundary reports whether r separates the words of a multi-word value
+func wordBoundary(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// titleCase upper-cases the first letter of every word and lower-cases the rest
+func titleCase(word string) string {
+	runes := []rune(strings.ToLower(word))
+	for idx := range runes {
+		if idx == 0 || wordBoundary(runes[idx-1]) {
+			runes[idx] = unicode.ToUpper(runes[idx])
+		}
 	}
-	return vg.ConvertSetToSlice(set)
+
+	return string(runes)
+}
+
+// camelCase joins the words with the first one lower-cased and the rest title-cased
+func camelCase(word string) string {
+	parts := strings.FieldsFunc(word, wordBoundary)
+	if len(parts) == 0 {
+		return word
+	}
+
+	result := strings.ToLower(parts[0])
+	for _, part := range parts[1:] {
+		result += titleCase(part)
+	}
+
+	return result
+}
+
+func toggleRune(r rune) rune {
+	if unicode.IsUpper(r) {
+		return unicode.ToLower(r)
+	}
+
+	return unicode.ToUpper(r)
 }
\ No newline at end of file
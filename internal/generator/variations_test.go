@@ -128,6 +128,103 @@ func TestApplyAllSubstitutions(t *testing.T) {
 	}
 }
 
+func TestGenerateStrategyCaseVariations(t *testing.T) {
+	vg := NewVariationGenerator(config.GeneratorConfig{})
+
+	tests := []struct {
+		name     string
+		input    string
+		cases    []string
+		expected []string
+	}{
+		{
+			name:     "lower and upper",
+			input:    "Acme",
+			cases:    []string{config.CaseLower, config.CaseUpper},
+			expected: []string{"Acme", "acme", "ACME"},
+		},
+		{
+			name:     "title and camel",
+			input:    "evil corp_labs",
+			cases:    []string{config.CaseTitle, config.CaseCamel},
+			expected: []string{"evil corp_labs", "Evil Corp_Labs", "evilCorpLabs"},
+		},
+		{
+			name:     "toggle first and inverted",
+			input:    "Acme",
+			cases:    []string{config.CaseToggleFirst, config.CaseInverted},
+			expected: []string{"Acme", "acme", "aCME"},
+		},
+		{
+			name:     "all combinations",
+			input:    "ab",
+			cases:    []string{config.CaseLower, config.CaseAll},
+			expected: []string{"ab", "aB", "Ab", "AB"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := vg.GenerateStrategyCaseVariations(tt.input, config.VariationStrategy{Case: tt.cases})
+			sort.Strings(got)
+			sort.Strings(tt.expected)
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplySubstitutionsWithStrategy(t *testing.T) {
+	cfg := config.GeneratorConfig{
+		Substitutions: map[string][]string{
+			"a": {"4"},
+			"e": {"3"},
+			"s": {"5"},
+		},
+	}
+	vg := NewVariationGenerator(cfg)
+
+	tests := []struct {
+		name     string
+		input    string
+		strategy config.VariationStrategy
+		expected []string
+	}{
+		{
+			name:     "one substitution per word",
+			input:    "aes",
+			strategy: config.VariationStrategy{MaxSubstitutions: 1},
+			expected: []string{"aes", "4es", "a3s", "ae5"},
+		},
+		{
+			name:     "only the last two characters",
+			input:    "aes",
+			strategy: config.VariationStrategy{SubstituteLast: 2},
+			expected: []string{"aes", "a3s", "ae5", "a35"},
+		},
+		{
+			name:     "both limits",
+			input:    "aes",
+			strategy: config.VariationStrategy{MaxSubstitutions: 1, SubstituteLast: 1},
+			expected: []string{"aes", "ae5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := vg.ApplySubstitutions(tt.input, tt.strategy)
+			sort.Strings(got)
+			sort.Strings(tt.expected)
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestConvertSetToSlice(t *testing.T) {
 	vg := NewVariationGenerator(config.GeneratorConfig{})
 
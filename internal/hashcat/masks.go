@@ -0,0 +1,558 @@
+package hashcat
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/generator"
+	"github.com/omarelshopky/craftlist/internal/interfaces"
+)
+
+// MaskExporter compiles patterns into hashcat mask and hybrid attacks, so
+// digit ranges and separators are enumerated by the cracker instead of on disk
+type MaskExporter struct {
+	config       config.GeneratorConfig
+	placeholders config.PlaceholdersConfig
+	dates        map[string][]string
+	keywalks     []string
+}
+
+// maskPart is one alternative for a piece of a pattern, already escaped for
+// a .hcmask line, together with the number of characters it produces
+type maskPart struct {
+	mask   string
+	length int
+}
+
+// patternToken is a placeholder or a run of literal text. label is set when
+// the placeholder is numbered (<X:n> or <X=n>) and names the value it shares,
+// modified when it carries modifiers (<X|upper>). mask holds the mask of an
+// inline mask placeholder.
+type patternToken struct {
+	placeholder string
+	literal     string
+	label       string
+	modified    bool
+	mask        config.Mask
+}
+
+func NewMaskExporter(cfg config.GeneratorConfig, placeholders config.PlaceholdersConfig) *MaskExporter {
+	return &MaskExporter{
+		config:       cfg,
+		placeholders: placeholders,
+		dates:        generator.DateValues(cfg, placeholders),
+		keywalks:     generator.KeywalkValues(cfg),
+	}
+}
+
+// SetKeywalks replaces the walks on the built-in layout, when the layout
+// comes from a file
+func (me *MaskExporter) SetKeywalks(walks []string) {
+	me.keywalks = walks
+}
+
+// Export writes one .hcmask file per pattern that a mask or hybrid attack can
+// express, the word lists those attacks need, and the full expansion of
+// every other pattern
+func (me *MaskExporter) Export(ctx context.Context, dir string, words map[string][]string, printer interfaces.Printer) (*Report, error) {
+	report := &Report{}
+
+	gen := me.newGenerator(me.config, words)
+	if err := gen.PrepareVariations(); err != nil {
+		return nil, err
+	}
+
+	lists := make(map[string]*wordList)
+	for _, list := range config.WordLists(me.config, me.placeholders) {
+		lists[list.Format] = &wordList{name: list.Name, words: gen.GetWords(list.Format)}
+	}
+	written := make(map[string]string)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	var fallback []string
+	for idx, pattern := range me.config.Patterns {
+		tokens := me.tokenize(pattern)
+
+		wordIdx, wordCount := -1, 0
+		for pos, token := range tokens {
+			if _, isWord := lists[token.placeholder]; isWord {
+				wordIdx = pos
+				wordCount++
+			}
+		}
+
+		if wordCount > 0 && len(lists[tokens[wordIdx].placeholder].words) == 0 {
+			continue
+		}
+
+		var reason string
+		switch {
+		case wordCount > 1:
+			reason = "combines several words"
+		case wordCount == 1 && wordIdx > 0 && wordIdx < len(tokens)-1:
+			reason = "has text on both sides of its word"
+		case sharesValues(tokens):
+			reason = "repeats a numbered value"
+		case slices.ContainsFunc(tokens, func(token patternToken) bool { return token.modified }):
+			reason = "modifies a placeholder"
+		}
+
+		if reason != "" {
+			fallback = append(fallback, pattern)
+			report.Mismatches = append(report.Mismatches, fmt.Sprintf("pattern %s %s, which masks cannot express, and is fully expanded", pattern, reason))
+			continue
+		}
+
+		if wordCount == 0 {
+			path := filepath.Join(dir, fmt.Sprintf("pattern-%02d.hcmask", idx+1))
+			masks := me.compileMasks(tokens, true)
+			if len(masks) == 0 {
+				continue
+			}
+			if err := writeLines(path, masks); err != nil {
+				return nil, err
+			}
+
+			report.Files = append(report.Files, path)
+			report.Commands = append(report.Commands, fmt.Sprintf("hashcat -a 3 -m <mode> <hashes> %s", path))
+			continue
+		}
+
+		listPath, err := me.writeWordList(dir, lists[tokens[wordIdx].placeholder], written, report)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(tokens) == 1 {
+			report.Commands = append(report.Commands, fmt.Sprintf("hashcat -a 0 -m <mode> <hashes> %s", listPath))
+			continue
+		}
+
+		// A word at the start gets the mask appended (-a 6), at the end prepended (-a 7)
+		mode, maskTokens := 6, tokens[1:]
+		if wordIdx > 0 {
+			mode, maskTokens = 7, tokens[:wordIdx]
+		}
+
+		masks := me.compileMasks(maskTokens, false)
+		if me.canBeEmpty(maskTokens) {
+			report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+				"pattern %s can also leave the word on its own, which a hybrid attack cannot; run %s as a wordlist as well", pattern, listPath))
+		}
+		if len(masks) == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("pattern-%02d.hcmask", idx+1))
+		if err := writeLines(path, masks); err != nil {
+			return nil, err
+		}
+
+		report.Files = append(report.Files, path)
+		cmd := fmt.Sprintf("hashcat -a %d -m <mode> <hashes> %s %s", mode, listPath, path)
+		if mode == 7 {
+			cmd = fmt.Sprintf("hashcat -a %d -m <mode> <hashes> %s %s", mode, path, listPath)
+		}
+		report.Commands = append(report.Commands, cmd)
+	}
+
+	if len(fallback) > 0 {
+		path, err := me.expandPatterns(ctx, dir, fallback, words, printer)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Files = append(report.Files, path)
+		report.Commands = append(report.Commands, fmt.Sprintf("hashcat -a 0 -m <mode> <hashes> %s", path))
+	}
+
+	if len(written) > 0 {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+			"hybrid attacks cannot filter by length, so candidates outside %d-%d characters are tried as well",
+			me.config.MinPasswordLen, me.config.MaxPasswordLen))
+	}
+
+	if me.config.Policy.Enabled() {
+		report.Mismatches = append(report.Mismatches,
+			"masks cannot apply the password policy, so candidates it rejects are tried as well")
+	}
+
+	if me.config.Roster.File != "" {
+		report.Mismatches = append(report.Mismatches,
+			"masks cannot bind the roster fields to one user at a time, so the names of different users are combined")
+	}
+
+	return report, nil
+}
+
+func (me *MaskExporter) newGenerator(cfg config.GeneratorConfig, words map[string][]string) *generator.Generator {
+	gen := generator.New(cfg, me.placeholders)
+	for format, list := range words {
+		gen.SetWords(format, list)
+	}
+
+	return gen
+}
+
+// tokenize splits a pattern into its placeholders and the literal text
+// between them. Numbered placeholders are tokenized as their plain format.
+func (me *MaskExporter) tokenize(pattern string) []patternToken {
+	formats := []string{
+		me.placeholders.Separator.Format, me.placeholders.Year.Format, me.placeholders.ShortYear.Format,
+		me.placeholders.Number.Format,
+	}
+	for _, list := range config.WordLists(me.config, me.placeholders) {
+		formats = append(formats, list.Format)
+	}
+	for _, format := range append(slices.Sorted(maps.Keys(me.dates)), me.placeholders.Keywalk.Format) {
+		if format != "" {
+			formats = append(formats, format)
+		}
+	}
+
+	var tokens []patternToken
+	for len(pattern) > 0 {
+		token, consumed := me.matchToken(pattern, formats)
+		if consumed > 0 {
+			tokens = append(tokens, token)
+			pattern = pattern[consumed:]
+			continue
+		}
+
+		if len(tokens) > 0 && tokens[len(tokens)-1].placeholder == "" {
+			tokens[len(tokens)-1].literal += pattern[:1]
+		} else {
+			tokens = append(tokens, patternToken{literal: pattern[:1]})
+		}
+		pattern = pattern[1:]
+	}
+
+	return tokens
+}
+
+// matchToken matches a placeholder, plain, numbered or modified, at the start of text
+func (me *MaskExporter) matchToken(text string, formats []string) (patternToken, int) {
+	for _, format := range formats {
+		if strings.HasPrefix(text, format) {
+			return patternToken{placeholder: format}, len(format)
+		}
+	}
+
+	end := strings.IndexByte(text, '>')
+	if end < 0 {
+		return patternToken{}, 0
+	}
+
+	if maskText, isMask := config.MaskText(text[:end+1], me.placeholders.Mask.Format); isMask {
+		mask, err := config.ParseMask(maskText)
+		if err != nil {
+			return patternToken{}, 0
+		}
+		return patternToken{placeholder: me.placeholders.Mask.Format, mask: mask}, end + 1
+	}
+
+	ref, ok := config.ParsePlaceholderRef(text[:end+1])
+	if !ok || !slices.Contains(formats, ref.Format) {
+		return patternToken{}, 0
+	}
+
+	token := patternToken{placeholder: ref.Format, modified: len(ref.Modifiers) > 0}
+	if ref.Label != "" {
+		// Full and short years share numbered values
+		owner := ref.Format
+		if owner == me.placeholders.ShortYear.Format {
+			owner = me.placeholders.Year.Format
+		}
+		token.label = owner + ref.Label
+	}
+
+	return token, end + 1
+}
+
+// sharesValues reports whether a numbered value appears more than once, which
+// ties placeholders together in a way masks cannot express
+func sharesValues(tokens []patternToken) bool {
+	seen := make(map[string]bool)
+	for _, token := range tokens {
+		if token.label == "" {
+			continue
+		}
+		if seen[token.label] {
+			return true
+		}
+		seen[token.label] = true
+	}
+
+	return false
+}
+
+// compileMasks builds every mask line for the tokens. Masks of fixed length
+// outside the password length limits are dropped when filterLength is set.
+func (me *MaskExporter) compileMasks(tokens []patternToken, filterLength bool) []string {
+	charset := me.separatorCharset()
+
+	var masks []string
+	seen := make(map[string]bool)
+
+	combos := []maskPart{{}}
+	for _, token := range tokens {
+		combos = joinParts(combos, me.tokenParts(token, charset))
+	}
+
+	for _, combo := range combos {
+		if combo.length == 0 {
+			continue
+		}
+		if filterLength && (combo.length < me.config.MinPasswordLen || combo.length > me.config.MaxPasswordLen) {
+			continue
+		}
+
+		line := combo.mask
+		if strings.Contains(line, "?1") {
+			line = charset + "," + line
+		}
+
+		if !seen[line] {
+			seen[line] = true
+			masks = append(masks, line)
+		}
+	}
+
+	return masks
+}
+
+// canBeEmpty reports whether every token can produce no characters at all
+func (me *MaskExporter) canBeEmpty(tokens []patternToken) bool {
+	charset := me.separatorCharset()
+
+	for _, token := range tokens {
+		empty := false
+		for _, part := range me.tokenParts(token, charset) {
+			if part.length == 0 {
+				empty = true
+				break
+			}
+		}
+		if !empty {
+			return false
+		}
+	}
+
+	return true
+}
+
+// tokenParts lists the mask alternatives of a single token
+func (me *MaskExporter) tokenParts(token patternToken, charset string) []maskPart {
+	switch token.placeholder {
+	case "":
+		return []maskPart{literalPart(token.literal)}
+	case me.placeholders.Separator.Format:
+		var parts []maskPart
+		if charset != "" {
+			parts = append(parts, maskPart{mask: "?1", length: 1})
+		}
+		for _, separator := range me.config.Separators {
+			if len(separator) != 1 {
+				parts = append(parts, literalPart(separator))
+			}
+		}
+		return parts
+	case me.placeholders.Year.Format:
+		return me.yearParts(false)
+	case me.placeholders.ShortYear.Format:
+		return me.yearParts(true)
+	case me.placeholders.Number.Format:
+		return me.numberParts()
+	case me.placeholders.Mask.Format:
+		return maskParts(token.mask)
+	}
+
+	// Date placeholders and keyboard walks are written out value by value
+	values := me.dates[token.placeholder]
+	if token.placeholder == me.placeholders.Keywalk.Format {
+		values = me.keywalks
+	}
+
+	var parts []maskPart
+	for _, value := range values {
+		parts = append(parts, literalPart(value))
+	}
+
+	return parts
+}
+
+// numberParts compiles every number pattern into masks. Digits become ?d,
+// repeated as often as the pattern allows; ranges, named generators and
+// literal characters are written out value by value.
+func (me *MaskExporter) numberParts() []maskPart {
+	var parts []maskPart
+
+	for _, pattern := range me.config.NumberPatterns {
+		parsed, err := config.ParseNumberPattern(pattern)
+		if err != nil {
+			continue
+		}
+
+		combos := []maskPart{{}}
+		for _, atom := range parsed.Atoms {
+			pieces := []maskPart{{mask: "?d", length: 1}}
+			if !atom.Digit {
+				pieces = nil
+				for _, value := range atom.Values {
+					pieces = append(pieces, literalPart(value))
+				}
+			}
+
+			combos = joinParts(combos, repeatParts(pieces, atom.MinRepeat, atom.MaxRepeat))
+		}
+
+		parts = append(parts, combos...)
+	}
+
+	return parts
+}
+
+// maskParts compiles an inline mask. The built-in charsets are kept, while
+// literal characters and the characters of [...] charsets are written out
+// one mask line each.
+func maskParts(mask config.Mask) []maskPart {
+	combos := []maskPart{{}}
+
+	for _, position := range mask.Positions {
+		pieces := []maskPart{{mask: "?" + string(position.Builtin), length: 1}}
+		if position.Builtin == 0 {
+			pieces = nil
+			for _, char := range position.Charset {
+				pieces = append(pieces, literalPart(string(char)))
+			}
+		}
+
+		combos = joinParts(combos, repeatParts(pieces, position.MinRepeat, position.MaxRepeat))
+	}
+
+	return combos
+}
+
+// repeatParts lists the pieces repeated minRepeat to maxRepeat times
+func repeatParts(pieces []maskPart, minRepeat, maxRepeat int) []maskPart {
+	var repeats []maskPart
+	for repeat := minRepeat; repeat <= maxRepeat; repeat++ {
+		repeated := []maskPart{{}}
+		for range repeat {
+			repeated = joinParts(repeated, pieces)
+		}
+		repeats = append(repeats, repeated...)
+	}
+
+	return repeats
+}
+
+// yearParts compresses the year range into one ?d mask per full decade and
+// literal years for the partial decades at either end
+func (me *MaskExporter) yearParts(short bool) []maskPart {
+	var parts []maskPart
+
+	for decade := me.config.MinYear / 10 * 10; decade <= me.config.MaxYear; decade += 10 {
+		digits := strconv.Itoa(decade)
+		if short {
+			digits = digits[2:]
+		}
+
+		if decade >= me.config.MinYear && decade+9 <= me.config.MaxYear {
+			parts = append(parts, maskPart{mask: digits[:len(digits)-1] + "?d", length: len(digits)})
+			continue
+		}
+
+		for year := max(decade, me.config.MinYear); year <= min(decade+9, me.config.MaxYear); year++ {
+			value := strconv.Itoa(year)
+			if short {
+				value = value[2:]
+			}
+			parts = append(parts, literalPart(value))
+		}
+	}
+
+	return parts
+}
+
+// separatorCharset collects the single-character separators into custom charset ?1
+func (me *MaskExporter) separatorCharset() string {
+	var charset strings.Builder
+	for _, separator := range me.config.Separators {
+		if len(separator) == 1 {
+			charset.WriteString(escapeMask(separator))
+		}
+	}
+
+	return charset.String()
+}
+
+func (me *MaskExporter) writeWordList(dir string, list *wordList, written map[string]string, report *Report) (string, error) {
+	if path, exists := written[list.name]; exists {
+		return path, nil
+	}
+
+	path := filepath.Join(dir, list.name+".txt")
+	if err := writeLines(path, list.words); err != nil {
+		return "", err
+	}
+
+	written[list.name] = path
+	report.Files = append(report.Files, path)
+
+	return path, nil
+}
+
+// expandPatterns writes the full candidate list of the given patterns
+func (me *MaskExporter) expandPatterns(ctx context.Context, dir string, patterns []string, words map[string][]string, printer interfaces.Printer) (string, error) {
+	cfg := me.config
+	cfg.Patterns = patterns
+
+	gen := me.newGenerator(cfg, words)
+	if err := gen.PrepareVariations(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "expanded.txt")
+	printer.Info(fmt.Sprintf("\nExpanding %d pattern(s) that masks cannot express...", len(patterns)))
+
+	if err := gen.Generate(ctx, config.OutputConfig{Filename: path, Compression: config.CompressionNone}, printer); err != nil {
+		return "", fmt.Errorf("failed to expand patterns: %w", err)
+	}
+
+	return path, nil
+}
+
+// joinParts appends every part to every combo
+func joinParts(combos, parts []maskPart) []maskPart {
+	joined := make([]maskPart, 0, len(combos)*len(parts))
+	for _, combo := range combos {
+		for _, part := range parts {
+			joined = append(joined, maskPart{mask: combo.mask + part.mask, length: combo.length + part.length})
+		}
+	}
+
+	return joined
+}
+
+func literalPart(text string) maskPart {
+	return maskPart{mask: escapeMask(text), length: utf8.RuneCountInString(text)}
+}
+
+// escapeMask escapes text for a .hcmask line: ? starts a charset and commas
+// separate the custom charsets from the mask
+func escapeMask(text string) string {
+	text = strings.ReplaceAll(text, "?", "??")
+	return strings.ReplaceAll(text, ",", "\\,")
+}
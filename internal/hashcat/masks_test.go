@@ -0,0 +1,191 @@
+package hashcat
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/generator"
+	"github.com/omarelshopky/craftlist/internal/ui"
+)
+
+// expandMask enumerates a .hcmask line using the subset of mask syntax the exporter writes
+func expandMask(t *testing.T, line string) []string {
+	t.Helper()
+
+	var charset string
+	mask := line
+	if strings.Contains(line, "?1") {
+		parts := strings.SplitN(strings.ReplaceAll(line, "\\,", "\x01"), ",", 2)
+		charset, mask = strings.ReplaceAll(parts[0], "\x01", ","), parts[1]
+		charset = strings.ReplaceAll(charset, "??", "?")
+	}
+	mask = strings.ReplaceAll(mask, "\x01", "\\,")
+
+	candidates := []string{""}
+	for idx := 0; idx < len(mask); idx++ {
+		var choices []string
+		switch {
+		case mask[idx] == '\\' && idx+1 < len(mask) && mask[idx+1] == ',':
+			choices = []string{","}
+			idx++
+		case mask[idx] == '?':
+			idx++
+			switch mask[idx] {
+			case '?':
+				choices = []string{"?"}
+			case 'd':
+				for digit := '0'; digit <= '9'; digit++ {
+					choices = append(choices, string(digit))
+				}
+			case '1':
+				for _, ch := range charset {
+					choices = append(choices, string(ch))
+				}
+			default:
+				t.Fatalf("unexpected charset ?%c in %q", mask[idx], line)
+			}
+		default:
+			choices = []string{mask[idx : idx+1]}
+		}
+
+		var next []string
+		for _, candidate := range candidates {
+			for _, choice := range choices {
+				next = append(next, candidate+choice)
+			}
+		}
+		candidates = next
+	}
+
+	return candidates
+}
+
+func TestMaskExportMatchesGenerator(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinYear = 2008
+	cfg.MaxYear = 2021
+	cfg.MinPasswordLen = 1
+	cfg.MaxPasswordLen = 64
+	cfg.CommonWords = []string{"admin"}
+	cfg.Separators = []string{"", "_", ",", "--"}
+	cfg.NumberPatterns = []string{"dd", "7", "[1-3]d{1,2}"}
+	cfg.Substitutions = map[string][]string{"a": {"4"}}
+	cfg.Patterns = []string{
+		"<CUSTOM>", "<CUSTOM><SEP><YEAR>", "<NUM><COMMON>", "<YEAR><SEP><NUM>",
+		"<CUSTOM><SEP><COMMON>", "<SHORTYEAR>?<YEAR>", "<NUM><SEP><NUM>", "<YEAR:1><SEP><SHORTYEAR=1>",
+		"<CUSTOM|upper><YEAR|rev>", "<CUSTOM><MASK:?d[!@]{1,2}>",
+	}
+	cfg.Dedup.Mode = config.DedupMemory
+	placeholders := config.NewDefaultPlaceholdersConfig()
+
+	gen := generator.New(cfg, placeholders)
+	gen.SetCustomWords([]string{"acme"})
+	if err := gen.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "passwords.txt")
+	if err := gen.Generate(context.Background(), config.OutputConfig{Filename: outputFile}, ui.NewPrinterTo(io.Discard, io.Discard)); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	expected := readLines(t, outputFile)
+	sort.Strings(expected)
+
+	report, err := NewMaskExporter(cfg, placeholders).Export(context.Background(), filepath.Join(dir, "masks"), map[string][]string{"<CUSTOM>": {"acme"}, "<COMMON>": cfg.CommonWords}, ui.NewPrinterTo(io.Discard, io.Discard))
+	if err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var got []string
+	add := func(candidate string) {
+		if !seen[candidate] {
+			seen[candidate] = true
+			got = append(got, candidate)
+		}
+	}
+
+	modes := make(map[string]int)
+	for _, command := range report.Commands {
+		fields := strings.Fields(command)
+		mode, args := fields[2], fields[6:]
+		modes[mode]++
+
+		switch mode {
+		case "0":
+			for _, word := range readLines(t, args[0]) {
+				add(word)
+			}
+		case "3":
+			for _, line := range readLines(t, args[0]) {
+				for _, candidate := range expandMask(t, line) {
+					add(candidate)
+				}
+			}
+		case "6", "7":
+			words, masks := args[0], args[1]
+			if mode == "7" {
+				words, masks = masks, words
+			}
+			for _, word := range readLines(t, words) {
+				for _, line := range readLines(t, masks) {
+					for _, candidate := range expandMask(t, line) {
+						if mode == "6" {
+							add(word + candidate)
+						} else {
+							add(candidate + word)
+						}
+					}
+				}
+			}
+		default:
+			t.Fatalf("unexpected attack mode in %q", command)
+		}
+	}
+	sort.Strings(got)
+
+	for _, mode := range []string{"0", "3", "6", "7"} {
+		if modes[mode] == 0 {
+			t.Errorf("expected an -a %s command, got %v", mode, report.Commands)
+		}
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("masks produce %d candidates, the generator %d", len(got), len(expected))
+	}
+}
+
+func TestMaskExportYearParts(t *testing.T) {
+	tests := []struct {
+		minYear  int
+		maxYear  int
+		short    bool
+		expected []string
+	}{
+		{2010, 2019, false, []string{"201?d"}},
+		{2018, 2021, false, []string{"2018", "2019", "2020", "2021"}},
+		{2008, 2021, false, []string{"2008", "2009", "201?d", "2020", "2021"}},
+		{1990, 2009, true, []string{"9?d", "0?d"}},
+	}
+
+	for _, tt := range tests {
+		cfg := config.NewDefaultGeneratorConfig()
+		cfg.MinYear, cfg.MaxYear = tt.minYear, tt.maxYear
+
+		var got []string
+		for _, part := range NewMaskExporter(cfg, config.NewDefaultPlaceholdersConfig()).yearParts(tt.short) {
+			got = append(got, part.mask)
+		}
+
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("yearParts(%d-%d, short=%v) = %v, expected %v", tt.minYear, tt.maxYear, tt.short, got, tt.expected)
+		}
+	}
+}
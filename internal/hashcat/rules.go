@@ -0,0 +1,459 @@
+package hashcat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/generator"
+)
+
+const (
+	// maxCasePositions bounds the case rules to every upper/lower combination
+	// of the first characters, which is 2^maxCasePositions rules
+	maxCasePositions = 8
+
+	// maxLeetDepth bounds how many different characters a single leet rule
+	// substitutes at once
+	maxLeetDepth = 2
+)
+
+// positionChars encodes character positions the way hashcat and John expect: 0-9 then A-Z
+const positionChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// RuleExporter describes the candidates of a config as base wordlists plus
+// rule files, so crackers can apply the case, leet and affix variations on the fly
+type RuleExporter struct {
+	config       config.GeneratorConfig
+	placeholders config.PlaceholdersConfig
+	variations   *generator.VariationGenerator
+	keywalks     []string
+}
+
+// Report lists the files an export wrote, the commands to run them and
+// every place where the rules do not reproduce the expanded list exactly
+type Report struct {
+	Files      []string
+	Commands   []string
+	Mismatches []string
+}
+
+type wordList struct {
+	name        string
+	placeholder string
+	words       []string
+	affixes     []string
+	profile     config.VariationProfile
+	strategy    config.VariationStrategy
+}
+
+func NewRuleExporter(cfg config.GeneratorConfig, placeholders config.PlaceholdersConfig) *RuleExporter {
+	return &RuleExporter{
+		config:       cfg,
+		placeholders: placeholders,
+		variations:   generator.NewVariationGenerator(cfg),
+	}
+}
+
+// SetKeywalks replaces the walks on the built-in layout, when the layout
+// comes from a file
+func (re *RuleExporter) SetKeywalks(walks []string) {
+	re.keywalks = walks
+}
+
+// Export writes one base wordlist and affix rule file per word list used by
+// the patterns, plus case.rule and leet.rule shared by all of them. words
+// holds the words of every word list keyed by placeholder.
+func (re *RuleExporter) Export(dir string, words map[string][]string) (*Report, error) {
+	report := &Report{}
+
+	var lists []*wordList
+	for _, list := range config.WordLists(re.config, re.placeholders) {
+		lists = append(lists, &wordList{
+			name:        list.Name,
+			placeholder: list.Format,
+			words:       re.baseWords(words[list.Format], list.Profile),
+			profile:     list.Profile,
+			strategy:    list.Strategy,
+		})
+	}
+
+	for _, pattern := range re.config.Patterns {
+		re.addPatternAffixes(pattern, lists, report)
+	}
+
+	var used []*wordList
+	for _, list := range lists {
+		if len(list.words) > 0 && len(list.affixes) > 0 {
+			used = append(used, list)
+		}
+	}
+
+	if len(used) == 0 {
+		return nil, fmt.Errorf("none of the patterns can be expressed as rules")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	var baseWords []string
+	for _, list := range used {
+		baseWords = append(baseWords, list.words...)
+	}
+
+	caseRules := re.caseRules(baseWords, report)
+	leetRules := re.leetRules(baseWords, report)
+
+	casePath := filepath.Join(dir, "case.rule")
+	leetPath := filepath.Join(dir, "leet.rule")
+
+	for path, lines := range map[string][]string{casePath: caseRules, leetPath: leetRules} {
+		if err := writeLines(path, lines); err != nil {
+			return nil, err
+		}
+	}
+	report.Files = append(report.Files, casePath, leetPath)
+
+	for _, list := range used {
+		wordsPath := filepath.Join(dir, list.name+".txt")
+		affixPath := filepath.Join(dir, list.name+".rule")
+
+		if err := writeLines(wordsPath, list.words); err != nil {
+			return nil, err
+		}
+		if err := writeLines(affixPath, list.affixes); err != nil {
+			return nil, err
+		}
+
+		report.Files = append(report.Files, wordsPath, affixPath)
+
+		if strategy := list.strategy; len(strategy.Case) > 0 || strategy.MaxSubstitutions > 0 || strategy.SubstituteLast > 0 {
+			report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+				"the %s variation strategy is not applied; the shared case and leet rules try every combination", list.name))
+		}
+
+		if !list.profile.Case || !list.profile.Leet {
+			report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+				"the %s variation profile turns off case or leet, but the shared case and leet rules still apply to it", list.name))
+		}
+
+		report.Commands = append(report.Commands, fmt.Sprintf("hashcat -a 0 -m <mode> <hashes> %s -r %s -r %s -r %s",
+			wordsPath, casePath, leetPath, affixPath))
+	}
+
+	report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+		"rules cannot filter by length, so candidates outside %d-%d characters are tried as well",
+		re.config.MinPasswordLen, re.config.MaxPasswordLen))
+
+	if re.config.Policy.Enabled() {
+		report.Mismatches = append(report.Mismatches,
+			"rules cannot apply the password policy, so candidates it rejects are tried as well")
+	}
+
+	if re.config.Roster.File != "" {
+		report.Mismatches = append(report.Mismatches,
+			"rules cannot bind the roster fields to one user at a time, so the names of different users are combined")
+	}
+
+	return report, nil
+}
+
+// baseWords applies only the word variations; case and leet come from rules
+func (re *RuleExporter) baseWords(words []string, profile config.VariationProfile) []string {
+	var base []string
+	seen := make(map[string]bool)
+
+	for _, word := range words {
+		variations := []string{word}
+		if profile.Words {
+			variations = re.variations.GenerateWordVariations(word)
+		}
+
+		for _, variation := range variations {
+			if variation != "" && !seen[variation] {
+				seen[variation] = true
+				base = append(base, variation)
+			}
+		}
+	}
+
+	return base
+}
+
+// addPatternAffixes expands everything around the single word of a pattern
+// into prepend and append rules for that word's list
+func (re *RuleExporter) addPatternAffixes(pattern string, lists []*wordList, report *Report) {
+	if modifiesWord(pattern, lists) {
+		report.Mismatches = append(report.Mismatches,
+			fmt.Sprintf("pattern %s modifies its word, which rules on the shared base words cannot express, and is not exported", pattern))
+		return
+	}
+
+	expanded, err := re.expandAround(pattern)
+	if err != nil {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf("%v, and is not exported", err))
+		return
+	}
+	if len(expanded) == 0 {
+		return
+	}
+
+	var target *wordList
+	var marker string
+	wordCount := 0
+
+	for idx, list := range lists {
+		if count := strings.Count(expanded[0], wordMarker(idx)); count > 0 {
+			wordCount += count
+			target, marker = list, wordMarker(idx)
+		}
+	}
+
+	switch {
+	case wordCount == 0:
+		report.Mismatches = append(report.Mismatches,
+			fmt.Sprintf("pattern %s has no word to apply rules to and is not exported", pattern))
+		return
+	case wordCount > 1:
+		report.Mismatches = append(report.Mismatches,
+			fmt.Sprintf("pattern %s combines several words, which rules on a single base word cannot express, and is not exported", pattern))
+		return
+	case len(target.words) == 0:
+		return
+	}
+
+	seen := make(map[string]bool, len(target.affixes))
+	for _, rule := range target.affixes {
+		seen[rule] = true
+	}
+
+	for _, candidate := range expanded {
+		prefix, suffix, _ := strings.Cut(candidate, marker)
+
+		rule := affixRule(prefix, suffix)
+		if !seen[rule] {
+			seen[rule] = true
+			target.affixes = append(target.affixes, rule)
+		}
+	}
+}
+
+var placeholderRegex = regexp.MustCompile(`<[^<>]+>`)
+
+// modifiesWord reports whether a word placeholder of the pattern carries modifiers
+func modifiesWord(pattern string, lists []*wordList) bool {
+	for _, match := range placeholderRegex.FindAllString(pattern, -1) {
+		ref, _ := config.ParsePlaceholderRef(match)
+		if len(ref.Modifiers) == 0 {
+			continue
+		}
+
+		for _, list := range lists {
+			if ref.Format == list.placeholder {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// expandAround fills every placeholder of the pattern except the words,
+// which are left as the wordMarker of their list
+func (re *RuleExporter) expandAround(pattern string) ([]string, error) {
+	gen := generator.New(re.config, re.placeholders)
+	if re.keywalks != nil {
+		gen.SetKeywalks(re.keywalks)
+	}
+	for idx, list := range config.WordLists(re.config, re.placeholders) {
+		gen.SetWords(list.Format, []string{wordMarker(idx)})
+	}
+
+	return gen.ExpandPattern(pattern)
+}
+
+// wordMarker stands in for the words of list while a pattern is expanded
+// into the text around them
+func wordMarker(list int) string {
+	return fmt.Sprintf("\x00%d\x00", list)
+}
+
+// affixRule prepends prefix and appends suffix. Prepending works one
+// character at a time at the front, so the prefix is prepended in reverse.
+// Rules work on bytes, so a multibyte character becomes one function per
+// byte of its UTF-8 encoding, in hashcat's \xNN notation.
+func affixRule(prefix, suffix string) string {
+	var functions []string
+
+	for idx := len(prefix) - 1; idx >= 0; idx-- {
+		functions = append(functions, "^"+ruleByte(prefix[idx]))
+	}
+
+	for idx := 0; idx < len(suffix); idx++ {
+		functions = append(functions, "$"+ruleByte(suffix[idx]))
+	}
+
+	if len(functions) == 0 {
+		return ":"
+	}
+
+	return strings.Join(functions, " ")
+}
+
+// ruleByte writes a byte as a rule function argument, in hex when it is not
+// ASCII
+func ruleByte(char byte) string {
+	if char > 127 {
+		return fmt.Sprintf(`\x%02x`, char)
+	}
+
+	return string(char)
+}
+
+// caseRules lowercases the word and toggles every combination of the first
+// characters, which gives all upper/lower case combinations like the
+// generator's case variations
+func (re *RuleExporter) caseRules(words []string, report *Report) []string {
+	longest, longer, nonASCII := 0, 0, 0
+	for _, word := range words {
+		longest = max(longest, len(word))
+		if len(word) > maxCasePositions {
+			longer++
+		}
+		if strings.IndexFunc(word, func(r rune) bool { return r > 127 }) >= 0 {
+			nonASCII++
+		}
+	}
+
+	positions := min(longest, maxCasePositions)
+	if longer > 0 {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+			"case rules only combine the first %d characters; %d base word(s) are longer and keep the rest lowercase",
+			maxCasePositions, longer))
+	}
+
+	if nonASCII > 0 {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+			"%d base word(s) contain non-ASCII characters, whose case the rules cannot change", nonASCII))
+	}
+
+	rules := make([]string, 0, 1<<positions)
+	for mask := 0; mask < 1<<positions; mask++ {
+		functions := []string{"l"}
+		for pos := 0; pos < positions; pos++ {
+			if mask&(1<<pos) != 0 {
+				functions = append(functions, "T"+positionChars[pos:pos+1])
+			}
+		}
+		rules = append(rules, strings.Join(functions, " "))
+	}
+
+	return rules
+}
+
+// leetRules builds substitution rules for every combination of up to
+// maxLeetDepth substituted characters
+func (re *RuleExporter) leetRules(words []string, report *Report) []string {
+	type substitution struct {
+		from       string
+		candidates []string
+	}
+
+	var substitutions []substitution
+	var skipped []string
+
+	for from, candidates := range re.config.Substitutions {
+		if len(from) != 1 {
+			skipped = append(skipped, from)
+			continue
+		}
+
+		var usable []string
+		for _, candidate := range candidates {
+			if len(candidate) != 1 {
+				skipped = append(skipped, from+"->"+candidate)
+				continue
+			}
+			usable = append(usable, candidate)
+		}
+
+		if len(usable) > 0 {
+			substitutions = append(substitutions, substitution{from: from, candidates: usable})
+		}
+	}
+
+	sort.Slice(substitutions, func(i, j int) bool { return substitutions[i].from < substitutions[j].from })
+
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+			"substitutions longer than one character cannot be written as rules: %s", strings.Join(skipped, ", ")))
+	}
+
+	if len(substitutions) > maxLeetDepth {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+			"leet rules substitute at most %d different characters at once; the expanded list has no such limit", maxLeetDepth))
+	}
+
+	repeated := 0
+	for _, word := range words {
+		for _, sub := range substitutions {
+			if strings.Count(strings.ToLower(word), strings.ToLower(sub.from)) > 1 {
+				repeated++
+				break
+			}
+		}
+	}
+
+	if repeated > 0 {
+		report.Mismatches = append(report.Mismatches, fmt.Sprintf(
+			"a substitution rule replaces every occurrence of a character, so %d base word(s) with repeated characters miss the partially substituted variations",
+			repeated))
+	}
+
+	rules := []string{":"}
+
+	var combine func(start int, functions []string)
+	combine = func(start int, functions []string) {
+		if len(functions) == maxLeetDepth {
+			return
+		}
+
+		for idx := start; idx < len(substitutions); idx++ {
+			for _, candidate := range substitutions[idx].candidates {
+				next := append(append([]string{}, functions...), "s"+substitutions[idx].from+candidate)
+				rules = append(rules, strings.Join(next, " "))
+				combine(idx+1, next)
+			}
+		}
+	}
+	combine(0, nil)
+
+	return rules
+}
+
+func writeLines(path string, lines []string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, line := range lines {
+		writer.WriteString(line)
+		writer.WriteByte('\n')
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
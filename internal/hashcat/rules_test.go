@@ -0,0 +1,187 @@
+package hashcat
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/omarelshopky/craftlist/internal/config"
+	"github.com/omarelshopky/craftlist/internal/generator"
+	"github.com/omarelshopky/craftlist/internal/ui"
+)
+
+// applyRule runs the subset of rule functions the exporter writes
+func applyRule(t *testing.T, word, rule string) string {
+	t.Helper()
+
+	for _, function := range strings.Split(rule, " ") {
+		switch function[0] {
+		case ':':
+		case 'l':
+			word = strings.ToLower(word)
+		case 'T':
+			pos := strings.IndexByte(positionChars, function[1])
+			if pos < len(word) {
+				ch := word[pos : pos+1]
+				if strings.ToLower(ch) == ch {
+					ch = strings.ToUpper(ch)
+				} else {
+					ch = strings.ToLower(ch)
+				}
+				word = word[:pos] + ch + word[pos+1:]
+			}
+		case 's':
+			word = strings.ReplaceAll(word, function[1:2], function[2:3])
+		case '^':
+			word = ruleArgument(t, function[1:]) + word
+		case '$':
+			word = word + ruleArgument(t, function[1:])
+		default:
+			t.Fatalf("unexpected rule function %q", function)
+		}
+	}
+
+	return word
+}
+
+// ruleArgument decodes a character argument, which may be in \xNN notation
+func ruleArgument(t *testing.T, argument string) string {
+	t.Helper()
+
+	hexDigits, found := strings.CutPrefix(argument, `\x`)
+	if !found {
+		return argument
+	}
+
+	char, err := strconv.ParseUint(hexDigits, 16, 8)
+	if err != nil {
+		t.Fatalf("invalid hex rule argument %q", argument)
+	}
+
+	return string([]byte{byte(char)})
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func TestRuleExportMatchesGenerator(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.MinYear = 2024
+	cfg.MaxYear = 2025
+	cfg.MinPasswordLen = 1
+	cfg.CommonWords = nil
+	cfg.Separators = []string{"", "_", "€"}
+	cfg.NumberPatterns = []string{"1", "12"}
+	cfg.Substitutions = map[string][]string{"a": {"4", "@"}, "e": {"3"}}
+	cfg.Patterns = []string{"<CUSTOM>", "<CUSTOM><SEP><YEAR>", "<SHORTYEAR><CUSTOM><NUM>", "<YEAR:1><CUSTOM><SHORTYEAR=1>"}
+	cfg.Dedup.Mode = config.DedupMemory
+	placeholders := config.NewDefaultPlaceholdersConfig()
+
+	gen := generator.New(cfg, placeholders)
+	gen.SetCustomWords([]string{"acme"})
+	if err := gen.PrepareVariations(); err != nil {
+		t.Fatalf("PrepareVariations() returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	outputFile := filepath.Join(dir, "passwords.txt")
+	if err := gen.Generate(context.Background(), config.OutputConfig{Filename: outputFile}, ui.NewPrinterTo(io.Discard, io.Discard)); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	expected := readLines(t, outputFile)
+	sort.Strings(expected)
+
+	report, err := NewRuleExporter(cfg, placeholders).Export(filepath.Join(dir, "rules"), map[string][]string{"<CUSTOM>": {"acme"}})
+	if err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+
+	if len(report.Commands) != 1 {
+		t.Fatalf("expected one command for the custom list, got %v", report.Commands)
+	}
+
+	seen := make(map[string]bool)
+	var got []string
+	for _, word := range readLines(t, filepath.Join(dir, "rules", "custom.txt")) {
+		for _, caseRule := range readLines(t, filepath.Join(dir, "rules", "case.rule")) {
+			for _, leetRule := range readLines(t, filepath.Join(dir, "rules", "leet.rule")) {
+				for _, affixRule := range readLines(t, filepath.Join(dir, "rules", "custom.rule")) {
+					candidate := applyRule(t, applyRule(t, applyRule(t, word, caseRule), leetRule), affixRule)
+					if !seen[candidate] {
+						seen[candidate] = true
+						got = append(got, candidate)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(got)
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("rules produce %d candidates, the generator %d", len(got), len(expected))
+	}
+}
+
+func TestRuleExportReportsMismatches(t *testing.T) {
+	cfg := config.NewDefaultGeneratorConfig()
+	cfg.Substitutions = map[string][]string{"a": {"4"}, "h": {"|-|"}}
+	cfg.Patterns = []string{"<CUSTOM><SEP><COMMON>", "<YEAR><NUM>", "<CUSTOM><YEAR>", "<CUSTOM:1><CUSTOM=1>", "<CUSTOM|upper><YEAR>"}
+
+	report, err := NewRuleExporter(cfg, config.NewDefaultPlaceholdersConfig()).Export(t.TempDir(), map[string][]string{"<CUSTOM>": {"banana"}, "<COMMON>": cfg.CommonWords})
+	if err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+
+	mismatches := strings.Join(report.Mismatches, "\n")
+	for _, expected := range []string{
+		"<CUSTOM><SEP><COMMON> combines several words",
+		"<YEAR><NUM> has no word",
+		"<CUSTOM:1><CUSTOM=1> combines several words",
+		"<CUSTOM|upper><YEAR> modifies its word",
+		"h->|-|",
+		"replaces every occurrence",
+	} {
+		if !strings.Contains(mismatches, expected) {
+			t.Errorf("expected a mismatch mentioning %q, got:\n%s", expected, mismatches)
+		}
+	}
+}
+
+func TestAffixRule(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		suffix   string
+		expected string
+	}{
+		{"", "", ":"},
+		{"", "_24", "$_ $2 $4"},
+		{"24", "", "^4 ^2"},
+		{"1", "!", "^1 $!"},
+		{"", "€24", `$\xe2 $\x82 $\xac $2 $4`},
+		{"ü", "", `^\xbc ^\xc3`},
+	}
+
+	for _, tt := range tests {
+		got := affixRule(tt.prefix, tt.suffix)
+		if got != tt.expected {
+			t.Errorf("affixRule(%q, %q) = %q, expected %q", tt.prefix, tt.suffix, got, tt.expected)
+		}
+		if candidate := applyRule(t, "acme", got); candidate != tt.prefix+"acme"+tt.suffix {
+			t.Errorf("rule %q turned acme into %q, expected %q", got, candidate, tt.prefix+"acme"+tt.suffix)
+		}
+	}
+}
@@ -13,6 +13,21 @@ type PlaceholdersConfig struct {
 	Year       Placeholder `mapstructure:"year" json:"year"`
 	ShortYear  Placeholder `mapstructure:"short_year" json:"short_year"`
 	Number     Placeholder `mapstructure:"number" json:"number"`
+	Month      Placeholder `mapstructure:"month" json:"month"`
+	MonthName  Placeholder `mapstructure:"month_name" json:"month_name"`
+	MonthAbbr  Placeholder `mapstructure:"month_abbr" json:"month_abbr"`
+	Season     Placeholder `mapstructure:"season" json:"season"`
+	Quarter    Placeholder `mapstructure:"quarter" json:"quarter"`
+	Day        Placeholder `mapstructure:"day" json:"day"`
+	DayMonth   Placeholder `mapstructure:"day_month" json:"day_month"`
+	MonthYear  Placeholder `mapstructure:"month_year" json:"month_year"`
+	FullDate   Placeholder `mapstructure:"full_date" json:"full_date"`
+	Keywalk    Placeholder `mapstructure:"keywalk" json:"keywalk"`
+	Mask       Placeholder `mapstructure:"mask" json:"mask"`
+	User       Placeholder `mapstructure:"user" json:"user"`
+	First      Placeholder `mapstructure:"first" json:"first"`
+	Last       Placeholder `mapstructure:"last" json:"last"`
+	Initials   Placeholder `mapstructure:"initials" json:"initials"`
 }
 
 type Printer interface {
@@ -22,11 +37,16 @@ type Printer interface {
 	Warning(message string)
 	Bold(message string)
 	PrintIntro(version string)
-	PrintPlaceholders(placeholders PlaceholdersConfig)
+	PrintPlaceholders(placeholders []Placeholder)
+	PrintPatternErrors(details []string)
 	PrintLoadedWords(category string, count int)
 	PrintCountStats(stats map[string]int)
+	PrintPassRates(stats map[string]int, rates map[string]float64)
+	PrintPartCounts(counts []int, current int)
 	PrintProgress(count int)
 	PrintFinalCount(count int)
+	PrintDuplicatesCount(count int)
 	PrintOutputFile(path string)
+	PrintCheckpointSaved(path string)
 	PrintTotalPasswordsCount(count int)
 }
\ No newline at end of file
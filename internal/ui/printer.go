@@ -2,7 +2,8 @@ package ui
 
 import (
 	"fmt"
-	"reflect"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/omarelshopky/craftlist/internal/interfaces"
@@ -13,37 +14,46 @@ import (
 type Printer struct {
 	colors Colors
 	humanizer *message.Printer
+	out io.Writer
+	errOut io.Writer
 }
 
 func NewPrinter() *Printer {
+	return NewPrinterTo(os.Stdout, os.Stdout)
+}
+
+// NewPrinterTo creates a printer that writes messages to out and errors to errOut
+func NewPrinterTo(out, errOut io.Writer) *Printer {
 	return &Printer{
 		colors: DefaultColors,
 		humanizer: message.NewPrinter(language.English),
+		out: out,
+		errOut: errOut,
 	}
 }
 
 func (p *Printer) Info(message string) {
-	fmt.Printf("%s%s%s\n", p.colors.Cyan, message, p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%s%s\n", p.colors.Cyan, message, p.colors.Reset)
 }
 
 func (p *Printer) Success(message string) {
-	fmt.Printf("%s%s%s\n", p.colors.Green, message, p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%s%s\n", p.colors.Green, message, p.colors.Reset)
 }
 
 func (p *Printer) Error(message string) {
-	fmt.Printf("%s%s%s\n", p.colors.Red, message, p.colors.Reset)
+	fmt.Fprintf(p.errOut, "%s%s%s\n", p.colors.Red, message, p.colors.Reset)
 }
 
 func (p *Printer) Warning(message string) {
-	fmt.Printf("%s%s%s\n", p.colors.Yellow, message, p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%s%s\n", p.colors.Yellow, message, p.colors.Reset)
 }
 
 func (p *Printer) Bold(message string) {
-	fmt.Printf("%s%s%s\n", p.colors.Bold, message, p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%s%s\n", p.colors.Bold, message, p.colors.Reset)
 }
 
 func (p *Printer) PrintIntro(version string) {
-	fmt.Printf(`
+	fmt.Fprintf(p.out, `
                  __ _   _ _     _   
                 / _| | | (_)   | |  
   ___ _ __ __ _| |_| |_| |_ ___| |_ 
@@ -56,30 +66,59 @@ v%s             By Omar Elshopky
 `, version)
 }
 
-func (p *Printer) PrintPlaceholders(placeholders interfaces.PlaceholdersConfig) {
-	fmt.Printf("%sAvailable Placeholders:%s\n\n", p.colors.Bold, p.colors.Reset)
-	fmt.Printf("%s%-15s %s%s\n", p.colors.Green, "PLACEHOLDER", "DESCRIPTION", p.colors.Reset)
-	fmt.Printf("%s%-15s %s%s\n", p.colors.Green, strings.Repeat("-", 15), strings.Repeat("-", 50), p.colors.Reset)
+func (p *Printer) PrintPlaceholders(placeholders []interfaces.Placeholder) {
+	fmt.Fprintf(p.out, "%sAvailable Placeholders:%s\n\n", p.colors.Bold, p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%-15s %s%s\n", p.colors.Green, "PLACEHOLDER", "DESCRIPTION", p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%-15s %s%s\n", p.colors.Green, strings.Repeat("-", 15), strings.Repeat("-", 50), p.colors.Reset)
 
-	values := reflect.ValueOf(placeholders)
-	for idx := 0; idx < values.NumField(); idx++ {
-		if placeholder, ok := values.Field(idx).Interface().(interfaces.Placeholder); ok {
-			fmt.Printf("%s%-15s %s%s\n", p.colors.Yellow, placeholder.Format, p.colors.Reset, placeholder.Description)
-		}
+	for _, placeholder := range placeholders {
+		fmt.Fprintf(p.out, "%s%-15s %s%s\n", p.colors.Yellow, placeholder.Format, p.colors.Reset, placeholder.Description)
+	}
+}
+
+func (p *Printer) PrintPatternErrors(details []string) {
+	fmt.Fprintf(p.errOut, "%sPattern validation failed:%s\n", p.colors.Red, p.colors.Reset)
+	for _, detail := range details {
+		fmt.Fprintf(p.errOut, "  %s\n", detail)
 	}
 }
 
 func (p *Printer) PrintLoadedWords(category string, count int) {
-	fmt.Printf("%sLoaded %s%d%s%s words for %s%s\n",
+	fmt.Fprintf(p.out, "%sLoaded %s%d%s%s words for %s%s\n",
 		p.colors.Cyan, p.colors.Bold, count, p.colors.Reset, p.colors.Cyan, category, p.colors.Reset)
 }
 
 func (p *Printer) PrintCountStats(stats map[string]int) {
-	fmt.Printf("\n%s%-50s %s%s\n", p.colors.Green, "PLACEHOLDER", "PASSWORDS COUNT", p.colors.Reset)
-	fmt.Printf("%s%-50s %s%s\n", p.colors.Green, strings.Repeat("-", 40), strings.Repeat("-", 25), p.colors.Reset)
+	fmt.Fprintf(p.out, "\n%s%-50s %s%s\n", p.colors.Green, "PLACEHOLDER", "PASSWORDS COUNT", p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%-50s %s%s\n", p.colors.Green, strings.Repeat("-", 40), strings.Repeat("-", 25), p.colors.Reset)
+
+	for pattern, count := range stats {
+		fmt.Fprintf(p.out, "%s%-50s %s%s\n", p.colors.Yellow, pattern, p.colors.Reset, p.humanizeNumber(count))
+	}
+}
+
+// PrintPassRates lists the estimated count of every pattern with the share
+// of its candidates the password policy accepts
+func (p *Printer) PrintPassRates(stats map[string]int, rates map[string]float64) {
+	fmt.Fprintf(p.out, "\n%s%-50s %-25s %s%s\n", p.colors.Green, "PLACEHOLDER", "PASSWORDS COUNT", "POLICY PASS RATE", p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%-50s %-25s %s%s\n", p.colors.Green, strings.Repeat("-", 40), strings.Repeat("-", 25), strings.Repeat("-", 16), p.colors.Reset)
 
 	for pattern, count := range stats {
-		fmt.Printf("%s%-50s %s%s\n", p.colors.Yellow, pattern, p.colors.Reset, p.humanizeNumber(count))
+		fmt.Fprintf(p.out, "%s%-50s %s%-25s %.1f%%\n", p.colors.Yellow, pattern, p.colors.Reset, p.humanizeNumber(count), rates[pattern]*100)
+	}
+}
+
+// PrintPartCounts lists the estimated size of every part, marking the current one
+func (p *Printer) PrintPartCounts(counts []int, current int) {
+	fmt.Fprintf(p.out, "\n%s%-50s %s%s\n", p.colors.Green, "PART", "PASSWORDS COUNT", p.colors.Reset)
+	fmt.Fprintf(p.out, "%s%-50s %s%s\n", p.colors.Green, strings.Repeat("-", 40), strings.Repeat("-", 25), p.colors.Reset)
+
+	for idx, count := range counts {
+		part := fmt.Sprintf("%d/%d", idx+1, len(counts))
+		if idx+1 == current {
+			part += " (this run)"
+		}
+		fmt.Fprintf(p.out, "%s%-50s %s%s\n", p.colors.Yellow, part, p.colors.Reset, p.humanizeNumber(count))
 	}
 }
 
@@ -3,19 +3,29 @@ package ui
 import "fmt"
 
 func (p *Printer) PrintProgress(count int) {
-	fmt.Printf("\rGenerated %s%s%s unique passwords...", p.colors.Bold, p.humanizeNumber(count), p.colors.Reset)
+	fmt.Fprintf(p.out, "\rProcessed %s%s%s password candidates...", p.colors.Bold, p.humanizeNumber(count), p.colors.Reset)
 }
 
 func (p *Printer) PrintFinalCount(count int) {
-	fmt.Printf("\n\n%sGenerated %s%s%s%s total unique passwords%s\n",
+	fmt.Fprintf(p.out, "\n\n%sGenerated %s%s%s%s total unique passwords%s\n",
 		p.colors.Green, p.colors.Bold, p.humanizeNumber(count), p.colors.Reset, p.colors.Green, p.colors.Reset)
 }
 
+func (p *Printer) PrintDuplicatesCount(count int) {
+	fmt.Fprintf(p.out, "%sDropped %s%s%s%s duplicate passwords%s\n",
+		p.colors.Cyan, p.colors.Bold, p.humanizeNumber(count), p.colors.Reset, p.colors.Cyan, p.colors.Reset)
+}
+
 func (p *Printer) PrintOutputFile(path string) {
 	p.Success(fmt.Sprintf("Output saved to: %s%s%s\n", p.colors.Bold, path, p.colors.Reset))
 }
 
+func (p *Printer) PrintCheckpointSaved(path string) {
+	p.Warning(fmt.Sprintf("\n\nGeneration interrupted, progress saved to: %s%s%s\nRun again with --resume to continue.",
+		p.colors.Bold, path, p.colors.Reset))
+}
+
 func (p *Printer) PrintTotalPasswordsCount(count int) {
-	fmt.Printf("\n%sA total of %s%s%s%s unique passwords will be generated.%s\n",
+	fmt.Fprintf(p.out, "\n%sUp to %s%s%s%s unique passwords will be generated.%s\n",
 		p.colors.Cyan, p.colors.Bold, p.humanizeNumber(count), p.colors.Reset, p.colors.Cyan, p.colors.Reset)
 }
\ No newline at end of file
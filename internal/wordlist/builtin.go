@@ -0,0 +1,42 @@
+package wordlist
+
+import "sort"
+
+// builtinLists are the word lists a user-defined placeholder can use
+// without a file
+var builtinLists = map[string][]string{
+	"months": {
+		"january", "february", "march", "april", "may", "june",
+		"july", "august", "september", "october", "november", "december",
+	},
+	"months_short": {
+		"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	},
+	"weekdays": {
+		"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday",
+	},
+	"seasons": {
+		"spring", "summer", "autumn", "fall", "winter",
+	},
+}
+
+// Builtin returns a copy of the built-in word list called name
+func Builtin(name string) ([]string, bool) {
+	words, exists := builtinLists[name]
+	if !exists {
+		return nil, false
+	}
+
+	return append([]string(nil), words...), true
+}
+
+// BuiltinNames lists the built-in word lists in sorted order
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtinLists))
+	for name := range builtinLists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
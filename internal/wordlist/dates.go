@@ -0,0 +1,99 @@
+package wordlist
+
+import "sort"
+
+// calendarNames are the month names, month abbreviations and seasons of one
+// language, written the way they start a sentence
+type calendarNames struct {
+	months      []string
+	monthsShort []string
+	seasons     []string
+}
+
+var calendarLocales = map[string]calendarNames{
+	"en": {
+		months: []string{"January", "February", "March", "April", "May", "June",
+			"July", "August", "September", "October", "November", "December"},
+		monthsShort: []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		seasons:     []string{"Spring", "Summer", "Autumn", "Fall", "Winter"},
+	},
+	"de": {
+		months: []string{"Januar", "Februar", "März", "April", "Mai", "Juni",
+			"Juli", "August", "September", "Oktober", "November", "Dezember"},
+		monthsShort: []string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		seasons:     []string{"Frühling", "Sommer", "Herbst", "Winter"},
+	},
+	"fr": {
+		months: []string{"Janvier", "Février", "Mars", "Avril", "Mai", "Juin",
+			"Juillet", "Août", "Septembre", "Octobre", "Novembre", "Décembre"},
+		monthsShort: []string{"Janv", "Févr", "Mars", "Avr", "Mai", "Juin", "Juil", "Août", "Sept", "Oct", "Nov", "Déc"},
+		seasons:     []string{"Printemps", "Été", "Automne", "Hiver"},
+	},
+	"es": {
+		months: []string{"Enero", "Febrero", "Marzo", "Abril", "Mayo", "Junio",
+			"Julio", "Agosto", "Septiembre", "Octubre", "Noviembre", "Diciembre"},
+		monthsShort: []string{"Ene", "Feb", "Mar", "Abr", "May", "Jun", "Jul", "Ago", "Sep", "Oct", "Nov", "Dic"},
+		seasons:     []string{"Primavera", "Verano", "Otoño", "Invierno"},
+	},
+	"it": {
+		months: []string{"Gennaio", "Febbraio", "Marzo", "Aprile", "Maggio", "Giugno",
+			"Luglio", "Agosto", "Settembre", "Ottobre", "Novembre", "Dicembre"},
+		monthsShort: []string{"Gen", "Feb", "Mar", "Apr", "Mag", "Giu", "Lug", "Ago", "Set", "Ott", "Nov", "Dic"},
+		seasons:     []string{"Primavera", "Estate", "Autunno", "Inverno"},
+	},
+	"nl": {
+		months: []string{"Januari", "Februari", "Maart", "April", "Mei", "Juni",
+			"Juli", "Augustus", "September", "Oktober", "November", "December"},
+		monthsShort: []string{"Jan", "Feb", "Mrt", "Apr", "Mei", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dec"},
+		seasons:     []string{"Lente", "Zomer", "Herfst", "Winter"},
+	},
+	"pt": {
+		months: []string{"Janeiro", "Fevereiro", "Março", "Abril", "Maio", "Junho",
+			"Julho", "Agosto", "Setembro", "Outubro", "Novembro", "Dezembro"},
+		monthsShort: []string{"Jan", "Fev", "Mar", "Abr", "Mai", "Jun", "Jul", "Ago", "Set", "Out", "Nov", "Dez"},
+		seasons:     []string{"Primavera", "Verão", "Outono", "Inverno"},
+	},
+}
+
+// MonthNames returns the month names of the locales, January first, without
+// the names two locales share
+func MonthNames(locales []string) []string {
+	return mergeLocales(locales, func(names calendarNames) []string { return names.months })
+}
+
+// MonthAbbreviations returns the abbreviated month names of the locales
+func MonthAbbreviations(locales []string) []string {
+	return mergeLocales(locales, func(names calendarNames) []string { return names.monthsShort })
+}
+
+// Seasons returns the season names of the locales, spring first
+func Seasons(locales []string) []string {
+	return mergeLocales(locales, func(names calendarNames) []string { return names.seasons })
+}
+
+// Locales lists the languages of the calendar names in sorted order
+func Locales() []string {
+	locales := make([]string, 0, len(calendarLocales))
+	for locale := range calendarLocales {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+func mergeLocales(locales []string, names func(calendarNames) []string) []string {
+	var merged []string
+	seen := make(map[string]bool)
+
+	for _, locale := range locales {
+		for _, name := range names(calendarLocales[locale]) {
+			if !seen[name] {
+				seen[name] = true
+				merged = append(merged, name)
+			}
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,112 @@
+package wordlist
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// KeyboardLayout is a keyboard as rows of keys, top row first. Keys at the
+// same index of neighbouring rows sit above each other, so 1, q, a and z
+// form a column on QWERTY. Shifted holds the characters the same keys type
+// with shift, and is nil when the layout has none.
+type KeyboardLayout struct {
+	Rows    [][]rune
+	Shifted [][]rune
+}
+
+// keyboardLayouts pairs every row with its shifted row
+var keyboardLayouts = map[string][][2]string{
+	"qwerty": {
+		{"1234567890-=", "!@#$%^&*()_+"},
+		{"qwertyuiop[]", "QWERTYUIOP{}"},
+		{"asdfghjkl;'", "ASDFGHJKL:\""},
+		{"zxcvbnm,./", "ZXCVBNM<>?"},
+	},
+	"azerty": {
+		{"&é\"'(-è_çà)=", "1234567890°+"},
+		{"azertyuiop^$", "AZERTYUIOP¨£"},
+		{"qsdfghjklmù*", "QSDFGHJKLM%µ"},
+		{"wxcvbn,;:!", "WXCVBN?./§"},
+	},
+	"qwertz": {
+		{"1234567890ß´", "!\"§$%&/()=?`"},
+		{"qwertzuiopü+", "QWERTZUIOPÜ*"},
+		{"asdfghjklöä#", "ASDFGHJKLÖÄ'"},
+		{"yxcvbnm,.-", "YXCVBNM;:_"},
+	},
+}
+
+// Keyboard returns the built-in keyboard layout called name
+func Keyboard(name string) (KeyboardLayout, bool) {
+	rows, exists := keyboardLayouts[name]
+	if !exists {
+		return KeyboardLayout{}, false
+	}
+
+	var layout KeyboardLayout
+	for _, row := range rows {
+		layout.Rows = append(layout.Rows, []rune(row[0]))
+		layout.Shifted = append(layout.Shifted, []rune(row[1]))
+	}
+
+	return layout, true
+}
+
+// KeyboardNames lists the built-in keyboard layouts in sorted order
+func KeyboardNames() []string {
+	names := make([]string, 0, len(keyboardLayouts))
+	for name := range keyboardLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// LoadKeyboard reads a keyboard layout file with one row of keys per line,
+// top row first. A row may be followed by whitespace and the same keys
+// with shift; either every row has a shifted row or none has.
+func (l *Loader) LoadKeyboard(filePath string) (KeyboardLayout, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return KeyboardLayout{}, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	var layout KeyboardLayout
+	scanner := bufio.NewScanner(file)
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+			continue
+		case len(fields) > 2:
+			return KeyboardLayout{}, fmt.Errorf("line %d of %s has more than a row and its shifted row", line, filePath)
+		case len(fields) == 2 && len([]rune(fields[0])) != len([]rune(fields[1])):
+			return KeyboardLayout{}, fmt.Errorf("line %d of %s has a shifted row of a different length", line, filePath)
+		}
+
+		layout.Rows = append(layout.Rows, []rune(fields[0]))
+		if len(fields) == 2 {
+			layout.Shifted = append(layout.Shifted, []rune(fields[1]))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return KeyboardLayout{}, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+
+	if len(layout.Rows) == 0 {
+		return KeyboardLayout{}, fmt.Errorf("keyboard layout %s has no rows", filePath)
+	}
+
+	if len(layout.Shifted) > 0 && len(layout.Shifted) != len(layout.Rows) {
+		return KeyboardLayout{}, fmt.Errorf("keyboard layout %s has shifted keys for some rows only", filePath)
+	}
+
+	return layout, nil
+}
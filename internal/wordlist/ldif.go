@@ -0,0 +1,76 @@
+package wordlist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// IsLDIF reports whether data looks like LDIF, which starts its records
+// with a dn: line, optionally after a version: line
+func IsLDIF(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.ToLower(scanner.Text())
+		if strings.HasPrefix(line, "dn:") || strings.HasPrefix(line, "version:") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseLDIF reads LDIF records keyed by lowercased attribute name, joining
+// folded lines and decoding base64 values. Comments are skipped.
+func ParseLDIF(data []byte) ([]map[string]string, error) {
+	var records []map[string]string
+	record := make(map[string]string)
+	var lines []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, " ") && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	for _, line := range append(lines, "") {
+		if strings.TrimSpace(line) == "" {
+			if len(record) > 0 {
+				records = append(records, record)
+				record = make(map[string]string)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		key = strings.ToLower(key)
+		if _, exists := record[key]; exists {
+			// Multi-valued attributes such as objectClass keep their first value
+			continue
+		}
+
+		if strings.HasPrefix(value, ":") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 value of %s", key)
+			}
+			value = string(decoded)
+		}
+		record[key] = strings.TrimSpace(value)
+	}
+
+	return records, nil
+}
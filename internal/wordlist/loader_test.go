@@ -70,3 +70,121 @@ func TestLoadFromFile(t *testing.T) {
 		}
 	})
 }
+
+func TestLoadKeyboard(t *testing.T) {
+	loader := NewLoader()
+
+	t.Run("loads rows with their shifted rows", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "keyboard.txt")
+		content := "123 !@#\nqwe QWE\n\nasd ASD\n"
+		if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		layout, err := loader.LoadKeyboard(tmpFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := KeyboardLayout{
+			Rows:    [][]rune{[]rune("123"), []rune("qwe"), []rune("asd")},
+			Shifted: [][]rune{[]rune("!@#"), []rune("QWE"), []rune("ASD")},
+		}
+		if !reflect.DeepEqual(layout, expected) {
+			t.Errorf("expected %v, got %v", expected, layout)
+		}
+	})
+
+	t.Run("rejects shifted keys for some rows only", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "partial.txt")
+		if err := os.WriteFile(tmpFile, []byte("123 !@#\nqwe\n"), 0644); err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		if _, err := loader.LoadKeyboard(tmpFile); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("rejects a shifted row of a different length", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "uneven.txt")
+		if err := os.WriteFile(tmpFile, []byte("123 !@\n"), 0644); err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		if _, err := loader.LoadKeyboard(tmpFile); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestLoadRoster(t *testing.T) {
+	loader := NewLoader()
+	expected := []User{
+		{Username: "jdoe", First: "John", Last: "Doe"},
+		{Username: "asmith", First: "Alice", Last: "Smith"},
+		{Username: "svc_backup", First: "Backup", Last: ""},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "users.csv")
+		content := "\xef\xbb\xbfSamAccountName,GivenName,Surname,DisplayName\n" +
+			"CORP\\jdoe,John,Doe,John Doe\n" +
+			"asmith@corp.local,,,\"Smith, Alice\"\n" +
+			"svc_backup,,,Backup\n" +
+			",Nobody,Here,\n" +
+			"JDOE,Johnny,Doe,\n"
+		if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		users, err := loader.LoadRoster(tmpFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(users, expected) {
+			t.Errorf("expected %v, got %v", expected, users)
+		}
+	})
+
+	t.Run("ldif", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "users.ldif")
+		content := "dn: CN=John Doe,CN=Users,DC=corp,DC=local\n" +
+			"sAMAccountName: jdoe\ngivenName: John\nsn: Doe\n\n" +
+			"dn: CN=Alice Smith,CN=Users,DC=corp,DC=local\n" +
+			"sAMAccountName: asmith\ndisplayName:: QWxpY2UgU21pdGg=\n\n" +
+			"dn: CN=svc_backup,CN=Users,DC=corp,DC=local\n" +
+			"sAMAccountName: svc_backup\ncn: Backup\n\n" +
+			"# search result\nsearch: 2\nresult: 0 Success\n"
+		if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		users, err := loader.LoadRoster(tmpFile)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(users, expected) {
+			t.Errorf("expected %v, got %v", expected, users)
+		}
+	})
+
+	t.Run("no usernames", func(t *testing.T) {
+		tmpFile := filepath.Join(t.TempDir(), "names.csv")
+		if err := os.WriteFile(tmpFile, []byte("first,last\nJohn,Doe\n"), 0644); err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+
+		if _, err := loader.LoadRoster(tmpFile); err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("initials", func(t *testing.T) {
+		if initials := expected[0].Initials(); initials != "jd" {
+			t.Errorf("expected jd, got %q", initials)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package wordlist
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// User is one account of a roster with the parts of its name
+type User struct {
+	Username string
+	First    string
+	Last     string
+}
+
+// Initials returns the lowercased first letters of the first and last name
+func (u User) Initials() string {
+	var initials strings.Builder
+	for _, name := range []string{u.First, u.Last} {
+		for _, char := range name {
+			initials.WriteString(strings.ToLower(string(char)))
+			break
+		}
+	}
+
+	return initials.String()
+}
+
+// column and attribute names of a roster, lowercased, most specific first
+var (
+	usernameKeys    = []string{"username", "samaccountname", "user", "login", "uid", "userprincipalname", "upn", "mail", "email"}
+	firstNameKeys   = []string{"first", "firstname", "first_name", "givenname", "given_name"}
+	lastNameKeys    = []string{"last", "lastname", "last_name", "surname", "sn", "familyname"}
+	displayNameKeys = []string{"displayname", "display_name", "name", "fullname", "full_name", "cn"}
+)
+
+// LoadRoster reads the users of a CSV file with a header row, or of an
+// LDIF export of user objects. Names missing from a user are taken from
+// its display name; users without a username are left out.
+func (l *Loader) LoadRoster(filePath string) ([]User, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var records []map[string]string
+	if IsLDIF(data) {
+		records, err = ParseLDIF(data)
+	} else {
+		records, err = parseCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading roster %s: %w", filePath, err)
+	}
+
+	var users []User
+	seen := make(map[string]bool)
+
+	for _, record := range records {
+		user := newUser(record)
+		key := strings.ToLower(user.Username)
+		if user.Username == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		users = append(users, user)
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no user with a username found in roster %s", filePath)
+	}
+
+	return users, nil
+}
+
+// parseCSV reads the rows of a CSV file keyed by their lowercased header
+func parseCSV(data []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	var records []map[string]string
+	for _, row := range rows[1:] {
+		record := make(map[string]string, len(header))
+		for idx, value := range row {
+			if idx < len(header) {
+				record[strings.ToLower(strings.TrimSpace(header[idx]))] = strings.TrimSpace(value)
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func newUser(record map[string]string) User {
+	user := User{
+		Username: accountName(lookupKeys(record, usernameKeys)),
+		First:    lookupKeys(record, firstNameKeys),
+		Last:     lookupKeys(record, lastNameKeys),
+	}
+
+	if user.First == "" || user.Last == "" {
+		first, last := splitDisplayName(lookupKeys(record, displayNameKeys))
+		if user.First == "" {
+			user.First = first
+		}
+		if user.Last == "" {
+			user.Last = last
+		}
+	}
+
+	return user
+}
+
+func lookupKeys(record map[string]string, keys []string) string {
+	for _, key := range keys {
+		if value := record[key]; value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// accountName strips the domain of DOMAIN\user and user@domain names
+func accountName(name string) string {
+	if _, account, found := strings.Cut(name, `\`); found {
+		name = account
+	}
+	if account, _, found := strings.Cut(name, "@"); found {
+		name = account
+	}
+
+	return name
+}
+
+// splitDisplayName splits "John Doe" and "Doe, John" into first and last name
+func splitDisplayName(name string) (string, string) {
+	if last, first, found := strings.Cut(name, ","); found {
+		return firstField(first), strings.TrimSpace(last)
+	}
+
+	fields := strings.Fields(name)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	}
+
+	return fields[0], fields[len(fields)-1]
+}
+
+func firstField(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}